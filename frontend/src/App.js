@@ -12,6 +12,9 @@ import { Container, Row, Col, Button, Nav, Navbar, Badge, Alert } from 'react-bo
 import 'bootstrap/dist/css/bootstrap.min.css';
 import 'bootstrap-icons/font/bootstrap-icons.css';
 
+// Version des Nachrichtenprotokolls (protocol/protocol.go), die das Frontend versteht
+const PROTOCOL_VERSION = 1;
+
 // Demo-Mock-Daten für den Fall, dass die API nicht funktioniert
 const MOCK_WORKERS = [
   { id: "worker-1", status: "IDLE" },
@@ -97,6 +100,19 @@ function App() {
             break;
           case 'welcome':
             console.log('WebSocket Welcome-Nachricht:', data);
+            if (data.version !== PROTOCOL_VERSION) {
+              // Nicht raten, wie Nachrichten einer anderen Version zu lesen sind
+              console.error(`Inkompatible Protokollversion ${data.version}, erwartet ${PROTOCOL_VERSION}`);
+              setError(`Inkompatible Protokollversion des Task-Managers (${data.version}, erwartet ${PROTOCOL_VERSION}). Bitte Frontend aktualisieren.`);
+              ws.close();
+            }
+            break;
+          case 'snapshot':
+            // Anfangszustand direkt nach dem Verbindungsaufbau
+            setTasks(data.content.tasks);
+            if (data.content.workers.length > 0) {
+              setWorkers(data.content.workers);
+            }
             break;
           default:
             console.log('Unbekannter Ereignistyp:', data.type);
@@ -0,0 +1,338 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EncryptedField ist der einzige Schlüssel eines verschlüsselten Feldes. Verschlüsselte
+// Werte bleiben dadurch JSON-Objekte, und Task-Strukturen ändern ihren Typ nicht:
+//
+//	{"data": {"$encrypted": {"v": 1, "kid": "2024-06", "dek": "...", "nonce": "...", "ct": "..."}}}
+const EncryptedField = "$encrypted"
+
+// sealedVersion ist die Version des Formats von SealedValue
+const sealedVersion = 1
+
+// ErrNoKeyring wird beim Entschlüsseln ohne geladenen Schlüsselbund zurückgegeben
+var ErrNoKeyring = errors.New("Wert ist verschlüsselt, aber kein Schlüsselbund geladen (ENCRYPTION_KEYRING_FILE)")
+
+// SealedValue ist ein mit Envelope-Verschlüsselung gesicherter Wert: Der Inhalt ist mit
+// einem zufälligen Datenschlüssel (AES-256-GCM) verschlüsselt, der Datenschlüssel mit dem
+// Schlüssel KeyID aus dem Schlüsselbund. Beim Rotieren muss daher nur WrappedKey neu
+// verschlüsselt werden.
+type SealedValue struct {
+	Version    int    `json:"v"`
+	KeyID      string `json:"kid"`
+	WrappedKey []byte `json:"dek"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// DataAAD und CheckpointAAD binden verschlüsselte Werte an Task und Feld, damit sie
+// nicht unbemerkt zwischen Tasks oder Feldern vertauscht werden können
+func DataAAD(taskID string) string       { return "task:" + taskID + ":data" }
+func CheckpointAAD(taskID string) string { return "task:" + taskID + ":checkpoint" }
+
+// keyringFile ist das Format der Schlüsselbund-Datei:
+//
+//	{"primary": "2024-06", "keys": {"2024-01": "<base64, 32 Bytes>", "2024-06": "<base64, 32 Bytes>"}}
+//
+// Neue Werte werden mit primary verschlüsselt; alle übrigen Schlüssel dienen nur
+// noch dem Entschlüsseln älterer Werte.
+type keyringFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// Keyring hält die Schlüssel zum Verschlüsseln von Task-Daten in Redis. Ein nil-Keyring
+// ist erlaubt: Werte werden dann unverschlüsselt gespeichert.
+type Keyring struct {
+	path    string
+	mutex   sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+	modTime time.Time
+}
+
+// LoadKeyring liest den Schlüsselbund aus path
+func LoadKeyring(path string) (*Keyring, error) {
+	k := &Keyring{path: path}
+	if _, err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// LoadKeyringFromEnv liest den Schlüsselbund aus ENCRYPTION_KEYRING_FILE; ohne
+// die Variable wird nil zurückgegeben und nicht verschlüsselt
+func LoadKeyringFromEnv() (*Keyring, error) {
+	path := os.Getenv("ENCRYPTION_KEYRING_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return LoadKeyring(path)
+}
+
+// Reload liest die Datei erneut ein, falls sie sich seit dem letzten Laden geändert hat.
+// Bei einem Fehler bleibt der bisherige Schlüsselbund in Kraft.
+func (k *Keyring) Reload() (bool, error) {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return false, fmt.Errorf("Fehler beim Lesen des Schlüsselbunds: %w", err)
+	}
+	k.mutex.RLock()
+	unchanged := info.ModTime().Equal(k.modTime)
+	k.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	raw, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		return false, fmt.Errorf("Fehler beim Lesen des Schlüsselbunds: %w", err)
+	}
+	var file keyringFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return false, fmt.Errorf("Ungültiger Schlüsselbund %s: %w", k.path, err)
+	}
+	keys := make(map[string]cipher.AEAD, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return false, fmt.Errorf("Schlüssel %s ist kein gültiges Base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return false, fmt.Errorf("Schlüssel %s muss 32 Bytes lang sein (AES-256), ist %d", id, len(key))
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return false, err
+		}
+		keys[id] = aead
+	}
+	if _, ok := keys[file.Primary]; !ok {
+		return false, fmt.Errorf("Primärschlüssel %q fehlt im Schlüsselbund %s", file.Primary, k.path)
+	}
+
+	k.mutex.Lock()
+	k.primary = file.Primary
+	k.keys = keys
+	k.modTime = info.ModTime()
+	k.mutex.Unlock()
+	return true, nil
+}
+
+// Primary liefert die ID des Schlüssels, mit dem neue Werte verschlüsselt werden
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.primary
+}
+
+// KeyIDs liefert die IDs aller geladenen Schlüssel
+func (k *Keyring) KeyIDs() []string {
+	if k == nil {
+		return nil
+	}
+	k.mutex.RLock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	k.mutex.RUnlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// Seal verschlüsselt value mit dem Primärschlüssel. Ohne Schlüsselbund, bei leeren
+// und bereits verschlüsselten Werten wird value unverändert zurückgegeben.
+func (k *Keyring) Seal(value map[string]interface{}, aad string) (map[string]interface{}, error) {
+	if k == nil || len(value) == 0 || IsSealed(value) {
+		return value, nil
+	}
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mutex.RLock()
+	keyID, kek := k.primary, k.keys[k.primary]
+	k.mutex.RUnlock()
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := seal(kek, dataKey, []byte(keyID))
+	if err != nil {
+		return nil, err
+	}
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, dek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return sealedMap(SealedValue{
+		Version:    sealedVersion,
+		KeyID:      keyID,
+		WrappedKey: wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: dek.Seal(nil, nonce, plaintext, []byte(aad)),
+	})
+}
+
+// Open entschlüsselt einen mit Seal verschlüsselten Wert; unverschlüsselte Werte
+// (z.B. aus der Zeit vor der Verschlüsselung) werden unverändert zurückgegeben
+func (k *Keyring) Open(value map[string]interface{}, aad string) (map[string]interface{}, error) {
+	sealed, ok, err := parseSealed(value)
+	if err != nil || !ok {
+		return value, err
+	}
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := dek.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("Entschlüsselung fehlgeschlagen (Wert manipuliert oder falscher Task): %w", err)
+	}
+	var opened map[string]interface{}
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return nil, fmt.Errorf("Entschlüsselter Wert ist kein JSON-Objekt: %w", err)
+	}
+	return opened, nil
+}
+
+// Rewrap verschlüsselt den Datenschlüssel eines Wertes mit dem aktuellen Primärschlüssel
+// neu, ohne den Inhalt anzufassen. changed ist false, wenn der Wert unverschlüsselt ist
+// oder bereits den Primärschlüssel verwendet.
+func (k *Keyring) Rewrap(value map[string]interface{}) (rewrapped map[string]interface{}, changed bool, err error) {
+	sealed, ok, err := parseSealed(value)
+	if err != nil || !ok || k == nil {
+		return value, false, err
+	}
+	k.mutex.RLock()
+	keyID, kek := k.primary, k.keys[k.primary]
+	k.mutex.RUnlock()
+	if sealed.KeyID == keyID {
+		return value, false, nil
+	}
+
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, false, err
+	}
+	if sealed.WrappedKey, err = seal(kek, dataKey, []byte(keyID)); err != nil {
+		return nil, false, err
+	}
+	sealed.KeyID = keyID
+	rewrapped, err = sealedMap(sealed)
+	return rewrapped, err == nil, err
+}
+
+// IsSealed prüft, ob value ein mit Seal verschlüsselter Wert ist
+func IsSealed(value map[string]interface{}) bool {
+	_, ok := value[EncryptedField]
+	return ok && len(value) == 1
+}
+
+// SealedKeyID liefert die Schlüssel-ID eines verschlüsselten Wertes
+func SealedKeyID(value map[string]interface{}) (string, bool) {
+	sealed, ok, err := parseSealed(value)
+	if err != nil || !ok {
+		return "", false
+	}
+	return sealed.KeyID, true
+}
+
+// unwrap entschlüsselt den Datenschlüssel eines Wertes
+func (k *Keyring) unwrap(sealed SealedValue) ([]byte, error) {
+	k.mutex.RLock()
+	kek, ok := k.keys[sealed.KeyID]
+	k.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Schlüssel %q nicht im Schlüsselbund", sealed.KeyID)
+	}
+	nonceSize := kek.NonceSize()
+	if len(sealed.WrappedKey) < nonceSize {
+		return nil, errors.New("Verschlüsselter Datenschlüssel ist zu kurz")
+	}
+	dataKey, err := kek.Open(nil, sealed.WrappedKey[:nonceSize], sealed.WrappedKey[nonceSize:], []byte(sealed.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("Datenschlüssel lässt sich mit Schlüssel %q nicht entschlüsseln: %w", sealed.KeyID, err)
+	}
+	return dataKey, nil
+}
+
+// parseSealed liest einen verschlüsselten Wert; ok ist false bei unverschlüsselten Werten
+func parseSealed(value map[string]interface{}) (sealed SealedValue, ok bool, err error) {
+	if !IsSealed(value) {
+		return sealed, false, nil
+	}
+	raw, err := json.Marshal(value[EncryptedField])
+	if err != nil {
+		return sealed, false, err
+	}
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return sealed, false, fmt.Errorf("Ungültiger verschlüsselter Wert: %w", err)
+	}
+	if sealed.Version != sealedVersion {
+		return sealed, false, fmt.Errorf("Nicht unterstützte Version %d eines verschlüsselten Wertes", sealed.Version)
+	}
+	return sealed, true, nil
+}
+
+// sealedMap bettet einen verschlüsselten Wert unter EncryptedField ein
+func sealedMap(sealed SealedValue) (map[string]interface{}, error) {
+	raw, err := json.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	var inner map[string]interface{}
+	if err := json.Unmarshal(raw, &inner); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{EncryptedField: inner}, nil
+}
+
+// seal verschlüsselt plaintext mit einer zufälligen Nonce, die dem Ergebnis vorangestellt wird
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
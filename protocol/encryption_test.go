@@ -0,0 +1,321 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey liefert einen festen 32-Byte-Schlüssel
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// writeKeyring schreibt eine Schlüsselbund-Datei und setzt eine neue Änderungszeit,
+// damit Reload die Änderung auch bei grober Zeitauflösung des Dateisystems erkennt
+func writeKeyring(t *testing.T, path, primary string, keys map[string][]byte) {
+	t.Helper()
+	file := keyringFile{Primary: primary, Keys: map[string]string{}}
+	for id, key := range keys {
+		file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	raw, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFileWithNewModTime(t, path, raw)
+}
+
+func writeFileWithNewModTime(t *testing.T, path string, raw []byte) {
+	t.Helper()
+	modTime := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(testKey(1))
+	short := base64.StdEncoding.EncodeToString(testKey(1)[:16])
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "gültig", content: `{"primary": "a", "keys": {"a": "` + valid + `", "b": "` + valid + `"}}`},
+		{name: "kein JSON", content: `{`, wantErr: "Ungültiger Schlüsselbund"},
+		{name: "Primärschlüssel fehlt", content: `{"primary": "b", "keys": {"a": "` + valid + `"}}`, wantErr: `Primärschlüssel "b" fehlt`},
+		{name: "kein Primärschlüssel", content: `{"keys": {"a": "` + valid + `"}}`, wantErr: `Primärschlüssel "" fehlt`},
+		{name: "kein Base64", content: `{"primary": "a", "keys": {"a": "!!"}}`, wantErr: "kein gültiges Base64"},
+		{name: "AES-128-Schlüssel", content: `{"primary": "a", "keys": {"a": "` + short + `"}}`, wantErr: "muss 32 Bytes lang sein"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyring.json")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			k, err := LoadKeyring(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if k.Primary() != "a" || !reflect.DeepEqual(k.KeyIDs(), []string{"a", "b"}) {
+				t.Fatalf("Primary = %q, KeyIDs = %v", k.Primary(), k.KeyIDs())
+			}
+		})
+	}
+
+	if _, err := LoadKeyring(filepath.Join(t.TempDir(), "fehlt.json")); err == nil {
+		t.Fatal("fehlende Datei ohne Fehler geladen")
+	}
+}
+
+func TestKeyringSealOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "k1", map[string][]byte{"k1": testKey(1)})
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPath := filepath.Join(t.TempDir(), "other.json")
+	writeKeyring(t, otherPath, "k1", map[string][]byte{"k1": testKey(2)})
+	other, err := LoadKeyring(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := map[string]interface{}{"operation": "sum", "input": []interface{}{1.0, 2.0}}
+	sealed, err := k.Seal(value, DataAAD("t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("Seal lieferte keinen verschlüsselten Wert: %v", sealed)
+	}
+	if keyID, ok := SealedKeyID(sealed); !ok || keyID != "k1" {
+		t.Fatalf("SealedKeyID = %q, %v", keyID, ok)
+	}
+	sealedJSON, _ := json.Marshal(sealed)
+	if bytes.Contains(sealedJSON, []byte("sum")) {
+		t.Fatalf("Klartext im verschlüsselten Wert: %s", sealedJSON)
+	}
+
+	tampered := mutateSealed(t, sealed, func(s *SealedValue) { s.Ciphertext[0] ^= 1 })
+	unknownKey := mutateSealed(t, sealed, func(s *SealedValue) { s.KeyID = "k9" })
+	shortKey := mutateSealed(t, sealed, func(s *SealedValue) { s.WrappedKey = s.WrappedKey[:4] })
+	wrongVersion := mutateSealed(t, sealed, func(s *SealedValue) { s.Version = 2 })
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   map[string]interface{}
+		aad     string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{name: "gültig", keyring: k, value: sealed, aad: DataAAD("t1"), want: value},
+		{name: "anderer Task", keyring: k, value: sealed, aad: DataAAD("t2"), wantErr: "Entschlüsselung fehlgeschlagen"},
+		{name: "anderes Feld", keyring: k, value: sealed, aad: CheckpointAAD("t1"), wantErr: "Entschlüsselung fehlgeschlagen"},
+		{name: "manipulierter Inhalt", keyring: k, value: tampered, aad: DataAAD("t1"), wantErr: "Entschlüsselung fehlgeschlagen"},
+		{name: "unbekannter Schlüssel", keyring: k, value: unknownKey, aad: DataAAD("t1"), wantErr: `Schlüssel "k9" nicht im Schlüsselbund`},
+		{name: "gleiche ID, anderer Schlüssel", keyring: other, value: sealed, aad: DataAAD("t1"), wantErr: "Datenschlüssel lässt sich"},
+		{name: "Datenschlüssel zu kurz", keyring: k, value: shortKey, aad: DataAAD("t1"), wantErr: "zu kurz"},
+		{name: "unbekannte Version", keyring: k, value: wrongVersion, aad: DataAAD("t1"), wantErr: "Version 2"},
+		{name: "ohne Schlüsselbund", keyring: nil, value: sealed, aad: DataAAD("t1"), wantErr: ErrNoKeyring.Error()},
+		{name: "unverschlüsselt", keyring: k, value: value, aad: DataAAD("t1"), want: value},
+		{name: "unverschlüsselt ohne Schlüsselbund", keyring: nil, value: value, aad: DataAAD("t1"), want: value},
+		// Ein Objekt mit weiteren Feldern neben $encrypted ist kein verschlüsselter Wert
+		{name: "$encrypted mit weiteren Feldern", keyring: k, value: map[string]interface{}{EncryptedField: 1.0, "x": 1.0}, want: map[string]interface{}{EncryptedField: 1.0, "x": 1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := tt.keyring.Open(tt.value, tt.aad)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if !reflect.DeepEqual(opened, tt.want) {
+				t.Fatalf("Open = %v, erwartet %v", opened, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyringSealPassThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "k1", map[string][]byte{"k1": testKey(1)})
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.Seal(map[string]interface{}{"a": 1.0}, DataAAD("t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		value   map[string]interface{}
+	}{
+		{name: "ohne Schlüsselbund", keyring: nil, value: map[string]interface{}{"a": 1.0}},
+		{name: "nil", keyring: k, value: nil},
+		{name: "leer", keyring: k, value: map[string]interface{}{}},
+		{name: "bereits verschlüsselt", keyring: k, value: sealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Seal(tt.value, DataAAD("t1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.value) {
+				t.Fatalf("Seal = %v, erwartet unverändert %v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestKeyringRotateAndRewrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "k1", map[string][]byte{"k1": testKey(1)})
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := map[string]interface{}{"secret": "geheim"}
+	old, err := k.Seal(value, CheckpointAAD("t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unveränderte Datei wird nicht neu geladen
+	if changed, err := k.Reload(); err != nil || changed {
+		t.Fatalf("Reload ohne Änderung = %v, %v", changed, err)
+	}
+
+	// Rotation: k2 wird Primärschlüssel, k1 bleibt zum Entschlüsseln
+	writeKeyring(t, path, "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	if changed, err := k.Reload(); err != nil || !changed {
+		t.Fatalf("Reload nach Rotation = %v, %v", changed, err)
+	}
+	if k.Primary() != "k2" {
+		t.Fatalf("Primary = %q, erwartet k2", k.Primary())
+	}
+	if opened, err := k.Open(old, CheckpointAAD("t1")); err != nil || !reflect.DeepEqual(opened, value) {
+		t.Fatalf("alter Wert nach Rotation: %v, %v", opened, err)
+	}
+	fresh, err := k.Seal(value, CheckpointAAD("t1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID, _ := SealedKeyID(fresh); keyID != "k2" {
+		t.Fatalf("neuer Wert mit Schlüssel %q verschlüsselt, erwartet k2", keyID)
+	}
+
+	rewrapped, changed, err := k.Rewrap(old)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v", changed, err)
+	}
+	if keyID, _ := SealedKeyID(rewrapped); keyID != "k2" {
+		t.Fatalf("Rewrap lieferte Schlüssel %q, erwartet k2", keyID)
+	}
+	if !reflect.DeepEqual(sealedField(t, rewrapped, "ct"), sealedField(t, old, "ct")) {
+		t.Fatal("Rewrap hat den Inhalt neu verschlüsselt statt nur den Datenschlüssel")
+	}
+	if _, changed, err := k.Rewrap(rewrapped); err != nil || changed {
+		t.Fatalf("zweites Rewrap = %v, %v, erwartet unverändert", changed, err)
+	}
+	if got, changed, err := k.Rewrap(value); err != nil || changed || !reflect.DeepEqual(got, value) {
+		t.Fatalf("Rewrap eines unverschlüsselten Wertes = %v, %v, %v", got, changed, err)
+	}
+
+	// Nach dem Entfernen von k1 lassen sich nur noch neu verpackte Werte öffnen
+	writeKeyring(t, path, "k2", map[string][]byte{"k2": testKey(2)})
+	if _, err := k.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := k.Open(rewrapped, CheckpointAAD("t1")); err != nil || !reflect.DeepEqual(opened, value) {
+		t.Fatalf("neu verpackter Wert: %v, %v", opened, err)
+	}
+	if _, err := k.Open(old, CheckpointAAD("t1")); err == nil {
+		t.Fatal("Wert mit entferntem Schlüssel ließ sich öffnen")
+	}
+	if _, _, err := k.Rewrap(old); err == nil {
+		t.Fatal("Rewrap mit entferntem Schlüssel ohne Fehler")
+	}
+}
+
+func TestKeyringReloadKeepsKeyringOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	writeKeyring(t, path, "k1", map[string][]byte{"k1": testKey(1)})
+	k, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFileWithNewModTime(t, path, []byte(`{"primary": "k2", "keys": {}}`))
+	if _, err := k.Reload(); err == nil {
+		t.Fatal("ungültiger Schlüsselbund ohne Fehler geladen")
+	}
+	if k.Primary() != "k1" {
+		t.Fatalf("Primary = %q, erwartet weiterhin k1", k.Primary())
+	}
+	if _, err := k.Seal(map[string]interface{}{"a": 1.0}, DataAAD("t1")); err != nil {
+		t.Fatalf("Seal nach fehlgeschlagenem Reload: %v", err)
+	}
+}
+
+// mutateSealed liefert eine veränderte Kopie eines verschlüsselten Wertes
+func mutateSealed(t *testing.T, value map[string]interface{}, mutate func(*SealedValue)) map[string]interface{} {
+	t.Helper()
+	sealed, ok, err := parseSealed(value)
+	if err != nil || !ok {
+		t.Fatalf("kein verschlüsselter Wert: %v", err)
+	}
+	sealed.WrappedKey = append([]byte(nil), sealed.WrappedKey...)
+	sealed.Ciphertext = append([]byte(nil), sealed.Ciphertext...)
+	mutate(&sealed)
+	mutated, err := sealedMap(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mutated
+}
+
+func sealedField(t *testing.T, value map[string]interface{}, field string) interface{} {
+	t.Helper()
+	inner, ok := value[EncryptedField].(map[string]interface{})
+	if !ok {
+		t.Fatal("kein verschlüsselter Wert")
+	}
+	return inner[field]
+}
@@ -0,0 +1,7 @@
+module github.com/scimbe/distributed-task-demo-system/protocol
+
+go 1.18
+
+require go.opentelemetry.io/otel/trace v1.14.0
+
+require go.opentelemetry.io/otel v1.14.0 // indirect
@@ -0,0 +1,406 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogLevel ist die Schwere einer Log-Zeile
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LogDebug || l > LogError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel liest eine Stufe (debug, info, warn, error)
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(strings.TrimSpace(name), levelName) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogInfo, fmt.Errorf("unbekannte Log-Stufe %q (erlaubt: %s)", name, strings.Join(logLevelNames, ", "))
+}
+
+func (l LogLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+func (l *LogLevel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	level, err := ParseLogLevel(name)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// LogLevels sind die Log-Stufen für GET und PUT auf LogLevelsHandler
+// (/api/system/logging im Task-Manager, /logging auf dem Admin-Server des Workers).
+// Components enthält beim Lesen die wirksame Stufe jeder Komponente; beim Setzen
+// nimmt "default" die Abweichung einer Komponente wieder zurück.
+type LogLevels struct {
+	Default    *LogLevel         `json:"default,omitempty"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+// logRegistry hält die Stufen aller Komponenten eines Prozesses; Änderungen gelten sofort
+type logRegistry struct {
+	mutex        sync.RWMutex
+	defaultLevel LogLevel
+	overrides    map[string]LogLevel
+	components   map[string]bool
+	workerID     string
+	output       io.Writer
+}
+
+var logs = &logRegistry{
+	defaultLevel: LogInfo,
+	overrides:    make(map[string]LogLevel),
+	components:   make(map[string]bool),
+	output:       os.Stderr,
+}
+
+// InitLogging stellt das Paket log auf JSON-Zeilen über main um und liest die Stufen
+// aus LOG_LEVEL und LOG_LEVELS. Die Dienste rufen es in init auf, damit auch frühe
+// Meldungen und log.Fatalf einheitlich formatiert sind.
+func InitLogging(main *Logger) {
+	log.SetFlags(0)
+	log.SetOutput(stdLogWriter{logger: main})
+	if err := logs.configure(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_LEVELS")); err != nil {
+		main.Warnf("%v", err)
+	}
+}
+
+// SetLogWorkerID ergänzt jede folgende Zeile ohne eigene worker_id um die des Prozesses
+func SetLogWorkerID(workerID string) {
+	logs.mutex.Lock()
+	logs.workerID = workerID
+	logs.mutex.Unlock()
+}
+
+// configure setzt die Stufen aus LOG_LEVEL (Standard info) und LOG_LEVELS
+// (z.B. "websocket=debug,audit=warn")
+func (r *logRegistry) configure(defaultLevel, componentLevels string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if defaultLevel != "" {
+		level, err := ParseLogLevel(defaultLevel)
+		if err != nil {
+			return fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		r.defaultLevel = level
+	}
+	for _, item := range strings.Split(componentLevels, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("LOG_LEVELS: %q ist nicht im Format komponente=stufe", item)
+		}
+		level, err := ParseLogLevel(parts[1])
+		if err != nil {
+			return fmt.Errorf("LOG_LEVELS: %w", err)
+		}
+		r.overrides[strings.TrimSpace(parts[0])] = level
+	}
+	return nil
+}
+
+func (r *logRegistry) enabled(component string, level LogLevel) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	threshold, ok := r.overrides[component]
+	if !ok {
+		threshold = r.defaultLevel
+	}
+	return level >= threshold
+}
+
+// levels liefert die Standardstufe und die wirksame Stufe jeder bekannten Komponente
+func (r *logRegistry) levels() LogLevels {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	defaultLevel := r.defaultLevel
+	result := LogLevels{Default: &defaultLevel, Components: make(map[string]string)}
+	for component := range r.components {
+		level, ok := r.overrides[component]
+		if !ok {
+			level = r.defaultLevel
+		}
+		result.Components[component] = level.String()
+	}
+	return result
+}
+
+// apply übernimmt geänderte Stufen; unbekannte Komponenten oder Stufen ändern nichts
+func (r *logRegistry) apply(update LogLevels) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	changes := make(map[string]*LogLevel, len(update.Components))
+	for component, name := range update.Components {
+		if !r.components[component] {
+			return fmt.Errorf("unbekannte Komponente %q", component)
+		}
+		if name == "default" {
+			changes[component] = nil
+			continue
+		}
+		level, err := ParseLogLevel(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", component, err)
+		}
+		changes[component] = &level
+	}
+
+	if update.Default != nil {
+		r.defaultLevel = *update.Default
+	}
+	for component, level := range changes {
+		if level == nil {
+			delete(r.overrides, component)
+		} else {
+			r.overrides[component] = *level
+		}
+	}
+	return nil
+}
+
+// write gibt eine Zeile aus; Zeilen verschiedener Goroutinen vermischen sich nicht
+func (r *logRegistry) write(line []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.output.Write(line)
+}
+
+// Logger schreibt Log-Zeilen einer Komponente als JSON, eine Zeile pro Eintrag:
+//
+//	{"time":"…","level":"info","component":"service","event":"task_created","msg":"…","task_id":"…","trace_id":"…"}
+type Logger struct {
+	component string
+}
+
+// NewLogger erstellt den Logger einer Komponente; ihr Name ist der Schlüssel in
+// LOG_LEVELS und erscheint in GET auf LogLevelsHandler
+func NewLogger(component string) *Logger {
+	logs.mutex.Lock()
+	logs.components[component] = true
+	logs.mutex.Unlock()
+	return &Logger{component: component}
+}
+
+// LogEntry sammelt die Felder einer Log-Zeile
+type LogEntry struct {
+	logger *Logger
+	event  string
+	fields map[string]interface{}
+}
+
+func (l *Logger) entry() *LogEntry {
+	return &LogEntry{logger: l, fields: make(map[string]interface{})}
+}
+
+// Ctx übernimmt trace_id und span_id des laufenden Spans
+func (l *Logger) Ctx(ctx context.Context) *LogEntry { return l.entry().Ctx(ctx) }
+
+// Task setzt task_id
+func (l *Logger) Task(taskID string) *LogEntry { return l.entry().Task(taskID) }
+
+// Worker setzt worker_id
+func (l *Logger) Worker(workerID string) *LogEntry { return l.entry().Worker(workerID) }
+
+// Event setzt den maschinenlesbaren Namen des Ereignisses, z.B. task_created
+func (l *Logger) Event(event string) *LogEntry { return l.entry().Event(event) }
+
+func (l *Logger) Debugf(format string, args ...interface{}) { l.entry().Debugf(format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.entry().Infof(format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.entry().Warnf(format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.entry().Errorf(format, args...) }
+func (l *Logger) Fatalf(format string, args ...interface{}) { l.entry().Fatalf(format, args...) }
+
+func (e *LogEntry) Ctx(ctx context.Context) *LogEntry {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		e.fields["trace_id"] = spanContext.TraceID().String()
+		e.fields["span_id"] = spanContext.SpanID().String()
+	}
+	return e
+}
+
+func (e *LogEntry) Task(taskID string) *LogEntry {
+	if taskID != "" {
+		e.fields["task_id"] = taskID
+	}
+	return e
+}
+
+func (e *LogEntry) Worker(workerID string) *LogEntry {
+	if workerID != "" {
+		e.fields["worker_id"] = workerID
+	}
+	return e
+}
+
+func (e *LogEntry) Event(event string) *LogEntry {
+	e.event = event
+	return e
+}
+
+// Field setzt ein weiteres Feld
+func (e *LogEntry) Field(key string, value interface{}) *LogEntry {
+	e.fields[key] = value
+	return e
+}
+
+func (e *LogEntry) Debugf(format string, args ...interface{}) { e.logf(LogDebug, format, args...) }
+func (e *LogEntry) Infof(format string, args ...interface{})  { e.logf(LogInfo, format, args...) }
+func (e *LogEntry) Warnf(format string, args ...interface{})  { e.logf(LogWarn, format, args...) }
+func (e *LogEntry) Errorf(format string, args ...interface{}) { e.logf(LogError, format, args...) }
+
+// Fatalf schreibt die Meldung unabhängig von der eingestellten Stufe und beendet den Prozess
+func (e *LogEntry) Fatalf(format string, args ...interface{}) {
+	e.write(LogError, format, args...)
+	os.Exit(1)
+}
+
+// leadingFields stehen in dieser Reihenfolge vor allen übrigen Feldern
+var leadingFields = []string{"task_id", "worker_id", "trace_id", "span_id"}
+
+func (e *LogEntry) logf(level LogLevel, format string, args ...interface{}) {
+	if logs.enabled(e.logger.component, level) {
+		e.write(level, format, args...)
+	}
+}
+
+func (e *LogEntry) write(level LogLevel, format string, args ...interface{}) {
+	if _, ok := e.fields["worker_id"]; !ok {
+		logs.mutex.RLock()
+		e.Worker(logs.workerID)
+		logs.mutex.RUnlock()
+	}
+
+	var line bytes.Buffer
+	line.WriteByte('{')
+	writeField(&line, "time", time.Now().UTC().Format(time.RFC3339Nano), true)
+	writeField(&line, "level", level.String(), false)
+	writeField(&line, "component", e.logger.component, false)
+	if e.event != "" {
+		writeField(&line, "event", e.event, false)
+	}
+	writeField(&line, "msg", fmt.Sprintf(format, args...), false)
+
+	keys := make([]string, 0, len(e.fields))
+	for key := range e.fields {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fieldRank(keys[i]) < fieldRank(keys[j]) ||
+			fieldRank(keys[i]) == fieldRank(keys[j]) && keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		writeField(&line, key, e.fields[key], false)
+	}
+	line.WriteString("}\n")
+	logs.write(line.Bytes())
+}
+
+func fieldRank(key string) int {
+	for i, leading := range leadingFields {
+		if key == leading {
+			return i
+		}
+	}
+	return len(leadingFields)
+}
+
+func writeField(line *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		line.WriteByte(',')
+	}
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(encodedKey)
+	line.WriteByte(':')
+	line.Write(encodedValue)
+}
+
+// stdLogWriter leitet Ausgaben des Pakets log in einen Logger um. Die Stufe ergibt
+// sich aus dem Anfang der Meldung (FEHLER/Fehler, WARNUNG).
+type stdLogWriter struct {
+	logger *Logger
+}
+
+func (w stdLogWriter) Write(p []byte) (int, error) {
+	message := strings.TrimRight(string(p), "\n")
+	level := LogInfo
+	switch {
+	case strings.HasPrefix(message, "FEHLER: "):
+		level, message = LogError, strings.TrimPrefix(message, "FEHLER: ")
+	case strings.HasPrefix(message, "WARNUNG: "):
+		level, message = LogWarn, strings.TrimPrefix(message, "WARNUNG: ")
+	case strings.HasPrefix(message, "Fehler"):
+		level = LogError
+	}
+	w.logger.entry().logf(level, "%s", message)
+	return len(p), nil
+}
+
+// LogLevelsHandler ist der HTTP-Handler für die Log-Stufen: GET liefert sie, PUT
+// ändert sie bis zum nächsten Neustart; danach gelten wieder LOG_LEVEL und
+// LOG_LEVELS. Änderungen protokolliert logger.
+func LogLevelsHandler(logger *Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var update LogLevels
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, "Ungültige Log-Stufen: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := logs.apply(update); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.Event("log_levels_changed").Infof("Log-Stufen geändert")
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Methode nicht erlaubt", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logs.levels())
+	}
+}
@@ -0,0 +1,140 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestLogRegistry ersetzt die Registry für die Dauer des Tests und sammelt die Ausgabe
+func newTestLogRegistry(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	previous := logs
+	output := &bytes.Buffer{}
+	logs = &logRegistry{
+		defaultLevel: LogInfo,
+		overrides:    make(map[string]LogLevel),
+		components:   make(map[string]bool),
+		output:       output,
+	}
+	t.Cleanup(func() { logs = previous })
+	return output
+}
+
+func TestLogRegistryConfigure(t *testing.T) {
+	tests := []struct {
+		name            string
+		defaultLevel    string
+		componentLevels string
+		wantErr         bool
+		// want ist die wirksame Stufe je Komponente
+		want map[string]LogLevel
+	}{
+		{name: "Standard", want: map[string]LogLevel{"a": LogInfo}},
+		{name: "nur LOG_LEVEL", defaultLevel: "WARN", want: map[string]LogLevel{"a": LogWarn}},
+		{
+			name:         "Abweichungen",
+			defaultLevel: "error", componentLevels: " a=debug, ,b = warn",
+			want: map[string]LogLevel{"a": LogDebug, "b": LogWarn, "c": LogError},
+		},
+		{name: "unbekannte Stufe", defaultLevel: "trace", wantErr: true},
+		{name: "falsches Format", componentLevels: "a", wantErr: true},
+		{name: "unbekannte Stufe einer Komponente", componentLevels: "a=laut", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestLogRegistry(t)
+			err := logs.configure(tt.defaultLevel, tt.componentLevels)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Fehler erwartet")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			for component, level := range tt.want {
+				if !logs.enabled(component, level) || level > LogDebug && logs.enabled(component, level-1) {
+					t.Errorf("%s: wirksame Stufe ist nicht %s", component, level)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerWrite(t *testing.T) {
+	output := newTestLogRegistry(t)
+	logger := NewLogger("service")
+	SetLogWorkerID("w1")
+
+	logger.Debugf("unterdrückt")
+	logger.Task("t1").Event("task_created").Field("type", "sum").Infof("Task %s erstellt", "t1")
+	logger.Worker("w2").Warnf("anderer Worker")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Zeilen = %q, erwartet 2", lines)
+	}
+	// Die Reihenfolge der Felder ist fest; time wird für den Vergleich entfernt
+	first := lines[0][strings.Index(lines[0], `,"level"`):]
+	want := `,"level":"info","component":"service","event":"task_created","msg":"Task t1 erstellt","task_id":"t1","worker_id":"w1","type":"sum"}`
+	if first != want {
+		t.Fatalf("Zeile = %s, erwartet %s", first, want)
+	}
+	if !strings.Contains(lines[1], `"worker_id":"w2"`) {
+		t.Fatalf("eigene worker_id überschrieben: %s", lines[1])
+	}
+}
+
+func TestLogLevelsHandler(t *testing.T) {
+	newTestLogRegistry(t)
+	NewLogger("a")
+	NewLogger("b")
+	handler := LogLevelsHandler(NewLogger("admin"))
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		// want sind die Stufen nach der Anfrage als "<default> a=<stufe> b=<stufe>"
+		want string
+	}{
+		{name: "lesen", method: http.MethodGet, wantStatus: http.StatusOK, want: "info a=info b=info"},
+		{name: "setzen", method: http.MethodPut, body: `{"default":"warn","components":{"a":"debug"}}`, wantStatus: http.StatusOK, want: "warn a=debug b=warn"},
+		{name: "zurücknehmen", method: http.MethodPut, body: `{"components":{"a":"default"}}`, wantStatus: http.StatusOK, want: "warn a=warn b=warn"},
+		{name: "unbekannte Komponente ändert nichts", method: http.MethodPut, body: `{"default":"debug","components":{"x":"debug"}}`, wantStatus: http.StatusBadRequest, want: "warn a=warn b=warn"},
+		{name: "unbekannte Stufe", method: http.MethodPut, body: `{"default":"laut"}`, wantStatus: http.StatusBadRequest, want: "warn a=warn b=warn"},
+		{name: "Methode", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed, want: "warn a=warn b=warn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(tt.method, "/logging", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, erwartet %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				var levels LogLevels
+				if err := json.NewDecoder(rec.Body).Decode(&levels); err != nil {
+					t.Fatal(err)
+				}
+				if levels.Components["admin"] == "" {
+					t.Fatalf("Komponente admin fehlt: %v", levels.Components)
+				}
+			}
+
+			levels := logs.levels()
+			got := levels.Default.String() + " a=" + levels.Components["a"] + " b=" + levels.Components["b"]
+			if got != tt.want {
+				t.Fatalf("Stufen = %q, erwartet %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package protocol
+
+import "time"
+
+// Task ist der Zustand eines Tasks, wie er über den Broker ausgetauscht wird
+type Task struct {
+	ID             string                 `json:"id"`
+	Type           string                 `json:"type"`
+	Status         string                 `json:"status"`
+	Priority       int                    `json:"priority"`
+	Data           map[string]interface{} `json:"data"`
+	Progress       int                    `json:"progress"`
+	WorkerID       string                 `json:"worker_id,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	CheckpointData map[string]interface{} `json:"checkpoint_data,omitempty"`
+	// Namespace fehlt bei Tasks im DefaultNamespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Checkpoint ist ein gespeicherter Zwischenstand eines Tasks
+type Checkpoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Progress  int       `json:"progress"`
+	Step      string    `json:"step"`
+}
+
+// Migration beauftragt TargetWorkerID, einen Task ab seinem Checkpoint fortzusetzen
+type Migration struct {
+	TaskID     string `json:"taskId"`
+	FromWorker string `json:"fromWorker"`
+	ToWorker   string `json:"toWorker"`
+	// TargetWorkerID entspricht ToWorker; Worker erkennen daran, ob sie gemeint sind
+	TargetWorkerID string `json:"targetWorkerId"`
+	Namespace      string `json:"namespace,omitempty"`
+}
+
+// WorkerStatus ist die regelmäßige Statusmeldung eines Workers
+type WorkerStatus struct {
+	ID     string    `json:"id"`
+	Status string    `json:"status"`
+	Task   string    `json:"task"`
+	Time   time.Time `json:"time"`
+	// Namespaces sind die Namespaces, deren Tasks der Worker annimmt
+	Namespaces []string `json:"namespaces,omitempty"`
+}
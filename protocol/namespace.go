@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultNamespace ist der Namespace von Tasks und Workern ohne ausdrückliche Angabe.
+// Seine Redis-Schlüssel und Warteschlangen tragen kein Präfix, damit bestehende Daten gültig bleiben.
+const DefaultNamespace = "default"
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidateNamespace prüft, ob name als Namespace verwendet werden darf
+func ValidateNamespace(name string) error {
+	if !namespacePattern.MatchString(name) {
+		return fmt.Errorf("ungültiger Namespace %q (erlaubt: a-z, 0-9 und -, höchstens 63 Zeichen)", name)
+	}
+	return nil
+}
+
+// NamespaceKey liefert den Redis-Schlüssel key innerhalb eines Namespace
+func NamespaceKey(namespace, key string) string {
+	if namespace == "" || namespace == DefaultNamespace {
+		return key
+	}
+	return "ns:" + namespace + ":" + key
+}
+
+// DispatchQueue liefert die Warteschlange, über die Tasks eines Namespace an Worker gehen
+func DispatchQueue(namespace string) string {
+	if namespace == "" || namespace == DefaultNamespace {
+		return string(TypeTaskCreated)
+	}
+	return string(TypeTaskCreated) + "." + namespace
+}
@@ -0,0 +1,180 @@
+// Package protocol definiert die Nachrichten, die Task-Manager und Worker über den
+// Broker austauschen, sowie die Hülle der Nachrichten an WebSocket-Clients.
+//
+// Kompatibilitätsregeln:
+//
+//   - Jede Nachricht trägt die Protokollversion des Senders im Feld "version".
+//   - Version wird bei jeder inkompatiblen Änderung erhöht: Entfernen oder Umbenennen
+//     eines Feldes, Änderung seines Typs oder seiner Bedeutung, neuer Pflicht-Inhalt.
+//   - Neue optionale Felder und neue Nachrichtentypen erhöhen die Version nicht;
+//     Empfänger ignorieren unbekannte Felder.
+//   - Ein Empfänger akzeptiert nur Versionen von MinVersion bis Version. Nachrichten
+//     ohne Version (von Komponenten vor Einführung dieses Pakets), mit neuerer oder
+//     zu alter Version sowie unbekannte Nachrichtentypen werden mit einem Fehler
+//     abgelehnt statt geraten zu werden.
+//   - Bei gemischten Versionen zuerst alle Empfänger aktualisieren (MinVersion
+//     unverändert lassen), danach die Sender; erst wenn keine alten Sender mehr
+//     laufen, darf MinVersion angehoben werden.
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Version ist die Protokollversion, die diese Komponente sendet
+	Version = 1
+	// MinVersion ist die älteste Protokollversion, die diese Komponente noch versteht
+	MinVersion = 1
+)
+
+// Type ist der Typ einer Broker-Nachricht
+type Type string
+
+// Nachrichtentypen auf dem Broker
+const (
+	// TypeTaskCreated verteilt einen neuen Task an die Worker (Inhalt: Task)
+	TypeTaskCreated Type = "task_created"
+	// TypeTaskStatus meldet den aktuellen Zustand eines Tasks (Inhalt: Task)
+	TypeTaskStatus Type = "task_status"
+	// TypeTaskCheckpoint meldet einen gespeicherten Checkpoint (Inhalt: Checkpoint)
+	TypeTaskCheckpoint Type = "task_checkpoint"
+	// TypeTaskRecovery verteilt einen Task zur Wiederaufnahme ab seinem Checkpoint (Inhalt: Task)
+	TypeTaskRecovery Type = "task_recovery"
+	// TypeTaskMigration verschiebt einen Task auf einen anderen Worker (Inhalt: Migration)
+	TypeTaskMigration Type = "task_migration"
+	// TypeWorkerStatus meldet den Zustand eines Workers (Inhalt: WorkerStatus)
+	TypeWorkerStatus Type = "worker_status"
+)
+
+// knownTypes sind alle Typen, die Decode akzeptiert
+var knownTypes = map[Type]bool{
+	TypeTaskCreated:    true,
+	TypeTaskStatus:     true,
+	TypeTaskCheckpoint: true,
+	TypeTaskRecovery:   true,
+	TypeTaskMigration:  true,
+	TypeWorkerStatus:   true,
+}
+
+var (
+	// ErrUnknownType wird geliefert, wenn der Nachrichtentyp nicht bekannt ist
+	ErrUnknownType = errors.New("unbekannter Nachrichtentyp")
+	// ErrWrongType wird geliefert, wenn der Inhalt als falscher Typ gelesen wird
+	ErrWrongType = errors.New("Inhalt passt nicht zum Nachrichtentyp")
+)
+
+// VersionError beschreibt eine Nachricht mit inkompatibler Protokollversion
+type VersionError struct {
+	// Got ist die Version der Nachricht; 0 bedeutet, dass sie keine Version trägt
+	Got int
+}
+
+func (e *VersionError) Error() string {
+	if e.Got == 0 {
+		return fmt.Sprintf("Nachricht ohne Protokollversion (unterstützt: %d bis %d)", MinVersion, Version)
+	}
+	return fmt.Sprintf("inkompatible Protokollversion %d (unterstützt: %d bis %d)", e.Got, MinVersion, Version)
+}
+
+// Envelope ist die Hülle jeder Broker-Nachricht
+type Envelope struct {
+	Version  int             `json:"version"`
+	Type     Type            `json:"type"`
+	TaskID   string          `json:"task_id,omitempty"`
+	WorkerID string          `json:"worker_id,omitempty"`
+	Content  json.RawMessage `json:"content,omitempty"`
+}
+
+// Encode serialisiert eine Nachricht mit der aktuellen Protokollversion
+func Encode(msgType Type, taskID, workerID string, content interface{}) ([]byte, error) {
+	if !knownTypes[msgType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, msgType)
+	}
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("Inhalt von %s nicht serialisierbar: %w", msgType, err)
+	}
+	return json.Marshal(Envelope{
+		Version:  Version,
+		Type:     msgType,
+		TaskID:   taskID,
+		WorkerID: workerID,
+		Content:  contentJSON,
+	})
+}
+
+// Decode liest die Hülle einer Nachricht und prüft Version und Typ. Der Inhalt
+// wird erst über die typisierten Methoden (Task, Checkpoint, ...) gelesen.
+func Decode(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("ungültige Nachricht: %w", err)
+	}
+	if env.Version < MinVersion || env.Version > Version {
+		return nil, &VersionError{Got: env.Version}
+	}
+	if !knownTypes[env.Type] {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, env.Type)
+	}
+	return &env, nil
+}
+
+// Task liest den Inhalt von task_created, task_status und task_recovery
+func (e *Envelope) Task() (*Task, error) {
+	var task Task
+	if err := e.decode(&task, TypeTaskCreated, TypeTaskStatus, TypeTaskRecovery); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Checkpoint liest den Inhalt von task_checkpoint
+func (e *Envelope) Checkpoint() (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	if err := e.decode(&checkpoint, TypeTaskCheckpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Migration liest den Inhalt von task_migration
+func (e *Envelope) Migration() (*Migration, error) {
+	var migration Migration
+	if err := e.decode(&migration, TypeTaskMigration); err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// WorkerStatus liest den Inhalt von worker_status
+func (e *Envelope) WorkerStatus() (*WorkerStatus, error) {
+	var status WorkerStatus
+	if err := e.decode(&status, TypeWorkerStatus); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// decode liest den Inhalt in v, sofern die Nachricht einen der erlaubten Typen hat
+func (e *Envelope) decode(v interface{}, allowed ...Type) error {
+	match := false
+	for _, t := range allowed {
+		if e.Type == t {
+			match = true
+			break
+		}
+	}
+	if !match {
+		return fmt.Errorf("%w: %s", ErrWrongType, e.Type)
+	}
+	if len(e.Content) == 0 {
+		return fmt.Errorf("Nachricht %s ohne Inhalt", e.Type)
+	}
+	if err := json.Unmarshal(e.Content, v); err != nil {
+		return fmt.Errorf("ungültiger Inhalt von %s: %w", e.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SecretField ist der einzige Schlüssel einer Secret-Referenz in Task-Daten:
+//
+//	{"data": {"password": {"$secret": "db/password"}}}
+//
+// Die Referenz wird erst auf dem Worker unmittelbar vor der Ausführung aufgelöst;
+// der Wert selbst läuft nie über den Broker und wird nie in Redis gespeichert.
+const SecretField = "$secret"
+
+// secretNamePattern erlaubt durch / getrennte Segmente aus Buchstaben, Ziffern, ., _ und -;
+// Segmente aus Punkten (. und ..) sind ausgeschlossen, damit kein Pfad verlassen werden kann
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*(/[A-Za-z0-9_-][A-Za-z0-9._-]*)*$`)
+
+// SecretRef prüft, ob value eine Secret-Referenz ist, und liefert deren Namen
+func SecretRef(value interface{}) (string, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	name, ok := m[SecretField].(string)
+	return name, ok
+}
+
+// ValidateSecretName prüft den Namen einer Secret-Referenz
+func ValidateSecretName(name string) error {
+	if len(name) > 255 || !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("ungültiger Secret-Name %q (erlaubt: durch / getrennte Segmente aus A-Z, a-z, 0-9, ., _ und -)", name)
+	}
+	return nil
+}
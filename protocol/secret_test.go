@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecretRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		wantName string
+		wantOK   bool
+	}{
+		{name: "Referenz", value: map[string]interface{}{SecretField: "db/password"}, wantName: "db/password", wantOK: true},
+		{name: "String", value: "db/password"},
+		{name: "nil", value: nil},
+		{name: "Name kein String", value: map[string]interface{}{SecretField: 1.0}},
+		{name: "weitere Felder", value: map[string]interface{}{SecretField: "a", "b": "c"}},
+		{name: "anderes Feld", value: map[string]interface{}{"secret": "a"}},
+		{name: "Liste", value: []interface{}{map[string]interface{}{SecretField: "a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := SecretRef(tt.value)
+			if name != tt.wantName || ok != tt.wantOK {
+				t.Fatalf("SecretRef = %q, %v, erwartet %q, %v", name, ok, tt.wantName, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateSecretName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "password", valid: true},
+		{name: "db/password", valid: true},
+		{name: "team-a/db_1/pass.word", valid: true},
+		{name: ".hidden/x"},
+		{name: "a/.hidden"},
+		{name: ""},
+		{name: "."},
+		{name: ".."},
+		{name: "../etc/passwd"},
+		{name: "a/../b"},
+		{name: "/etc/passwd"},
+		{name: "a/"},
+		{name: "a//b"},
+		{name: `a\b`},
+		{name: "a b"},
+		{name: "ä"},
+		{name: strings.Repeat("a", 255), valid: true},
+		{name: strings.Repeat("a", 256)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSecretName(tt.name)
+			if tt.valid && err != nil {
+				t.Fatalf("gültiger Name abgelehnt: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("ungültiger Name akzeptiert")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package protocol
+
+// StreamMessage ist die Hülle aller Nachrichten an WebSocket-Clients. Für sie gelten
+// dieselben Kompatibilitätsregeln wie für Broker-Nachrichten; Clients prüfen die
+// Version in der Begrüßung (Typ "welcome") und brechen bei Abweichung ab.
+type StreamMessage struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+	// ID ist die Korrelations-ID einer Client-Anfrage, auf die die Nachricht antwortet
+	ID string `json:"id,omitempty"`
+	// Seq ist die fortlaufende Nummer verteilter Ereignisse (0: nicht nummeriert)
+	Seq uint64 `json:"seq,omitempty"`
+	// Epoch und Resync tragen nur Snapshots; siehe Dokumentation der WebSocket-API
+	Epoch    string      `json:"epoch,omitempty"`
+	Resync   bool        `json:"resync,omitempty"`
+	TaskID   string      `json:"taskId,omitempty"`
+	WorkerID string      `json:"workerId,omitempty"`
+	Content  interface{} `json:"content"`
+}
+
+// NewStreamMessage erstellt eine Nachricht an WebSocket-Clients mit der aktuellen Version
+func NewStreamMessage(msgType string, content interface{}) StreamMessage {
+	return StreamMessage{Version: Version, Type: msgType, Content: content}
+}
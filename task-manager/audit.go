@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// auditStreamKey ist der Redis-Stream mit den Audit-Einträgen; Einträge werden nur angehängt
+	auditStreamKey = "audit_log"
+	// auditMaxParams ist die größte Anfrage, die vollständig im Audit-Log landet
+	auditMaxParams = 4096
+	// auditDefaultLimit und auditMaxLimit begrenzen die Einträge je Abfrage
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+	// auditBatchSize ist die Anzahl Einträge, die beim Durchsuchen auf einmal gelesen werden
+	auditBatchSize = 500
+)
+
+// AuditEntry ist ein Eintrag im Audit-Log: wer hat wann was mit welchem Ergebnis ausgelöst
+type AuditEntry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor ist der authentifizierte Aufrufer; leer, wenn die Authentifizierung fehlschlug
+	Actor    string `json:"actor"`
+	Role     string `json:"role,omitempty"`
+	SourceIP string `json:"sourceIp"`
+	// Interface ist die Schnittstelle des Aufrufs (rest oder grpc)
+	Interface string `json:"interface"`
+	// Action ist die Route ("POST /api/workers/{id}/fail") bzw. die gRPC-Methode
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Namespace string `json:"namespace,omitempty"`
+	// Params sind die Parameter der Anfrage (JSON-Body, bei Bedarf gekürzt)
+	Params json.RawMessage `json:"params,omitempty"`
+	// Status ist der HTTP-Status; bei gRPC der zu Code passende HTTP-Status
+	Status int    `json:"status"`
+	Code   string `json:"code,omitempty"`
+	// Outcome ist success, denied, limited, rejected oder error
+	Outcome    string  `json:"outcome"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// AuditLog speichert alle verändernden API-Aufrufe in einem Redis-Stream
+type AuditLog struct {
+	redisClient *redis.Client
+	// maxEntries begrenzt die Länge des Streams (ungefähr); 0 bedeutet unbegrenzt
+	maxEntries int64
+}
+
+// NewAuditLogFromEnv erstellt ein AuditLog; AUDIT_MAX_ENTRIES begrenzt die Aufbewahrung
+func NewAuditLogFromEnv(redisClient *redis.Client) *AuditLog {
+	return &AuditLog{
+		redisClient: redisClient,
+		maxEntries:  int64(envInt("AUDIT_MAX_ENTRIES", 100000)),
+	}
+}
+
+// Record hängt einen Eintrag an. Ist Redis nicht erreichbar, landet der Eintrag
+// zumindest im Log des Task-Managers.
+func (al *AuditLog) Record(entry *AuditEntry) {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		auditLogger.Errorf("Fehler beim Serialisieren des Audit-Eintrags: %v", err)
+		return
+	}
+	args := &redis.XAddArgs{
+		Stream: auditStreamKey,
+		Values: map[string]interface{}{"entry": entryJSON},
+	}
+	if al.maxEntries > 0 {
+		args.MaxLen = al.maxEntries
+		args.Approx = true
+	}
+	// Nicht den Kontext der Anfrage verwenden: der Eintrag soll auch nach deren Abbruch gespeichert werden
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := al.redisClient.XAdd(ctx, args).Err(); err != nil {
+		auditLogger.Errorf("Fehler beim Speichern des Audit-Eintrags: %v; Eintrag: %s", err, entryJSON)
+	}
+}
+
+// auditOutcome ordnet einen HTTP-Status einem Ergebnis zu
+func auditOutcome(status int) string {
+	switch {
+	case status >= 200 && status < 400:
+		return "success"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "denied"
+	case status == http.StatusTooManyRequests:
+		return "limited"
+	case status >= 500:
+		return "error"
+	}
+	return "rejected"
+}
+
+// auditRedactedField ist das Feld mit den Task-Daten; es landet nie im Audit-Log, da
+// Task-Daten in Redis sonst nur verschlüsselt gespeichert werden
+const auditRedactedField = "data"
+
+// auditParams übernimmt einen Anfrage-Body als JSON ohne Task-Daten; andere oder zu große
+// Bodies als gekürzten String. Bodies, deren Task-Daten sich nicht gezielt entfernen
+// lassen, werden nur mit ihrer Größe festgehalten.
+func auditParams(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) <= auditMaxParams && json.Valid(body) {
+		if params, ok := redactAuditParams(body); ok {
+			return params
+		}
+	}
+	text := string(body)
+	if strings.Contains(text, `"`+auditRedactedField+`"`) {
+		text = fmt.Sprintf("[%d Bytes mit %s nicht protokolliert]", len(body), auditRedactedField)
+	} else if len(text) > auditMaxParams {
+		text = text[:auditMaxParams] + "…"
+	}
+	quoted, _ := json.Marshal(text)
+	return quoted
+}
+
+// redactAuditParams ersetzt das Feld data eines JSON-Objekts. Liefert false, wenn body
+// kein Objekt ist, die Task-Daten aber an anderer Stelle enthalten kann.
+func redactAuditParams(body []byte) (json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return json.RawMessage(body), !bytes.Contains(body, []byte(`"`+auditRedactedField+`"`))
+	}
+	if _, ok := fields[auditRedactedField]; !ok {
+		return json.RawMessage(body), true
+	}
+	fields[auditRedactedField] = json.RawMessage(`"[entfernt]"`)
+	redacted, err := json.Marshal(fields)
+	return redacted, err == nil
+}
+
+// sourceIP liefert die IP-Adresse aus einer Adresse der Form host:port
+func sourceIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+type auditContextKey struct{}
+
+// setAuditActor trägt den authentifizierten Aufrufer in den Audit-Eintrag der Anfrage ein
+func setAuditActor(ctx context.Context, principal *Principal) {
+	if entry, ok := ctx.Value(auditContextKey{}).(*AuditEntry); ok {
+		entry.Actor = principal.Name
+		entry.Role = principal.Role.String()
+	}
+}
+
+// auditBody liest zuerst den für das Audit-Log gelesenen Anfang, dann den Rest des Bodys
+type auditBody struct {
+	io.Reader
+	io.Closer
+}
+
+// auditResponseWriter merkt sich den Status der Antwort
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Middleware protokolliert alle verändernden Anfragen (POST, PUT, PATCH, DELETE),
+// auch abgelehnte. Sie muss vor der Authentifizierung eingehängt werden, die den
+// Aufrufer über setAuditActor nachträgt.
+func (al *AuditLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Nur so viel lesen, wie im Eintrag landen kann; den Rest liest der Handler
+		// direkt, damit auch unauthentifizierte Aufrufer nichts Großes puffern lassen
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, auditMaxParams+1))
+		if err != nil {
+			r.Body.Close()
+			http.Error(w, "Fehler beim Lesen der Anfrage", http.StatusBadRequest)
+			return
+		}
+		r.Body = auditBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+
+		action := r.Method + " " + r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				action = r.Method + " " + template
+			}
+		}
+		namespace, _ := requestNamespace(r)
+		entry := &AuditEntry{
+			Time:      time.Now().UTC(),
+			SourceIP:  sourceIP(r.RemoteAddr),
+			Interface: "rest",
+			Action:    action,
+			Target:    r.URL.Path,
+			Namespace: namespace,
+			Params:    auditParams(body),
+		}
+
+		start := time.Now()
+		recorder := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, entry)))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		entry.Status = recorder.status
+		entry.Outcome = auditOutcome(recorder.status)
+		entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		al.Record(entry)
+	})
+}
+
+// auditQuery sind die Filter einer Abfrage des Audit-Logs; leere Felder bedeuten "alle"
+type auditQuery struct {
+	actor     string
+	action    string
+	target    string
+	outcome   string
+	namespace string
+	since     time.Time
+	until     time.Time
+	// principal beschränkt die Einträge auf die Namespaces des Aufrufers; nil bedeutet alle
+	principal *Principal
+}
+
+// errAuditNamespace meldet einen Namespace-Filter, den der Aufrufer nicht sehen darf
+var errAuditNamespace = errors.New("Kein Zugriff auf Namespace")
+
+// parseAuditQuery liest die Filter actor, action, target (Präfix), outcome, namespace,
+// since und until (RFC 3339) und beschränkt sie auf die Namespaces des Aufrufers
+func parseAuditQuery(r *http.Request) (auditQuery, error) {
+	query := r.URL.Query()
+	q := auditQuery{
+		actor:     query.Get("actor"),
+		action:    query.Get("action"),
+		target:    query.Get("target"),
+		outcome:   query.Get("outcome"),
+		namespace: query.Get("namespace"),
+		principal: principalFromContext(r.Context()),
+	}
+	if q.namespace != "" && q.principal != nil && !q.principal.allows(q.namespace) {
+		return q, fmt.Errorf("%w %s", errAuditNamespace, q.namespace)
+	}
+	for name, dst := range map[string]*time.Time{"since": &q.since, "until": &q.until} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return q, fmt.Errorf("%s ist kein Zeitpunkt im Format RFC 3339: %q", name, value)
+			}
+			*dst = t
+		}
+	}
+	return q, nil
+}
+
+// matches prüft, ob ein Eintrag die Filter erfüllt
+func (q auditQuery) matches(entry *AuditEntry) bool {
+	return (q.actor == "" || entry.Actor == q.actor) &&
+		(q.action == "" || strings.Contains(entry.Action, q.action)) &&
+		(q.target == "" || strings.HasPrefix(entry.Target, q.target)) &&
+		(q.outcome == "" || entry.Outcome == q.outcome) &&
+		(q.namespace == "" || entry.Namespace == q.namespace) &&
+		(q.principal == nil || q.principal.allows(entry.Namespace))
+}
+
+// writeAuditQueryError beantwortet eine ungültige oder unzulässige Abfrage
+func writeAuditQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAuditNamespace) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// streamRange liefert die Grenzen der Stream-IDs für since und until
+func (q auditQuery) streamRange() (start, end string) {
+	start, end = "-", "+"
+	if !q.since.IsZero() {
+		start = strconv.FormatInt(q.since.UnixMilli(), 10)
+	}
+	if !q.until.IsZero() {
+		end = strconv.FormatInt(q.until.UnixMilli(), 10)
+	}
+	return start, end
+}
+
+// scan durchläuft die Einträge im Zeitraum der Abfrage (neueste zuerst, wenn newestFirst)
+// und ruft fn für jeden passenden auf, bis fn false liefert
+func (al *AuditLog) scan(ctx context.Context, q auditQuery, newestFirst bool, fn func(*AuditEntry) bool) error {
+	start, end := q.streamRange()
+	for {
+		var messages []redis.XMessage
+		var err error
+		if newestFirst {
+			messages, err = al.redisClient.XRevRangeN(ctx, auditStreamKey, end, start, auditBatchSize).Result()
+		} else {
+			messages, err = al.redisClient.XRangeN(ctx, auditStreamKey, start, end, auditBatchSize).Result()
+		}
+		if err != nil {
+			return fmt.Errorf("Fehler beim Lesen des Audit-Logs: %w", err)
+		}
+
+		for _, msg := range messages {
+			raw, _ := msg.Values["entry"].(string)
+			var entry AuditEntry
+			if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+				auditLogger.Warnf("Ungültiger Audit-Eintrag %s: %v", msg.ID, err)
+				continue
+			}
+			entry.ID = msg.ID
+			if q.matches(&entry) && !fn(&entry) {
+				return nil
+			}
+		}
+		if len(messages) < auditBatchSize {
+			return nil
+		}
+
+		// Nächster Block ab der ID nach bzw. vor dem letzten Eintrag
+		last := messages[len(messages)-1].ID
+		if newestFirst {
+			if end = previousStreamID(last); end == "" {
+				return nil
+			}
+		} else {
+			start = nextStreamID(last)
+		}
+	}
+}
+
+// nextStreamID liefert die kleinste Stream-ID nach id ("ms-seq")
+func nextStreamID(id string) string {
+	ms, seq := splitStreamID(id)
+	return fmt.Sprintf("%d-%d", ms, seq+1)
+}
+
+// previousStreamID liefert die größte Stream-ID vor id oder "", wenn es keine gibt
+func previousStreamID(id string) string {
+	ms, seq := splitStreamID(id)
+	switch {
+	case seq > 0:
+		return fmt.Sprintf("%d-%d", ms, seq-1)
+	case ms > 0:
+		return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64))
+	}
+	return ""
+}
+
+func splitStreamID(id string) (uint64, uint64) {
+	parts := strings.SplitN(id, "-", 2)
+	ms, _ := strconv.ParseUint(parts[0], 10, 64)
+	var seq uint64
+	if len(parts) == 2 {
+		seq, _ = strconv.ParseUint(parts[1], 10, 64)
+	}
+	return ms, seq
+}
+
+// HandleListAudit ist der HTTP-Handler für GET /api/audit. Liefert die neuesten
+// passenden Einträge zuerst, höchstens limit (Standard 100, höchstens 1000).
+func (al *AuditLog) HandleListAudit(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		writeAuditQueryError(w, err)
+		return
+	}
+	limit := auditDefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > auditMaxLimit {
+			http.Error(w, fmt.Sprintf("limit muss zwischen 1 und %d liegen", auditMaxLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := []*AuditEntry{}
+	err = al.scan(r.Context(), q, true, func(entry *AuditEntry) bool {
+		entries = append(entries, entry)
+		return len(entries) < limit
+	})
+	if err != nil {
+		auditLogger.Errorf("%v", err)
+		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// HandleExportAudit ist der HTTP-Handler für GET /api/audit/export. Liefert alle
+// passenden Einträge in zeitlicher Reihenfolge als NDJSON (ein JSON-Objekt pro Zeile).
+func (al *AuditLog) HandleExportAudit(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		writeAuditQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	err = al.scan(r.Context(), q, false, func(entry *AuditEntry) bool {
+		if err := encoder.Encode(entry); err != nil {
+			return false
+		}
+		count++
+		if flusher != nil && count%auditBatchSize == 0 {
+			flusher.Flush()
+		}
+		return true
+	})
+	if err != nil {
+		// Die Antwort hat bereits begonnen; der Abbruch ist nur im Log sichtbar
+		auditLogger.Warnf("Export des Audit-Logs nach %d Einträgen abgebrochen: %v", count, err)
+	}
+}
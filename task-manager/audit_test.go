@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestAuditLog(t *testing.T) *AuditLog {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &AuditLog{redisClient: client}
+}
+
+// auditEntries liefert alle Einträge des Audit-Logs, älteste zuerst
+func auditEntries(t *testing.T, al *AuditLog) []*AuditEntry {
+	t.Helper()
+	var entries []*AuditEntry
+	if err := al.scan(context.Background(), auditQuery{}, false, func(entry *AuditEntry) bool {
+		entries = append(entries, entry)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestAuditMiddlewareBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantParams string
+	}{
+		{"json", `{"priority":1}`, `{"priority":1}`},
+		{"leer", "", ""},
+		{"groß", strings.Repeat("x", 10*auditMaxParams), `"` + strings.Repeat("x", auditMaxParams) + `…"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			al := newTestAuditLog(t)
+			var received string
+			handler := al.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				received = string(body)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+
+			req := httptest.NewRequest("POST", "/api/tasks", strings.NewReader(tt.body))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Der Handler erhält den vollständigen Body, das Audit-Log nur den Anfang
+			if received != tt.body {
+				t.Fatalf("Handler erhielt %d Bytes, erwartet %d", len(received), len(tt.body))
+			}
+			entries := auditEntries(t, al)
+			if len(entries) != 1 {
+				t.Fatalf("%d Einträge, erwartet 1", len(entries))
+			}
+			if got := string(entries[0].Params); got != tt.wantParams {
+				t.Fatalf("Params = %.60s, erwartet %.60s", got, tt.wantParams)
+			}
+			if entries[0].Status != http.StatusAccepted || entries[0].Outcome != "success" {
+				t.Fatalf("Status %d, Ergebnis %s", entries[0].Status, entries[0].Outcome)
+			}
+		})
+	}
+}
+
+func TestAuditMiddlewareSkipsReads(t *testing.T) {
+	al := newTestAuditLog(t)
+	handler := al.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/tasks", nil))
+	if entries := auditEntries(t, al); len(entries) != 0 {
+		t.Fatalf("%d Einträge für GET, erwartet 0", len(entries))
+	}
+}
+
+func TestAuditQueryNamespaces(t *testing.T) {
+	al := newTestAuditLog(t)
+	for _, namespace := range []string{"default", "team-a", "team-b", ""} {
+		al.Record(&AuditEntry{Time: time.Now().UTC(), Action: "POST /api/tasks", Namespace: namespace, Outcome: "success"})
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{"uneingeschränkt", nil, "", http.StatusOK, []string{"default", "team-a", "team-b", ""}},
+		{"uneingeschränkt mit Filter", nil, "?namespace=team-b", http.StatusOK, []string{"team-b"}},
+		{"eingeschränkt", []string{"team-a"}, "", http.StatusOK, []string{"team-a"}},
+		{"eingeschränkt mit eigenem Filter", []string{"team-a", "default"}, "?namespace=default", http.StatusOK, []string{"default"}},
+		{"eingeschränkt mit fremdem Filter", []string{"team-a"}, "?namespace=team-b", http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := &Principal{Name: "admin", Role: RoleAdmin, Namespaces: tt.namespaces}
+			for _, path := range []string{"/api/audit", "/api/audit/export"} {
+				req := httptest.NewRequest("GET", path+tt.query, nil)
+				req = req.WithContext(withPrincipal(req.Context(), principal))
+				rec := httptest.NewRecorder()
+				if path == "/api/audit" {
+					al.HandleListAudit(rec, req)
+				} else {
+					al.HandleExportAudit(rec, req)
+				}
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("%s: Status %d, erwartet %d", path, rec.Code, tt.wantStatus)
+				}
+				if tt.wantStatus != http.StatusOK {
+					continue
+				}
+
+				var entries []AuditEntry
+				if path == "/api/audit" {
+					if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					decoder := json.NewDecoder(rec.Body)
+					for decoder.More() {
+						var entry AuditEntry
+						if err := decoder.Decode(&entry); err != nil {
+							t.Fatal(err)
+						}
+						entries = append(entries, entry)
+					}
+				}
+				got := []string{}
+				for _, entry := range entries {
+					got = append(got, entry.Namespace)
+				}
+				sort.Strings(got)
+				want := append([]string{}, tt.want...)
+				sort.Strings(want)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: Namespaces %q, erwartet %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditParams(t *testing.T) {
+	large := `{"type":"process_data","data":{"items":"` + strings.Repeat("x", auditMaxParams) + `"}}`
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"leer", "", ""},
+		{"ohne data", `{"worker_id":"worker-2"}`, `{"worker_id":"worker-2"}`},
+		{"data entfernt", `{"type":"process_data","priority":2,"data":{"password":"geheim"}}`, `{"data":"[entfernt]","priority":2,"type":"process_data"}`},
+		{"kein Objekt", `[{"data":{"password":"geheim"}}]`, `"[32 Bytes mit data nicht protokolliert]"`},
+		{"zu groß mit data", large, fmt.Sprintf(`"[%d Bytes mit data nicht protokolliert]"`, len(large))},
+		{"kein JSON", "a=b", `"a=b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(auditParams([]byte(tt.body)))
+			if got != tt.want {
+				t.Fatalf("auditParams = %s, erwartet %s", got, tt.want)
+			}
+			if strings.Contains(got, "geheim") {
+				t.Fatalf("Task-Daten im Audit-Log: %s", got)
+			}
+		})
+	}
+}
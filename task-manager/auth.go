@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// ErrUnauthenticated wird geliefert, wenn keine oder ungültige Zugangsdaten vorliegen
+var ErrUnauthenticated = errors.New("Nicht authentifiziert")
+
+// Role ist die Berechtigungsstufe eines Aufrufers; jede Rolle umfasst die niedrigeren
+type Role int
+
+const (
+	// RolePublic kennzeichnet Routen, die keine Zugangsdaten erfordern
+	RolePublic Role = iota
+	// RoleViewer darf lesen
+	RoleViewer
+	// RoleOperator darf zusätzlich Tasks erstellen, abbrechen, pausieren und migrieren
+	RoleOperator
+	// RoleAdmin darf zusätzlich Ausfälle simulieren und die Konfiguration ändern
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RolePublic:   "public",
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// parseRole liest einen Rollennamen (viewer, operator, admin)
+func parseRole(name string) (Role, bool) {
+	for role, roleName := range roleNames {
+		if role != RolePublic && strings.EqualFold(roleName, name) {
+			return role, true
+		}
+	}
+	return RolePublic, false
+}
+
+// Principal ist ein authentifizierter Aufrufer
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"-"`
+	// Namespaces sind die Namespaces, auf die der Aufrufer zugreifen darf; leer bedeutet alle
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// allows gibt an, ob der Aufrufer auf einen Namespace zugreifen darf
+func (p *Principal) allows(namespace string) bool {
+	if len(p.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range p.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymousPrincipal ist der Aufrufer, wenn die Authentifizierung abgeschaltet ist
+var anonymousPrincipal = &Principal{Name: "anonym", Role: RoleAdmin}
+
+// Verifier prüft Zugangsdaten einer bestimmten Art. Passt ein Token nicht zu dieser
+// Art, liefert Verify genau ErrUnauthenticated, damit der nächste Verifier es versucht.
+type Verifier interface {
+	Verify(token string) (*Principal, error)
+}
+
+// Authenticator prüft die Zugangsdaten von REST-, gRPC- und WebSocket-Aufrufen.
+// Ohne konfigurierte Verifier ist die Authentifizierung abgeschaltet.
+type Authenticator struct {
+	verifiers []Verifier
+}
+
+// NewAuthenticatorFromEnv richtet die Verifier aus den Umgebungsvariablen ein:
+// API_KEYS für statische Schlüssel sowie JWT_HS256_SECRET bzw.
+// JWT_RS256_PUBLIC_KEY_FILE für lokal geprüfte JWTs
+func NewAuthenticatorFromEnv() *Authenticator {
+	a := &Authenticator{}
+	if keys := parseAPIKeys(os.Getenv("API_KEYS")); len(keys) > 0 {
+		a.verifiers = append(a.verifiers, keys)
+		authLogger.Infof("%d API-Schlüssel konfiguriert", len(keys))
+	}
+	a.verifiers = append(a.verifiers, jwtVerifiersFromEnv()...)
+
+	if !a.Enabled() {
+		authLogger.Warnf("Weder API_KEYS noch JWT-Schlüssel gesetzt, Authentifizierung ist abgeschaltet")
+	}
+	return a
+}
+
+// Enabled gibt an, ob Zugangsdaten geprüft werden
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.verifiers) > 0
+}
+
+// Authenticate prüft ein Token und liefert den zugehörigen Aufrufer
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	if !a.Enabled() {
+		return anonymousPrincipal, nil
+	}
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+	result := ErrUnauthenticated
+	for _, v := range a.verifiers {
+		principal, err := v.Verify(token)
+		if err == nil {
+			return principal, nil
+		}
+		if err != ErrUnauthenticated {
+			// Genauere Begründung eines Verifiers, zu dem das Token gehört
+			result = err
+		}
+	}
+	return nil, result
+}
+
+// Middleware prüft Zugangsdaten und Rolle jeder Anfrage gegen routeRoles und legt
+// den Aufrufer im Kontext ab. Sie muss per Router.Use eingehängt werden, damit die
+// passende Route bekannt ist.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			setAuditActor(r.Context(), anonymousPrincipal)
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), anonymousPrincipal)))
+			return
+		}
+		required, template := requiredRole(r)
+		if required == RolePublic || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" && queryTokenRoutes[template] {
+			token = r.URL.Query().Get("token")
+		}
+		principal, err := a.Authenticate(token)
+		if err != nil {
+			writeUnauthorized(w, err)
+			return
+		}
+		setAuditActor(r.Context(), principal)
+		if principal.Role < required {
+			authLogger.Event("access_denied").Warnf("Zugriff verweigert: %s (%s) auf %s %s, erforderlich: %s",
+				principal.Name, principal.Role, r.Method, template, required)
+			http.Error(w, fmt.Sprintf("Rolle %s erforderlich", required), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+type principalContextKey struct{}
+
+func withPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// principalFromContext liefert den Aufrufer einer Anfrage oder nil
+func principalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// apiKey ist ein statischer API-Schlüssel mit seinem Inhaber
+type apiKey struct {
+	key       []byte
+	principal Principal
+}
+
+// apiKeys prüft statische API-Schlüssel
+type apiKeys []apiKey
+
+// parseAPIKeys liest API-Schlüssel im Format "name:schlüssel[:rolle[:ns1|ns2]],...";
+// ohne Rolle erhält ein Schlüssel nur Leserechte, ohne Namespaces gilt er für alle
+func parseAPIKeys(value string) apiKeys {
+	var keys apiKeys
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 4)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			authLogger.Fatalf("Ungültiger Eintrag in API_KEYS (erwartet name:schlüssel[:rolle[:namespaces]]): %q", entry)
+		}
+		role := RoleViewer
+		if len(parts) >= 3 {
+			var ok bool
+			if role, ok = parseRole(parts[2]); !ok {
+				authLogger.Fatalf("Unbekannte Rolle %q für API-Schlüssel %s", parts[2], parts[0])
+			}
+		}
+		var namespaces []string
+		if len(parts) == 4 {
+			for _, ns := range strings.Split(parts[3], "|") {
+				if err := protocol.ValidateNamespace(ns); err != nil {
+					authLogger.Fatalf("Ungültiger Namespace für API-Schlüssel %s: %v", parts[0], err)
+				}
+				namespaces = append(namespaces, ns)
+			}
+		}
+		keys = append(keys, apiKey{key: []byte(parts[1]), principal: Principal{Name: parts[0], Role: role, Namespaces: namespaces}})
+	}
+	return keys
+}
+
+// Verify vergleicht das Token mit allen Schlüsseln
+func (keys apiKeys) Verify(token string) (*Principal, error) {
+	// Alle Schlüssel in konstanter Zeit vergleichen, damit die Laufzeit nichts verrät
+	var found *Principal
+	for i := range keys {
+		if subtle.ConstantTimeCompare(keys[i].key, []byte(token)) == 1 {
+			found = &keys[i].principal
+		}
+	}
+	if found == nil {
+		return nil, ErrUnauthenticated
+	}
+	return found, nil
+}
+
+// bearerToken liest das Token aus dem Header "Authorization: Bearer <token>"
+func bearerToken(r *http.Request) string {
+	return parseBearer(r.Header.Get("Authorization"))
+}
+
+// parseBearer liest das Token aus dem Wert eines Authorization-Headers
+func parseBearer(header string) string {
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// writeUnauthorized antwortet mit 401 und dem passenden WWW-Authenticate-Header
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="task-manager"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,154 @@
+// Package client ist ein typisierter Go-Client für die REST-API des Task-Managers.
+//
+// Die Typen und Methoden in client_gen.go werden aus task-manager/openapi.json
+// generiert. Nach Änderungen am Dokument neu generieren mit:
+//
+//	go generate ./client
+package client
+
+//go:generate go run ./internal/gen -spec ../openapi.json -out client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client spricht mit einem Task-Manager
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	requestEditors []RequestEditor
+}
+
+// RequestEditor kann jede Anfrage vor dem Senden anpassen, z.B. um Header zu setzen
+type RequestEditor func(req *http.Request) error
+
+// Option konfiguriert einen Client
+type Option func(c *Client)
+
+// WithHTTPClient setzt den zu verwendenden http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRequestEditor fügt einen RequestEditor hinzu, der auf jede Anfrage angewendet wird
+func WithRequestEditor(editor RequestEditor) Option {
+	return func(c *Client) {
+		c.requestEditors = append(c.requestEditors, editor)
+	}
+}
+
+// WithBearerToken sendet einen API-Schlüssel oder JWT als "Authorization: Bearer <token>"
+func WithBearerToken(token string) Option {
+	return WithRequestEditor(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// WithNamespace sendet alle Anfragen im angegebenen Namespace (Header X-Namespace)
+func WithNamespace(namespace string) Option {
+	return WithRequestEditor(func(req *http.Request) error {
+		req.Header.Set("X-Namespace", namespace)
+		return nil
+	})
+}
+
+// NewClient erstellt einen Client für den Task-Manager unter baseURL (z.B. "http://localhost:8080")
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError wird zurückgegeben, wenn der Task-Manager mit einem Fehlerstatus antwortet
+type APIError struct {
+	StatusCode int
+	Body       []byte
+	// RetryAfter ist bei 429 die vom Task-Manager empfohlene Wartezeit
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Task-Manager antwortete mit %d: %s", e.StatusCode, strings.TrimSpace(string(e.Body)))
+}
+
+// ValidationErrors dekodiert feldgenaue Fehler aus einer 422-Antwort
+func (e *APIError) ValidationErrors() (*ValidationError, bool) {
+	if e.StatusCode != http.StatusUnprocessableEntity {
+		return nil, false
+	}
+	var verr ValidationError
+	if err := json.Unmarshal(e.Body, &verr); err != nil {
+		return nil, false
+	}
+	return &verr, true
+}
+
+// do sendet eine Anfrage und dekodiert die JSON-Antwort in out (falls nicht nil)
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("Fehler beim Serialisieren der Anfrage: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, editor := range c.requestEditors {
+		if err := editor(req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Lesen der Antwort: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: respBody}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return apiErr
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("Fehler beim Deserialisieren der Antwort: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+// Code generated by internal/gen from openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// AuditEntry entspricht #/components/schemas/AuditEntry
+type AuditEntry struct {
+	Action     string          `json:"action,omitempty"`
+	Actor      string          `json:"actor,omitempty"`
+	Code       string          `json:"code,omitempty"`
+	DurationMs float64         `json:"durationMs,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Interface  string          `json:"interface,omitempty"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Outcome    string          `json:"outcome,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Role       string          `json:"role,omitempty"`
+	SourceIp   string          `json:"sourceIp,omitempty"`
+	Status     int             `json:"status,omitempty"`
+	Target     string          `json:"target,omitempty"`
+	Time       *time.Time      `json:"time,omitempty"`
+}
+
+// CoalescingStats entspricht #/components/schemas/CoalescingStats
+type CoalescingStats struct {
+	Ratio    float64 `json:"ratio,omitempty"`
+	Received int     `json:"received,omitempty"`
+	Sent     int     `json:"sent,omitempty"`
+}
+
+// CreateTaskRequest entspricht #/components/schemas/CreateTaskRequest
+type CreateTaskRequest struct {
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Priority int                    `json:"priority,omitempty"`
+	Type     string                 `json:"type"`
+}
+
+// DependencyStatus entspricht #/components/schemas/DependencyStatus
+type DependencyStatus struct {
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Status    string  `json:"status,omitempty"`
+}
+
+// EncryptionStatus entspricht #/components/schemas/EncryptionStatus
+type EncryptionStatus struct {
+	Enabled bool     `json:"enabled,omitempty"`
+	KeyIds  []string `json:"keyIds,omitempty"`
+	Primary string   `json:"primary,omitempty"`
+}
+
+// FieldError entspricht #/components/schemas/FieldError
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// LogLevels entspricht #/components/schemas/LogLevels
+type LogLevels struct {
+	Components map[string]interface{} `json:"components,omitempty"`
+	Default    string                 `json:"default,omitempty"`
+}
+
+// MigrateTaskRequest entspricht #/components/schemas/MigrateTaskRequest
+type MigrateTaskRequest struct {
+	WorkerID string `json:"worker_id"`
+}
+
+// QueueStatus entspricht #/components/schemas/QueueStatus
+type QueueStatus struct {
+	Consumers              int    `json:"consumers,omitempty"`
+	Exists                 bool   `json:"exists,omitempty"`
+	Messages               int    `json:"messages,omitempty"`
+	MessagesReady          int    `json:"messages_ready,omitempty"`
+	MessagesUnacknowledged int    `json:"messages_unacknowledged,omitempty"`
+	Name                   string `json:"name,omitempty"`
+	Vhost                  string `json:"vhost,omitempty"`
+}
+
+// QuotaLimits Limits eines Clients; 0 bedeutet unbegrenzt
+type QuotaLimits struct {
+	Burst          int     `json:"burst,omitempty"`
+	MaxOutstanding int     `json:"maxOutstanding,omitempty"`
+	Rate           float64 `json:"rate,omitempty"`
+}
+
+// QuotaUsage entspricht #/components/schemas/QuotaUsage
+type QuotaUsage struct {
+	Client      string      `json:"client,omitempty"`
+	Limits      QuotaLimits `json:"limits,omitempty"`
+	Outstanding int         `json:"outstanding,omitempty"`
+	Tokens      float64     `json:"tokens,omitempty"`
+}
+
+// RewrapResult entspricht #/components/schemas/RewrapResult
+type RewrapResult struct {
+	Conflicts int    `json:"conflicts,omitempty"`
+	Failed    int    `json:"failed,omitempty"`
+	Primary   string `json:"primary,omitempty"`
+	Rewrapped int    `json:"rewrapped,omitempty"`
+	Scanned   int    `json:"scanned,omitempty"`
+}
+
+// StatusResponse entspricht #/components/schemas/StatusResponse
+type StatusResponse struct {
+	Status string `json:"status,omitempty"`
+}
+
+// StuckTasks entspricht #/components/schemas/StuckTasks
+type StuckTasks struct {
+	Stalled int      `json:"stalled,omitempty"`
+	TaskIds []string `json:"task_ids,omitempty"`
+	Waiting int      `json:"waiting,omitempty"`
+}
+
+// SystemEvent entspricht #/components/schemas/SystemEvent
+type SystemEvent map[string]interface{}
+
+// SystemStatus entspricht #/components/schemas/SystemStatus
+type SystemStatus struct {
+	CheckedAt    time.Time              `json:"checked_at"`
+	Dependencies map[string]interface{} `json:"dependencies"`
+	Namespace    string                 `json:"namespace"`
+	Queues       []QueueStatus          `json:"queues"`
+	Reasons      []string               `json:"reasons"`
+	Status       string                 `json:"status"`
+	StuckTasks   StuckTasks             `json:"stuck_tasks"`
+	Tasks        map[string]interface{} `json:"tasks"`
+	Workers      map[string]interface{} `json:"workers"`
+}
+
+// Task entspricht #/components/schemas/Task
+type Task struct {
+	CheckpointData map[string]interface{} `json:"checkpoint_data,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	ID             string                 `json:"id"`
+	Priority       int                    `json:"priority"`
+	Progress       int                    `json:"progress"`
+	Status         string                 `json:"status"`
+	Type           string                 `json:"type"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	WorkerID       string                 `json:"worker_id,omitempty"`
+}
+
+// TaskSchema entspricht #/components/schemas/TaskSchema
+type TaskSchema struct {
+	Schema    map[string]interface{} `json:"schema"`
+	TaskType  string                 `json:"task_type"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+	Version   int                    `json:"version"`
+}
+
+// ValidationError entspricht #/components/schemas/ValidationError
+type ValidationError struct {
+	Error  string       `json:"error,omitempty"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// WebSocketCounters entspricht #/components/schemas/WebSocketCounters
+type WebSocketCounters struct {
+	ConnectionsDropped  int `json:"connectionsDropped,omitempty"`
+	ConnectionsOpened   int `json:"connectionsOpened,omitempty"`
+	ConnectionsRejected int `json:"connectionsRejected,omitempty"`
+	ConnectionsTimedOut int `json:"connectionsTimedOut,omitempty"`
+	MessagesDropped     int `json:"messagesDropped,omitempty"`
+	MessagesTooLarge    int `json:"messagesTooLarge,omitempty"`
+}
+
+// WebSocketStats entspricht #/components/schemas/WebSocketStats
+type WebSocketStats struct {
+	ActiveConnections int               `json:"activeConnections,omitempty"`
+	Counters          WebSocketCounters `json:"counters,omitempty"`
+	ProgressUpdates   CoalescingStats   `json:"progressUpdates,omitempty"`
+}
+
+// Worker entspricht #/components/schemas/Worker
+type Worker struct {
+	ID       string     `json:"id"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	Status   string     `json:"status"`
+}
+
+// CancelTask Task abbrechen (POST /api/tasks/{id}/cancel)
+func (c *Client) CancelTask(ctx context.Context, taskID string) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/tasks/%s/cancel", url.PathEscape(taskID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateTask Task erstellen (POST /api/tasks)
+func (c *Client) CreateTask(ctx context.Context, body CreateTaskRequest) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, "POST", "/api/tasks", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FailWorker Worker-Ausfall simulieren (POST /api/workers/{id}/fail)
+func (c *Client) FailWorker(ctx context.Context, workerID string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/workers/%s/fail", url.PathEscape(workerID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetEncryptionStatus Status der Verschlüsselung von Task-Daten abrufen (GET /api/encryption)
+func (c *Client) GetEncryptionStatus(ctx context.Context) (*EncryptionStatus, error) {
+	var out EncryptionStatus
+	if err := c.do(ctx, "GET", "/api/encryption", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLogLevels Log-Stufen abrufen (GET /api/system/logging)
+func (c *Client) GetLogLevels(ctx context.Context) (*LogLevels, error) {
+	var out LogLevels
+	if err := c.do(ctx, "GET", "/api/system/logging", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPISpec Dieses OpenAPI-Dokument abrufen (GET /api/openapi.json)
+func (c *Client) GetOpenAPISpec(ctx context.Context) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	if err := c.do(ctx, "GET", "/api/openapi.json", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOwnQuota Limits und Verbrauch des aufrufenden Clients abrufen (GET /api/quotas)
+func (c *Client) GetOwnQuota(ctx context.Context) (*QuotaUsage, error) {
+	var out QuotaUsage
+	if err := c.do(ctx, "GET", "/api/quotas", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetQuota Limits und Verbrauch eines Clients abrufen (GET /api/quotas/{client})
+func (c *Client) GetQuota(ctx context.Context, quotaClient string) (*QuotaUsage, error) {
+	var out QuotaUsage
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/quotas/%s", url.PathEscape(quotaClient)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSchema Schema eines Task-Typs abrufen (GET /api/schemas/{type})
+func (c *Client) GetSchema(ctx context.Context, taskType string) (*TaskSchema, error) {
+	var out TaskSchema
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/schemas/%s", url.PathEscape(taskType)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSystemStatus System-Status abrufen (GET /api/system/status)
+func (c *Client) GetSystemStatus(ctx context.Context) (*SystemStatus, error) {
+	var out SystemStatus
+	if err := c.do(ctx, "GET", "/api/system/status", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTask Einzelnen Task abrufen (GET /api/tasks/{id})
+func (c *Client) GetTask(ctx context.Context, taskID string) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/tasks/%s", url.PathEscape(taskID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWebSocketStats Zähler der WebSocket-Verbindungen abrufen (GET /api/system/websocket)
+func (c *Client) GetWebSocketStats(ctx context.Context) (*WebSocketStats, error) {
+	var out WebSocketStats
+	if err := c.do(ctx, "GET", "/api/system/websocket", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWorker Worker-Status abrufen (GET /api/workers/{id})
+func (c *Client) GetWorker(ctx context.Context, workerID string) (*Worker, error) {
+	var out Worker
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/workers/%s", url.PathEscape(workerID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAudit Audit-Log verändernder API-Aufrufe abfragen (neueste zuerst) (GET /api/audit)
+func (c *Client) ListAudit(ctx context.Context) ([]AuditEntry, error) {
+	var out []AuditEntry
+	if err := c.do(ctx, "GET", "/api/audit", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSchemas Alle Payload-Schemas abrufen (GET /api/schemas)
+func (c *Client) ListSchemas(ctx context.Context) ([]TaskSchema, error) {
+	var out []TaskSchema
+	if err := c.do(ctx, "GET", "/api/schemas", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListSystemEvents Systemereignisse abrufen (GET /api/system/events)
+func (c *Client) ListSystemEvents(ctx context.Context) ([]SystemEvent, error) {
+	var out []SystemEvent
+	if err := c.do(ctx, "GET", "/api/system/events", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTasks Alle Tasks abrufen (GET /api/tasks)
+func (c *Client) ListTasks(ctx context.Context) ([]Task, error) {
+	var out []Task
+	if err := c.do(ctx, "GET", "/api/tasks", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListWorkers Alle Worker abrufen (GET /api/workers)
+func (c *Client) ListWorkers(ctx context.Context) ([]Worker, error) {
+	var out []Worker
+	if err := c.do(ctx, "GET", "/api/workers", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MigrateTask Task-Migration auslösen (POST /api/tasks/{id}/migrate)
+func (c *Client) MigrateTask(ctx context.Context, taskID string, body MigrateTaskRequest) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/tasks/%s/migrate", url.PathEscape(taskID)), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OverloadWorker Worker-Überlastung simulieren (POST /api/workers/{id}/overload)
+func (c *Client) OverloadWorker(ctx context.Context, workerID string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/workers/%s/overload", url.PathEscape(workerID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PauseTask Task anhalten (POST /api/tasks/{id}/pause)
+func (c *Client) PauseTask(ctx context.Context, taskID string) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/tasks/%s/pause", url.PathEscape(taskID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecoverWorker Ausgefallenen Worker wiederherstellen (POST /api/workers/{id}/recover)
+func (c *Client) RecoverWorker(ctx context.Context, workerID string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/workers/%s/recover", url.PathEscape(workerID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RegisterSchema Schema registrieren oder ersetzen (PUT /api/schemas/{type})
+func (c *Client) RegisterSchema(ctx context.Context, taskType string, body map[string]interface{}) (*TaskSchema, error) {
+	var out TaskSchema
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/schemas/%s", url.PathEscape(taskType)), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumeTask Pausierten Task fortsetzen (POST /api/tasks/{id}/resume)
+func (c *Client) ResumeTask(ctx context.Context, taskID string) (*Task, error) {
+	var out Task
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/tasks/%s/resume", url.PathEscape(taskID)), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RewrapEncryptionKeys Datenschlüssel aller Tasks und Checkpoints mit dem aktuellen Primärschlüssel neu verschlüsseln (POST /api/encryption/rewrap)
+func (c *Client) RewrapEncryptionKeys(ctx context.Context) (*RewrapResult, error) {
+	var out RewrapResult
+	if err := c.do(ctx, "POST", "/api/encryption/rewrap", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetLogLevels Log-Stufen zur Laufzeit ändern (PUT /api/system/logging)
+func (c *Client) SetLogLevels(ctx context.Context, body LogLevels) (*LogLevels, error) {
+	var out LogLevels
+	if err := c.do(ctx, "PUT", "/api/system/logging", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetQuotaLimits Limits eines Clients festlegen (PUT /api/quotas/{client})
+func (c *Client) SetQuotaLimits(ctx context.Context, quotaClient string, body QuotaLimits) (*QuotaUsage, error) {
+	var out QuotaUsage
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/quotas/%s", url.PathEscape(quotaClient)), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,360 @@
+// Command gen erzeugt client_gen.go aus dem OpenAPI-Dokument des Task-Managers.
+//
+// Unterstützt wird genau die Teilmenge von OpenAPI 3, die openapi.json verwendet:
+// Komponenten-Schemas, Pfadparameter vom Typ string, JSON-Request-Bodies und
+// JSON-Antworten mit $ref, Arrays oder freien Objekten.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `json:"parameters"`
+		Schemas    map[string]*schema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+
+	method string
+	path   string
+}
+
+type parameter struct {
+	Ref  string `json:"$ref"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+
+	goName string
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties interface{}        `json:"additionalProperties"`
+}
+
+// initialisms werden in Go-Namen großgeschrieben
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API", "json": "JSON", "ip": "IP", "ndjson": "NDJSON"}
+
+func main() {
+	specPath := flag.String("spec", "../openapi.json", "Pfad zum OpenAPI-Dokument")
+	outPath := flag.String("out", "client_gen.go", "Ausgabedatei")
+	flag.Parse()
+
+	raw, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("Fehler beim Lesen von %s: %v", *specPath, err)
+	}
+
+	var s spec
+	if err := json.Unmarshal(raw, &s); err != nil {
+		log.Fatalf("Fehler beim Parsen von %s: %v", *specPath, err)
+	}
+
+	var body bytes.Buffer
+	writeSchemas(&body, &s)
+	writeOperations(&body, &s)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by internal/gen from openapi.json. DO NOT EDIT.\n\n")
+	buf.WriteString("package client\n\n")
+	buf.WriteString("import (\n")
+	for _, imp := range []struct{ pkg, selector string }{
+		{"context", "context."},
+		{"encoding/json", "json."},
+		{"fmt", "fmt."},
+		{"net/url", "url."},
+		{"time", "time."},
+	} {
+		if bytes.Contains(body.Bytes(), []byte(imp.selector)) {
+			fmt.Fprintf(&buf, "%q\n", imp.pkg)
+		}
+	}
+	buf.WriteString(")\n\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("Generierter Code ist ungültig: %v\n%s", err, buf.String())
+	}
+	if err := ioutil.WriteFile(*outPath, src, 0644); err != nil {
+		log.Fatalf("Fehler beim Schreiben von %s: %v", *outPath, err)
+	}
+}
+
+func writeSchemas(buf *bytes.Buffer, s *spec) {
+	names := sortedKeys(s.Components.Schemas)
+	for _, name := range names {
+		sc := s.Components.Schemas[name]
+		if sc.Description != "" {
+			fmt.Fprintf(buf, "// %s %s\n", name, sc.Description)
+		} else {
+			fmt.Fprintf(buf, "// %s entspricht #/components/schemas/%s\n", name, name)
+		}
+
+		if sc.Type != "object" || len(sc.Properties) == 0 {
+			fmt.Fprintf(buf, "type %s %s\n\n", name, goType(sc, true))
+			continue
+		}
+
+		required := make(map[string]bool)
+		for _, r := range sc.Required {
+			required[r] = true
+		}
+
+		fmt.Fprintf(buf, "type %s struct {\n", name)
+		for _, prop := range sortedKeys(sc.Properties) {
+			propSchema := sc.Properties[prop]
+			typ := goType(propSchema, required[prop])
+			tag := prop
+			if !required[prop] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(buf, "%s %s `json:%q`\n", goName(prop), typ, tag)
+		}
+		buf.WriteString("}\n\n")
+	}
+}
+
+func writeOperations(buf *bytes.Buffer, s *spec) {
+	var ops []*operation
+	for path, methods := range s.Paths {
+		for method, op := range methods {
+			op.method = strings.ToUpper(method)
+			op.path = path
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+
+	for _, op := range ops {
+		if op.OperationID == "" {
+			log.Fatalf("%s %s hat keine operationId", op.method, op.path)
+		}
+		if isStreaming(op) {
+			// Streams wie text/event-stream passen nicht in das Anfrage/Antwort-Schema des Clients
+			continue
+		}
+
+		args := []string{"ctx context.Context"}
+		pathExpr := fmt.Sprintf("%q", op.path)
+		var pathArgs []string
+
+		for _, p := range op.Parameters {
+			param := resolveParameter(s, p)
+			if param.In != "path" {
+				continue
+			}
+			args = append(args, param.goName+" string")
+			pathArgs = append(pathArgs, "url.PathEscape("+param.goName+")")
+		}
+		if len(pathArgs) > 0 {
+			format := pathParamFormat(op.path)
+			pathExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(pathArgs, ", "))
+		}
+
+		bodyArg := "nil"
+		if op.RequestBody != nil {
+			if media, ok := op.RequestBody.Content["application/json"]; ok {
+				args = append(args, "body "+goType(media.Schema, true))
+				bodyArg = "body"
+			}
+		}
+
+		result := responseSchema(op)
+		fmt.Fprintf(buf, "// %s %s (%s %s)\n", op.OperationID, op.Summary, op.method, op.path)
+
+		if result == nil {
+			fmt.Fprintf(buf, "func (c *Client) %s(%s) error {\n", op.OperationID, strings.Join(args, ", "))
+			fmt.Fprintf(buf, "return c.do(ctx, %q, %s, %s, nil)\n}\n\n", op.method, pathExpr, bodyArg)
+			continue
+		}
+
+		typ := goType(result, true)
+		if result.Ref != "" {
+			fmt.Fprintf(buf, "func (c *Client) %s(%s) (*%s, error) {\n", op.OperationID, strings.Join(args, ", "), typ)
+			fmt.Fprintf(buf, "var out %s\n", typ)
+			fmt.Fprintf(buf, "if err := c.do(ctx, %q, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", op.method, pathExpr, bodyArg)
+			buf.WriteString("return &out, nil\n}\n\n")
+		} else {
+			fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s, error) {\n", op.OperationID, strings.Join(args, ", "), typ)
+			fmt.Fprintf(buf, "var out %s\n", typ)
+			fmt.Fprintf(buf, "if err := c.do(ctx, %q, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", op.method, pathExpr, bodyArg)
+			buf.WriteString("return out, nil\n}\n\n")
+		}
+	}
+}
+
+// isStreaming prüft, ob die erfolgreiche Antwort Inhalt, aber kein JSON liefert
+func isStreaming(op *operation) bool {
+	for code, resp := range op.Responses {
+		if !strings.HasPrefix(code, "2") || len(resp.Content) == 0 {
+			continue
+		}
+		if _, ok := resp.Content["application/json"]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// responseSchema liefert das JSON-Schema der ersten 2xx-Antwort
+func responseSchema(op *operation) *schema {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		if media, ok := op.Responses[code].Content["application/json"]; ok {
+			return media.Schema
+		}
+		return nil
+	}
+	return nil
+}
+
+func resolveParameter(s *spec, p *parameter) *parameter {
+	if p.Ref == "" {
+		p.goName = lowerFirst(goName(p.Name))
+		return p
+	}
+	name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+	resolved, ok := s.Components.Parameters[name]
+	if !ok {
+		log.Fatalf("Unbekannter Parameter %s", p.Ref)
+	}
+	resolved.goName = lowerFirst(name)
+	return resolved
+}
+
+// pathParamFormat ersetzt {param} durch %s
+func pathParamFormat(path string) string {
+	var out strings.Builder
+	inParam := false
+	for _, r := range path {
+		switch {
+		case r == '{':
+			inParam = true
+			out.WriteString("%s")
+		case r == '}':
+			inParam = false
+		case !inParam:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+func goType(sc *schema, required bool) string {
+	if sc == nil {
+		return "json.RawMessage"
+	}
+	if sc.Ref != "" {
+		return strings.TrimPrefix(sc.Ref, "#/components/schemas/")
+	}
+
+	switch sc.Type {
+	case "string":
+		if sc.Format == "date-time" {
+			if required {
+				return "time.Time"
+			}
+			return "*time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(sc.Items, true)
+	case "object":
+		if len(sc.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+	}
+	return "json.RawMessage"
+}
+
+// goName wandelt snake_case in einen exportierten Go-Namen um
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	var out strings.Builder
+	for _, part := range parts {
+		if initialism, ok := initialisms[strings.ToLower(part)]; ok {
+			out.WriteString(initialism)
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		out.WriteString(string(runes))
+	}
+	return out.String()
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	// Führende Initialismen wie "ID" komplett klein schreiben
+	for _, initialism := range initialisms {
+		if name == initialism {
+			return strings.ToLower(name)
+		}
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]*schema:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// progressCoalescer fasst reine Fortschritts-Updates eines Tasks innerhalb eines
+// Zeitfensters zusammen. Statuswechsel und Wechsel des Workers werden sofort
+// weitergegeben und verwerfen ein noch wartendes Fortschritts-Update.
+type progressCoalescer struct {
+	window time.Duration
+	// flush verteilt ein Update; es wird unter mutex aufgerufen, damit ein verspätetes
+	// Fortschritts-Update nie nach einem neueren Statuswechsel verteilt wird
+	flush func(*Task)
+
+	mutex   sync.Mutex
+	last    map[string]taskState
+	pending map[string]*Task
+	// received und sent zählen eingehende und tatsächlich verteilte Task-Updates
+	received uint64
+	sent     uint64
+}
+
+// taskState ist der Teil eines Tasks, dessen Änderung sofort verteilt wird
+type taskState struct {
+	status   string
+	workerID string
+}
+
+// CoalescingStats beschreibt die Wirkung des Zusammenfassens
+type CoalescingStats struct {
+	Received uint64 `json:"received"`
+	Sent     uint64 `json:"sent"`
+	// Ratio ist received/sent; 1 bedeutet, dass nichts zusammengefasst wurde
+	Ratio float64 `json:"ratio"`
+}
+
+func newProgressCoalescer(window time.Duration, flush func(*Task)) *progressCoalescer {
+	return &progressCoalescer{
+		window:  window,
+		flush:   flush,
+		last:    make(map[string]taskState),
+		pending: make(map[string]*Task),
+	}
+}
+
+// submit nimmt ein Task-Update entgegen und verteilt es sofort oder nach Ablauf des Fensters
+func (pc *progressCoalescer) submit(task *Task) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	pc.received++
+	state := taskState{status: task.Status, workerID: task.WorkerID}
+	prev, known := pc.last[task.ID]
+
+	if pc.window <= 0 || !known || prev != state {
+		delete(pc.pending, task.ID)
+		if terminalTaskStates[task.Status] {
+			delete(pc.last, task.ID)
+		} else {
+			pc.last[task.ID] = state
+		}
+		pc.sent++
+		pc.flush(task)
+		return
+	}
+
+	if _, waiting := pc.pending[task.ID]; !waiting {
+		id := task.ID
+		time.AfterFunc(pc.window, func() { pc.flushPending(id) })
+	}
+	pc.pending[task.ID] = task
+}
+
+// flushPending verteilt das zuletzt eingegangene Fortschritts-Update eines Tasks
+func (pc *progressCoalescer) flushPending(id string) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	task, ok := pc.pending[id]
+	if !ok {
+		// Inzwischen durch einen Statuswechsel überholt
+		return
+	}
+	delete(pc.pending, id)
+	pc.sent++
+	pc.flush(task)
+}
+
+// stats liefert die Zähler und das Verhältnis von eingegangenen zu verteilten Updates
+func (pc *progressCoalescer) stats() CoalescingStats {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	stats := CoalescingStats{Received: pc.received, Sent: pc.sent, Ratio: 1}
+	if pc.sent > 0 {
+		stats.Ratio = float64(pc.received) / float64(pc.sent)
+	}
+	return stats
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flushRecorder merkt sich die verteilten Updates als "<id>:<status>:<progress>"
+type flushRecorder struct {
+	mutex   sync.Mutex
+	flushed []string
+}
+
+func (r *flushRecorder) flush(task *Task) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.flushed = append(r.flushed, fmt.Sprintf("%s:%s:%d", task.ID, task.Status, task.Progress))
+}
+
+func (r *flushRecorder) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.flushed...)
+}
+
+func TestProgressCoalescer(t *testing.T) {
+	const window = 30 * time.Millisecond
+	task := func(id, status, workerID string, progress int) *Task {
+		return &Task{ID: id, Status: status, WorkerID: workerID, Progress: progress}
+	}
+
+	tests := []struct {
+		name    string
+		window  time.Duration
+		updates []*Task
+		// immediate sind die Updates direkt nach submit in Reihenfolge, want alle Updates
+		// nach Ablauf des Fensters
+		immediate []string
+		want      []string
+	}{
+		{
+			name:      "erstes Update sofort, Fortschritt zusammengefasst",
+			window:    window,
+			updates:   []*Task{task("a", "RUNNING", "w1", 10), task("a", "RUNNING", "w1", 20), task("a", "RUNNING", "w1", 30)},
+			immediate: []string{"a:RUNNING:10"},
+			want:      []string{"a:RUNNING:10", "a:RUNNING:30"},
+		},
+		{
+			name:      "Statuswechsel sofort und verwirft wartenden Fortschritt",
+			window:    window,
+			updates:   []*Task{task("a", "RUNNING", "w1", 10), task("a", "RUNNING", "w1", 50), task("a", "COMPLETED", "w1", 100)},
+			immediate: []string{"a:RUNNING:10", "a:COMPLETED:100"},
+			want:      []string{"a:RUNNING:10", "a:COMPLETED:100"},
+		},
+		{
+			name:      "Wechsel des Workers sofort",
+			window:    window,
+			updates:   []*Task{task("a", "RUNNING", "w1", 10), task("a", "RUNNING", "w2", 10)},
+			immediate: []string{"a:RUNNING:10", "a:RUNNING:10"},
+			want:      []string{"a:RUNNING:10", "a:RUNNING:10"},
+		},
+		{
+			name:      "Tasks unabhängig",
+			window:    window,
+			updates:   []*Task{task("a", "RUNNING", "w1", 10), task("b", "RUNNING", "w1", 10), task("a", "RUNNING", "w1", 20), task("b", "RUNNING", "w1", 20)},
+			immediate: []string{"a:RUNNING:10", "b:RUNNING:10"},
+			want:      []string{"a:RUNNING:10", "b:RUNNING:10", "a:RUNNING:20", "b:RUNNING:20"},
+		},
+		{
+			// Nach einem Endstatus ist der Task vergessen; ein erneutes Update gilt als erstes
+			name:      "Endstatus vergisst den Task",
+			window:    window,
+			updates:   []*Task{task("a", "FAILED", "w1", 10), task("a", "FAILED", "w1", 10)},
+			immediate: []string{"a:FAILED:10", "a:FAILED:10"},
+			want:      []string{"a:FAILED:10", "a:FAILED:10"},
+		},
+		{
+			name:      "ohne Fenster alles sofort",
+			window:    0,
+			updates:   []*Task{task("a", "RUNNING", "w1", 10), task("a", "RUNNING", "w1", 20)},
+			immediate: []string{"a:RUNNING:10", "a:RUNNING:20"},
+			want:      []string{"a:RUNNING:10", "a:RUNNING:20"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &flushRecorder{}
+			pc := newProgressCoalescer(tt.window, recorder.flush)
+			for _, update := range tt.updates {
+				pc.submit(update)
+			}
+			if got := recorder.get(); strings.Join(got, ",") != strings.Join(tt.immediate, ",") {
+				t.Fatalf("sofort verteilt = %v, erwartet %v", got, tt.immediate)
+			}
+
+			// Die Fenster verschiedener Tasks laufen unabhängig ab; ihre Reihenfolge ist offen
+			time.Sleep(3 * window)
+			got, want := recorder.get(), append([]string(nil), tt.want...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Fatalf("verteilt = %v, erwartet %v", got, want)
+			}
+
+			stats := pc.stats()
+			if stats.Received != uint64(len(tt.updates)) || stats.Sent != uint64(len(tt.want)) {
+				t.Fatalf("stats = %+v, erwartet %d/%d", stats, len(tt.updates), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestProgressCoalescerStatsRatio(t *testing.T) {
+	pc := newProgressCoalescer(time.Hour, func(*Task) {})
+	if ratio := pc.stats().Ratio; ratio != 1 {
+		t.Fatalf("Ratio ohne Updates = %v, erwartet 1", ratio)
+	}
+	for i := 0; i < 4; i++ {
+		pc.submit(&Task{ID: "a", Status: "RUNNING", Progress: i})
+	}
+	// Eines sofort verteilt, drei warten noch
+	if ratio := pc.stats().Ratio; ratio != 4 {
+		t.Fatalf("Ratio = %v, erwartet 4", ratio)
+	}
+}
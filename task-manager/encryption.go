@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// encryptedKeyPatterns sind die Redis-Schlüssel mit verschlüsselten Feldern in allen Namespaces
+var encryptedKeyPatterns = []string{"task:*", "ns:*:task:*", "checkpoint:*", "ns:*:checkpoint:*"}
+
+// Encryption verschlüsselt Data und CheckpointData von Tasks, bevor sie in Redis
+// landen, und entschlüsselt sie beim Lesen. Ohne ENCRYPTION_KEYRING_FILE werden
+// die Felder wie bisher im Klartext gespeichert.
+type Encryption struct {
+	redisClient *redis.Client
+	keyring     *protocol.Keyring
+}
+
+// RewrapResult fasst das Ergebnis von POST /api/encryption/rewrap zusammen
+type RewrapResult struct {
+	Primary   string `json:"primary"`
+	Scanned   int    `json:"scanned"`
+	Rewrapped int    `json:"rewrapped"`
+	// Conflicts sind Schlüssel, die während des Neuverschlüsselns geändert wurden;
+	// sie wurden dabei bereits mit dem neuen Schlüsselbund geschrieben oder beim nächsten Lauf erfasst
+	Conflicts int `json:"conflicts"`
+	Failed    int `json:"failed"`
+}
+
+// NewEncryptionFromEnv lädt den Schlüsselbund aus ENCRYPTION_KEYRING_FILE
+func NewEncryptionFromEnv(redisClient *redis.Client) (*Encryption, error) {
+	keyring, err := protocol.LoadKeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if keyring != nil {
+		encryptionLogger.Infof("Verschlüsselung von Task-Daten aktiv (Primärschlüssel %s, %d Schlüssel)",
+			keyring.Primary(), len(keyring.KeyIDs()))
+	}
+	return &Encryption{redisClient: redisClient, keyring: keyring}, nil
+}
+
+// Start prüft regelmäßig (ENCRYPTION_KEYRING_RELOAD, Standard 30s), ob die
+// Schlüsselbund-Datei geändert wurde, damit ein neuer Primärschlüssel ohne Neustart gilt
+func (e *Encryption) Start() {
+	if e.keyring == nil {
+		return
+	}
+	interval := envDuration("ENCRYPTION_KEYRING_RELOAD", 30*time.Second)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			changed, err := e.keyring.Reload()
+			if err != nil {
+				encryptionLogger.Errorf("Fehler beim Neuladen des Schlüsselbunds, bisherige Schlüssel bleiben aktiv: %v", err)
+			} else if changed {
+				encryptionLogger.Infof("Schlüsselbund neu geladen (Primärschlüssel %s)", e.keyring.Primary())
+			}
+		}
+	}()
+}
+
+// sealTask liefert eine Kopie des Tasks mit verschlüsselten Feldern für Redis
+func (e *Encryption) sealTask(task *Task) (*Task, error) {
+	sealed := *task
+	var err error
+	if sealed.Data, err = e.keyring.Seal(task.Data, protocol.DataAAD(task.ID)); err != nil {
+		return nil, fmt.Errorf("Fehler beim Verschlüsseln der Task-Daten: %w", err)
+	}
+	if sealed.CheckpointData, err = e.keyring.Seal(task.CheckpointData, protocol.CheckpointAAD(task.ID)); err != nil {
+		return nil, fmt.Errorf("Fehler beim Verschlüsseln der Checkpoint-Daten: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openTask entschlüsselt die Felder eines Tasks an Ort und Stelle. Felder, die sich
+// nicht entschlüsseln lassen, bleiben verschlüsselt, damit der Task sichtbar bleibt.
+func (e *Encryption) openTask(task *Task) {
+	if data, err := e.keyring.Open(task.Data, protocol.DataAAD(task.ID)); err != nil {
+		encryptionLogger.Task(task.ID).Event("task_decryption_failed").Errorf("Task %s: Daten nicht entschlüsselbar: %v", task.ID, err)
+	} else {
+		task.Data = data
+	}
+	if checkpoint, err := e.keyring.Open(task.CheckpointData, protocol.CheckpointAAD(task.ID)); err != nil {
+		encryptionLogger.Task(task.ID).Event("task_decryption_failed").Errorf("Task %s: Checkpoint-Daten nicht entschlüsselbar: %v", task.ID, err)
+	} else {
+		task.CheckpointData = checkpoint
+	}
+}
+
+// Rewrap verschlüsselt die Datenschlüssel aller Tasks und Checkpoints in Redis mit dem
+// aktuellen Primärschlüssel neu. Danach kann ein alter Schlüssel aus dem Schlüsselbund
+// entfernt werden. Die Inhalte selbst werden dabei nicht neu verschlüsselt.
+func (e *Encryption) Rewrap(ctx context.Context) (RewrapResult, error) {
+	result := RewrapResult{Primary: e.keyring.Primary()}
+	for _, pattern := range encryptedKeyPatterns {
+		iter := e.redisClient.Scan(ctx, 0, pattern, 500).Iterator()
+		for iter.Next(ctx) {
+			result.Scanned++
+			changed, err := e.rewrapKey(ctx, iter.Val())
+			switch {
+			case err == redis.TxFailedErr:
+				result.Conflicts++
+			case err != nil:
+				encryptionLogger.Errorf("Fehler beim Neuverschlüsseln von %s: %v", iter.Val(), err)
+				result.Failed++
+			case changed:
+				result.Rewrapped++
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// rewrapKey verschlüsselt die Datenschlüssel eines Redis-Wertes neu. Der Schlüssel wird
+// beobachtet, damit ein gleichzeitiges Update eines Workers nicht überschrieben wird.
+func (e *Encryption) rewrapKey(ctx context.Context, key string) (bool, error) {
+	changed := false
+	err := e.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// UseNumber, damit Zahlen in unverschlüsselten Feldern unverändert bleiben
+		var value map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		if protocol.IsSealed(value) {
+			// Checkpoint: der gesamte Wert ist verschlüsselt
+			value, changed, err = e.keyring.Rewrap(value)
+			if err != nil {
+				return err
+			}
+		} else {
+			// Task: nur data und checkpoint_data sind verschlüsselt
+			for _, field := range []string{"data", "checkpoint_data"} {
+				fieldValue, ok := value[field].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				rewrapped, fieldChanged, err := e.keyring.Rewrap(fieldValue)
+				if err != nil {
+					return fmt.Errorf("%s: %w", field, err)
+				}
+				value[field] = rewrapped
+				changed = changed || fieldChanged
+			}
+		}
+		if !changed {
+			return nil
+		}
+
+		updated, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}, key)
+	return changed && err == nil, err
+}
+
+// HandleStatus ist der HTTP-Handler für GET /api/encryption
+func (e *Encryption) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": e.keyring != nil,
+		"primary": e.keyring.Primary(),
+		"keyIds":  e.keyring.KeyIDs(),
+	})
+}
+
+// HandleRewrap ist der HTTP-Handler für POST /api/encryption/rewrap
+func (e *Encryption) HandleRewrap(w http.ResponseWriter, r *http.Request) {
+	if e.keyring == nil {
+		http.Error(w, "Verschlüsselung ist nicht aktiviert (ENCRYPTION_KEYRING_FILE)", http.StatusConflict)
+		return
+	}
+	// Vor dem Neuverschlüsseln den aktuellen Stand der Datei übernehmen
+	if _, err := e.keyring.Reload(); err != nil {
+		encryptionLogger.Errorf("Fehler beim Neuladen des Schlüsselbunds: %v", err)
+		http.Error(w, "Schlüsselbund kann nicht geladen werden", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := e.Rewrap(r.Context())
+	if err != nil {
+		encryptionLogger.Errorf("Fehler beim Neuverschlüsseln: %v", err)
+		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
+		return
+	}
+	encryptionLogger.Infof("Datenschlüssel mit %s neu verschlüsselt: %d von %d Schlüsseln, %d Konflikte, %d Fehler",
+		result.Primary, result.Rewrapped, result.Scanned, result.Conflicts, result.Failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
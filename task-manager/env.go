@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// envString liest eine Umgebungsvariable mit Standardwert
+func envString(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envInt liest eine ganzzahlige Umgebungsvariable; ungültige Werte werden protokolliert
+// und durch den Standardwert ersetzt
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		configLogger.Warnf("Ungültiger Wert für %s (%q), verwende %d", name, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// envDuration liest eine Dauer wie "10s" oder "500ms" aus einer Umgebungsvariable
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		configLogger.Warnf("Ungültiger Wert für %s (%q), verwende %s", name, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envFloat liest eine Gleitkommazahl aus einer Umgebungsvariable
+func envFloat(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		configLogger.Warnf("Ungültiger Wert für %s (%q), verwende %g", name, value, fallback)
+		return fallback
+	}
+	return f
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+	pb "github.com/scimbe/distributed-task-demo-system/task-manager/taskmanagerpb"
+)
+
+// watchBufferSize ist die Anzahl der Ereignisse, die pro Stream gepuffert werden
+const watchBufferSize = 256
+
+// GRPCServer stellt den TaskService über gRPC bereit
+type GRPCServer struct {
+	pb.UnimplementedTaskManagerServer
+
+	service   *TaskService
+	tm        *TaskManager
+	wsHandler *WebSocketHandler
+}
+
+// grpcMethodRoles legt wie routeRoles für jede RPC-Methode die erforderliche Rolle fest;
+// nicht aufgeführte Methoden erfordern admin
+var grpcMethodRoles = map[string]Role{
+	"CreateTask":   RoleOperator,
+	"GetTask":      RoleViewer,
+	"CancelTask":   RoleOperator,
+	"MigrateTask":  RoleOperator,
+	"PauseTask":    RoleOperator,
+	"ResumeTask":   RoleOperator,
+	"WatchTasks":   RoleViewer,
+	"WatchWorkers": RoleViewer,
+}
+
+// NewGRPCServer erstellt einen gRPC-Server mit registriertem TaskManager-Dienst.
+// Verändernde Methoden werden wie REST-Aufrufe im Audit-Log festgehalten.
+// Mit tlsConfig (siehe serverTLSConfigFromEnv) spricht der Server TLS.
+func NewGRPCServer(service *TaskService, tm *TaskManager, auth *Authenticator, audit *AuditLog, tlsConfig *tls.Config) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(append(opts,
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			start := time.Now()
+			ctx, span := startGRPCSpan(ctx, info.FullMethod)
+			ctx, err := auth.authorizeGRPC(ctx, info.FullMethod)
+			var resp interface{}
+			if err == nil {
+				resp, err = handler(ctx, req)
+			}
+			if grpcMutating(info.FullMethod) {
+				audit.recordGRPC(ctx, info.FullMethod, req, start, err)
+			}
+			endSpan(span, err)
+			return resp, err
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, span := startGRPCSpan(stream.Context(), info.FullMethod)
+			ctx, err := auth.authorizeGRPC(ctx, info.FullMethod)
+			if err == nil {
+				err = handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+			}
+			endSpan(span, err)
+			return err
+		}),
+	)...)
+	pb.RegisterTaskManagerServer(server, &GRPCServer{
+		service:   service,
+		tm:        tm,
+		wsHandler: tm.wsHandler,
+	})
+	return server
+}
+
+// grpcMutating gibt an, ob eine Methode mehr als Leserechte erfordert und daher ins Audit-Log gehört
+func grpcMutating(fullMethod string) bool {
+	role, ok := grpcMethodRoles[path.Base(fullMethod)]
+	return !ok || role > RoleViewer
+}
+
+// recordGRPC hält einen gRPC-Aufruf im Audit-Log fest
+func (al *AuditLog) recordGRPC(ctx context.Context, fullMethod string, req interface{}, start time.Time, err error) {
+	code := status.Code(err)
+	entry := &AuditEntry{
+		Time:      start.UTC(),
+		Interface: "grpc",
+		Action:    fullMethod,
+		Namespace: namespaceFromContext(ctx),
+		Status:    grpcHTTPStatus(code),
+		Code:      code.String(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		entry.SourceIP = sourceIP(p.Addr.String())
+	}
+	if principal := principalFromContext(ctx); principal != nil {
+		entry.Actor = principal.Name
+		entry.Role = principal.Role.String()
+	}
+	if r, ok := req.(interface{ GetId() string }); ok {
+		entry.Target = r.GetId()
+	}
+	if msg, ok := req.(proto.Message); ok {
+		if params, err := protojson.Marshal(msg); err == nil {
+			entry.Params = auditParams(params)
+		}
+	}
+	entry.Outcome = auditOutcome(entry.Status)
+	entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	al.Record(entry)
+}
+
+// grpcHTTPStatus ordnet einem gRPC-Statuscode den entsprechenden HTTP-Status zu
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// contextStream ersetzt den Kontext eines Streams, damit Handler Aufrufer und Namespace sehen
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorizeGRPC prüft das Token aus den Metadaten ("authorization: Bearer <token>")
+// gegen die Rolle der Methode und den Namespace aus "x-namespace" und legt beide im Kontext ab.
+// Auch bei fehlender Berechtigung enthält der Kontext den Aufrufer, damit er im Audit-Log erscheint.
+func (a *Authenticator) authorizeGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	namespace := protocol.DefaultNamespace
+	if values := md.Get("x-namespace"); len(values) > 0 && values[0] != "" {
+		if err := protocol.ValidateNamespace(values[0]); err != nil {
+			return ctx, status.Error(codes.InvalidArgument, err.Error())
+		}
+		namespace = values[0]
+	}
+	ctx = withNamespace(ctx, namespace)
+
+	if !a.Enabled() {
+		return withPrincipal(ctx, anonymousPrincipal), nil
+	}
+	method := path.Base(fullMethod)
+	required, ok := grpcMethodRoles[method]
+	if !ok {
+		required = RoleAdmin
+	}
+
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token = parseBearer(values[0])
+	}
+	principal, err := a.Authenticate(token)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if principal.Role < required {
+		grpcLogger.Event("access_denied").Warnf("Zugriff verweigert: %s (%s) auf gRPC %s, erforderlich: %s",
+			principal.Name, principal.Role, method, required)
+		return withPrincipal(ctx, principal), status.Errorf(codes.PermissionDenied, "Rolle %s erforderlich", required)
+	}
+	if !principal.allows(namespace) {
+		return withPrincipal(ctx, principal), status.Errorf(codes.PermissionDenied, "Kein Zugriff auf Namespace %s", namespace)
+	}
+	return withPrincipal(ctx, principal), nil
+}
+
+// ServeGRPC startet den gRPC-Server auf addr und blockiert, bis er beendet wird
+func ServeGRPC(server *grpc.Server, addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		grpcLogger.Fatalf("Fehler beim Öffnen des gRPC-Ports %s: %v", addr, err)
+	}
+
+	grpcLogger.Infof("Task-Manager-gRPC-API gestartet auf %s", addr)
+	if err := server.Serve(listener); err != nil {
+		grpcLogger.Fatalf("Fehler beim Starten des gRPC-Servers: %v", err)
+	}
+}
+
+// CreateTask erstellt einen Task
+func (s *GRPCServer) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
+	task, err := s.service.CreateTask(ctx, CreateTaskRequest{
+		Type:     req.GetType(),
+		Priority: int(req.GetPriority()),
+		Data:     req.GetData().AsMap(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// GetTask liefert einen Task
+func (s *GRPCServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	task, err := s.service.GetTask(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// CancelTask bricht einen Task ab
+func (s *GRPCServer) CancelTask(ctx context.Context, req *pb.CancelTaskRequest) (*pb.Task, error) {
+	task, err := s.service.CancelTask(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// PauseTask fordert das Anhalten eines Tasks an
+func (s *GRPCServer) PauseTask(ctx context.Context, req *pb.PauseTaskRequest) (*pb.Task, error) {
+	task, err := s.service.PauseTask(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// ResumeTask setzt einen pausierten Task fort
+func (s *GRPCServer) ResumeTask(ctx context.Context, req *pb.ResumeTaskRequest) (*pb.Task, error) {
+	task, err := s.service.ResumeTask(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// MigrateTask migriert einen Task auf einen anderen Worker
+func (s *GRPCServer) MigrateTask(ctx context.Context, req *pb.MigrateTaskRequest) (*pb.Task, error) {
+	if req.GetWorkerId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "worker_id fehlt")
+	}
+	task, err := s.service.MigrateTask(ctx, req.GetId(), req.GetWorkerId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(task), nil
+}
+
+// WatchTasks streamt Task-Ereignisse des Namespace, bis der Client den Stream beendet
+func (s *GRPCServer) WatchTasks(req *pb.WatchTasksRequest, stream pb.TaskManager_WatchTasksServer) error {
+	filter := make(map[string]bool)
+	for _, id := range req.GetTaskIds() {
+		filter[id] = true
+	}
+	namespace := namespaceFromContext(stream.Context())
+	visible := eventFilter{namespace: namespace}
+
+	// Vor dem Snapshot abonnieren, damit zwischenzeitliche Änderungen nicht verloren gehen
+	events, unsubscribe := s.wsHandler.Subscribe(watchBufferSize)
+	defer unsubscribe()
+
+	if req.GetIncludeSnapshot() {
+		tasks := s.service.ListTasks(namespace)
+		snapshot := make([]*pb.TaskEvent, 0, len(tasks))
+		for _, task := range tasks {
+			if len(filter) > 0 && !filter[task.ID] {
+				continue
+			}
+			snapshot = append(snapshot, &pb.TaskEvent{Type: "task_update", TaskId: task.ID, Task: taskToProto(task)})
+		}
+
+		for _, event := range snapshot {
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.TaskID == "" || event.Type == "worker_update" || !visible.matches(event) {
+				continue
+			}
+			if len(filter) > 0 && !filter[event.TaskID] {
+				continue
+			}
+
+			msg := &pb.TaskEvent{Type: event.Type, TaskId: event.TaskID}
+			if task, ok := event.Content.(*Task); ok {
+				msg.Task = taskToProto(task)
+			} else {
+				msg.Content = toStruct(event.Content)
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchWorkers streamt Ereignisse der Worker des Namespace, bis der Client den Stream beendet
+func (s *GRPCServer) WatchWorkers(req *pb.WatchWorkersRequest, stream pb.TaskManager_WatchWorkersServer) error {
+	filter := make(map[string]bool)
+	for _, id := range req.GetWorkerIds() {
+		filter[id] = true
+	}
+	namespace := namespaceFromContext(stream.Context())
+	visible := eventFilter{namespace: namespace}
+
+	events, unsubscribe := s.wsHandler.Subscribe(watchBufferSize)
+	defer unsubscribe()
+
+	if req.GetIncludeSnapshot() {
+		workers := s.service.ListWorkers(namespace)
+		snapshot := make([]*pb.WorkerEvent, 0, len(workers))
+		for _, worker := range workers {
+			if len(filter) > 0 && !filter[worker.ID] {
+				continue
+			}
+			snapshot = append(snapshot, &pb.WorkerEvent{Type: "worker_update", Worker: workerToProto(worker)})
+		}
+
+		for _, event := range snapshot {
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			worker, isWorker := event.Content.(*Worker)
+			if !isWorker || !visible.matches(event) {
+				continue
+			}
+			if len(filter) > 0 && !filter[worker.ID] {
+				continue
+			}
+			if err := stream.Send(&pb.WorkerEvent{Type: event.Type, Worker: workerToProto(worker)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError übersetzt Fehler des Service-Layers in gRPC-Statuscodes
+func grpcError(err error) error {
+	var validationErr *ValidationFailedError
+	var quotaErr *QuotaExceededError
+	switch {
+	case errors.As(err, &validationErr):
+		msg := validationErr.Error()
+		for _, field := range validationErr.Fields {
+			msg += "; " + field.Field + ": " + field.Message
+		}
+		return status.Error(codes.InvalidArgument, msg)
+	case errors.As(err, &quotaErr):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrTaskNotFound), errors.Is(err, ErrWorkerNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrInvalidState):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		grpcLogger.Errorf("Interner Fehler: %v", err)
+		return status.Error(codes.Internal, "Interner Fehler")
+	}
+}
+
+func taskToProto(task *Task) *pb.Task {
+	return &pb.Task{
+		Id:             task.ID,
+		Type:           task.Type,
+		Status:         task.Status,
+		Priority:       int32(task.Priority),
+		Data:           toStruct(task.Data),
+		Progress:       int32(task.Progress),
+		WorkerId:       task.WorkerID,
+		CreatedAt:      timestamppb.New(time.Time(task.CreatedAt)),
+		UpdatedAt:      timestamppb.New(time.Time(task.UpdatedAt)),
+		CheckpointData: toStruct(task.CheckpointData),
+	}
+}
+
+func workerToProto(worker *Worker) *pb.Worker {
+	return &pb.Worker{
+		Id:       worker.ID,
+		Status:   string(worker.Status),
+		LastSeen: timestamppb.New(time.Time(worker.LastSeen)),
+	}
+}
+
+// toStruct wandelt beliebige JSON-artige Werte in ein protobuf Struct um
+func toStruct(value interface{}) *structpb.Struct {
+	if value == nil {
+		return nil
+	}
+
+	var m map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+		m = v
+	default:
+		// Umweg über JSON für Strukturen und typisierte Maps
+		converted, err := jsonRoundTrip(value)
+		if err != nil {
+			grpcLogger.Errorf("Fehler beim Umwandeln in protobuf Struct: %v", err)
+			return nil
+		}
+		m = converted
+	}
+
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		// Maps mit Go-Typen wie []int lassen sich erst nach einem JSON-Umweg umwandeln
+		converted, jsonErr := jsonRoundTrip(m)
+		if jsonErr == nil {
+			s, err = structpb.NewStruct(converted)
+		}
+	}
+	if err != nil {
+		grpcLogger.Errorf("Fehler beim Umwandeln in protobuf Struct: %v", err)
+		return nil
+	}
+	return s
+}
+
+func jsonRoundTrip(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
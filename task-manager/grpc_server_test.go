@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/scimbe/distributed-task-demo-system/task-manager/taskmanagerpb"
+)
+
+// startTestGRPCServer startet den gRPC-Server über eine In-Memory-Verbindung. Der Schlüssel
+// "geheim" gilt nur für den Namespace default; task-1 liegt in default, task-2 in team-b.
+func startTestGRPCServer(t *testing.T) (pb.TaskManagerClient, *TaskManager) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	tm := &TaskManager{
+		redisClient: client,
+		tasks: map[string]*Task{
+			"task-1": {ID: "task-1", Type: "demo", Status: "RUNNING", Progress: 10},
+			"task-2": {ID: "task-2", Type: "demo", Status: "RUNNING", Progress: 20},
+		},
+		workerStatus: map[string]*Worker{},
+		wsHandler:    newTestWSHandler(16, 16),
+	}
+	namespaces := NewNamespaceRegistry(client)
+	if err := namespaces.SetTask(context.Background(), "task-2", "team-b"); err != nil {
+		t.Fatal(err)
+	}
+	tm.wsHandler.SetNamespaces(namespaces)
+	ts := NewTaskService(tm, nil, namespaces, nil, &Encryption{}, nil, nil)
+	auth := &Authenticator{verifiers: []Verifier{parseAPIKeys("ops:geheim:operator:default")}}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(ts, tm, auth, &AuditLog{redisClient: client}, nil)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewTaskManagerClient(conn), tm
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPCGetTask(t *testing.T) {
+	client, _ := startTestGRPCServer(t)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       string
+		wantCode codes.Code
+	}{
+		{"ohne Token", context.Background(), "task-1", codes.Unauthenticated},
+		{"falsches Token", withToken(context.Background(), "falsch"), "task-1", codes.Unauthenticated},
+		{"eigener Namespace", withToken(context.Background(), "geheim"), "task-1", codes.OK},
+		{"anderer Namespace", withToken(context.Background(), "geheim"), "task-2", codes.NotFound},
+		{"unbekannt", withToken(context.Background(), "geheim"), "task-9", codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := client.GetTask(tt.ctx, &pb.GetTaskRequest{Id: tt.id})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("Status %s (%v), erwartet %s", got, err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && task.GetId() != tt.id {
+				t.Fatalf("Task %q, erwartet %q", task.GetId(), tt.id)
+			}
+		})
+	}
+}
+
+func TestGRPCWatchTasks(t *testing.T) {
+	client, tm := startTestGRPCServer(t)
+	ctx, cancel := context.WithTimeout(withToken(context.Background(), "geheim"), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchTasks(ctx, &pb.WatchTasksRequest{IncludeSnapshot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Zuerst der Snapshot, ohne Tasks anderer Namespaces
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetTaskId() != "task-1" || event.GetTask().GetStatus() != "RUNNING" {
+		t.Fatalf("Snapshot %v, erwartet task-1 RUNNING", event)
+	}
+
+	// Danach Live-Updates; das Update aus team-b erreicht den Stream nicht
+	tm.wsHandler.BroadcastTaskUpdate(&Task{ID: "task-2", Type: "demo", Status: "COMPLETED", Progress: 100})
+	tm.wsHandler.BroadcastTaskUpdate(&Task{ID: "task-1", Type: "demo", Status: "COMPLETED", Progress: 100})
+	event, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetType() != "task_update" || event.GetTaskId() != "task-1" || event.GetTask().GetStatus() != "COMPLETED" {
+		t.Fatalf("Live-Update %v, erwartet task_update für task-1 COMPLETED", event)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// jwtLeeway gleicht Uhrabweichungen zwischen Aussteller und Task-Manager aus
+const jwtLeeway = 30 * time.Second
+
+// jwtVerifier prüft JWTs lokal mit einem HS256-Schlüssel oder einem RS256-Public-Key.
+// Der Name des Aufrufers ist der Claim "sub", die Rolle steht im Claim roleClaim
+// (ein Rollenname oder eine Liste, dann gilt die höchste Rolle). Der Claim
+// namespaceClaim beschränkt den Zugriff auf einzelne Namespaces.
+type jwtVerifier struct {
+	alg            string
+	secret         []byte
+	publicKey      *rsa.PublicKey
+	issuer         string
+	audience       string
+	roleClaim      string
+	namespaceClaim string
+}
+
+// jwtVerifiersFromEnv liest die JWT-Konfiguration aus JWT_HS256_SECRET,
+// JWT_RS256_PUBLIC_KEY_FILE, JWT_ISSUER, JWT_AUDIENCE, JWT_ROLE_CLAIM und JWT_NAMESPACE_CLAIM
+func jwtVerifiersFromEnv() []Verifier {
+	base := jwtVerifier{
+		issuer:         os.Getenv("JWT_ISSUER"),
+		audience:       os.Getenv("JWT_AUDIENCE"),
+		roleClaim:      envString("JWT_ROLE_CLAIM", "role"),
+		namespaceClaim: envString("JWT_NAMESPACE_CLAIM", "namespaces"),
+	}
+
+	var verifiers []Verifier
+	if secret := os.Getenv("JWT_HS256_SECRET"); secret != "" {
+		if len(secret) < 32 {
+			authLogger.Fatalf("JWT_HS256_SECRET muss mindestens 32 Zeichen lang sein")
+		}
+		v := base
+		v.alg = "HS256"
+		v.secret = []byte(secret)
+		verifiers = append(verifiers, &v)
+		authLogger.Infof("JWT-Prüfung mit HS256 aktiviert")
+	}
+	if file := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); file != "" {
+		publicKey, err := loadRSAPublicKey(file)
+		if err != nil {
+			authLogger.Fatalf("Fehler beim Laden von JWT_RS256_PUBLIC_KEY_FILE: %v", err)
+		}
+		v := base
+		v.alg = "RS256"
+		v.publicKey = publicKey
+		verifiers = append(verifiers, &v)
+		authLogger.Infof("JWT-Prüfung mit RS256 aktiviert (%s)", file)
+	}
+	return verifiers
+}
+
+// loadRSAPublicKey liest einen RSA-Public-Key im PEM-Format (PKIX oder PKCS#1)
+func loadRSAPublicKey(file string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("keine PEM-Daten gefunden")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("kein RSA-Schlüssel")
+	}
+	return rsaKey, nil
+}
+
+// Verify prüft Signatur, Gültigkeitszeitraum, Aussteller, Zielgruppe und Rolle
+func (v *jwtVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrUnauthenticated
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != v.alg {
+		// Kein JWT oder eines für einen anderen Verifier; "none" wird so nie akzeptiert
+		return nil, ErrUnauthenticated
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signatur ungültig", ErrUnauthenticated)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	switch v.alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, fmt.Errorf("%w: Signatur ungültig", ErrUnauthenticated)
+		}
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("%w: Signatur ungültig", ErrUnauthenticated)
+		}
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: Claims ungültig", ErrUnauthenticated)
+	}
+	return v.principalFromClaims(claims, time.Now())
+}
+
+// principalFromClaims prüft die Claims eines korrekt signierten Tokens
+func (v *jwtVerifier) principalFromClaims(claims map[string]interface{}, now time.Time) (*Principal, error) {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: Token ohne Ablaufzeit (exp)", ErrUnauthenticated)
+	}
+	if now.Add(-jwtLeeway).After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("%w: Token abgelaufen", ErrUnauthenticated)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: Token noch nicht gültig", ErrUnauthenticated)
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, fmt.Errorf("%w: falscher Aussteller", ErrUnauthenticated)
+	}
+	if v.audience != "" && !claimContains(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("%w: falsche Zielgruppe", ErrUnauthenticated)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: Token ohne Subjekt (sub)", ErrUnauthenticated)
+	}
+	role := RolePublic
+	for _, name := range claimStrings(claims[v.roleClaim]) {
+		if r, ok := parseRole(name); ok && r > role {
+			role = r
+		}
+	}
+	if role == RolePublic {
+		return nil, fmt.Errorf("%w: Token ohne gültige Rolle (%s)", ErrUnauthenticated, v.roleClaim)
+	}
+	namespaces := claimStrings(claims[v.namespaceClaim])
+	for _, ns := range namespaces {
+		if err := protocol.ValidateNamespace(ns); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+	}
+	return &Principal{Name: subject, Role: role, Namespaces: namespaces}, nil
+}
+
+// decodeJWTPart dekodiert einen Base64URL-kodierten JSON-Teil eines JWT
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimStrings liefert einen Claim, der ein String oder eine Liste von Strings ist
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func claimContains(value interface{}, want string) bool {
+	for _, s := range claimStrings(value) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func encodeJWTPart(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signHS256 erzeugt ein Token mit beliebigem Header, signiert mit HMAC-SHA256
+func signHS256(t *testing.T, header, claims map[string]interface{}, secret []byte) string {
+	t.Helper()
+	signed := encodeJWTPart(t, header) + "." + encodeJWTPart(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signRS256 erzeugt ein mit RSA-SHA256 signiertes Token
+func signRS256(t *testing.T, claims map[string]interface{}, key *rsa.PrivateKey) string {
+	t.Helper()
+	signed := encodeJWTPart(t, map[string]interface{}{"alg": "RS256", "typ": "JWT"}) + "." + encodeJWTPart(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":  "alice",
+		"role": "operator",
+		"exp":  float64(time.Now().Add(time.Hour).Unix()),
+	}
+}
+
+func TestJWTVerifyAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &rsaKey.PublicKey)})
+
+	hs := &jwtVerifier{alg: "HS256", secret: []byte(testJWTSecret), roleClaim: "role"}
+	rs := &jwtVerifier{alg: "RS256", publicKey: &rsaKey.PublicKey, roleClaim: "role"}
+	hsHeader := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+
+	unsigned := encodeJWTPart(t, map[string]interface{}{"alg": "none"}) + "." + encodeJWTPart(t, validClaims()) + "."
+	tampered := signHS256(t, hsHeader, validClaims(), []byte(testJWTSecret))
+	parts := strings.Split(tampered, ".")
+	adminClaims := validClaims()
+	adminClaims["role"] = "admin"
+	tampered = parts[0] + "." + encodeJWTPart(t, adminClaims) + "." + parts[2]
+
+	tests := []struct {
+		name     string
+		verifier *jwtVerifier
+		token    string
+		wantErr  bool
+	}{
+		{name: "HS256 gültig", verifier: hs, token: signHS256(t, hsHeader, validClaims(), []byte(testJWTSecret))},
+		{name: "RS256 gültig", verifier: rs, token: signRS256(t, validClaims(), rsaKey)},
+		{name: "alg none", verifier: hs, token: unsigned, wantErr: true},
+		{name: "falscher Schlüssel", verifier: hs, token: signHS256(t, hsHeader, validClaims(), []byte(strings.Repeat("x", 32))), wantErr: true},
+		{name: "veränderte Claims", verifier: hs, token: tampered, wantErr: true},
+		{name: "RS256-Token an HS256-Verifier", verifier: hs, token: signRS256(t, validClaims(), rsaKey), wantErr: true},
+		// Klassischer Angriff: HS256 mit dem öffentlichen RSA-Schlüssel als HMAC-Geheimnis
+		{name: "HS256 mit Public Key an RS256-Verifier", verifier: rs, token: signHS256(t, hsHeader, validClaims(), publicKeyPEM), wantErr: true},
+		{name: "alg in Kleinbuchstaben", verifier: hs, token: signHS256(t, map[string]interface{}{"alg": "hs256"}, validClaims(), []byte(testJWTSecret)), wantErr: true},
+		{name: "zwei Teile", verifier: hs, token: "a.b", wantErr: true},
+		{name: "Header kein Base64", verifier: hs, token: "!!!.e30.sig", wantErr: true},
+		{name: "Signatur kein Base64", verifier: hs, token: strings.Join(parts[:2], ".") + ".!!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := tt.verifier.Verify(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Token akzeptiert: %+v", principal)
+				}
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Fatalf("Fehler %v ist kein ErrUnauthenticated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if principal.Name != "alice" || principal.Role != RoleOperator {
+				t.Fatalf("Principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	unix := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+
+	tests := []struct {
+		name     string
+		verifier jwtVerifier
+		claims   map[string]interface{}
+		want     *Principal
+		wantErr  string
+	}{
+		{
+			name:   "gültig",
+			claims: map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Minute)},
+			want:   &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:    "ohne exp",
+			claims:  map[string]interface{}{"sub": "alice", "role": "viewer"},
+			wantErr: "Ablaufzeit",
+		},
+		{
+			name:    "exp als String",
+			claims:  map[string]interface{}{"sub": "alice", "role": "viewer", "exp": "1700000060"},
+			wantErr: "Ablaufzeit",
+		},
+		{
+			name:   "abgelaufen innerhalb der Toleranz",
+			claims: map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(-jwtLeeway + time.Second)},
+			want:   &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:    "abgelaufen",
+			claims:  map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(-jwtLeeway - time.Second)},
+			wantErr: "abgelaufen",
+		},
+		{
+			name:   "nbf innerhalb der Toleranz",
+			claims: map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "nbf": unix(jwtLeeway - time.Second)},
+			want:   &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:    "noch nicht gültig",
+			claims:  map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "nbf": unix(jwtLeeway + time.Second)},
+			wantErr: "noch nicht gültig",
+		},
+		{
+			name:     "Aussteller passt",
+			verifier: jwtVerifier{issuer: "https://idp"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "iss": "https://idp"},
+			want:     &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:     "falscher Aussteller",
+			verifier: jwtVerifier{issuer: "https://idp"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "iss": "https://other"},
+			wantErr:  "Aussteller",
+		},
+		{
+			name:     "Aussteller fehlt",
+			verifier: jwtVerifier{issuer: "https://idp"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour)},
+			wantErr:  "Aussteller",
+		},
+		{
+			name:     "Zielgruppe als String",
+			verifier: jwtVerifier{audience: "task-manager"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "aud": "task-manager"},
+			want:     &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:     "Zielgruppe in Liste",
+			verifier: jwtVerifier{audience: "task-manager"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "aud": []interface{}{"other", "task-manager"}},
+			want:     &Principal{Name: "alice", Role: RoleViewer},
+		},
+		{
+			name:     "falsche Zielgruppe",
+			verifier: jwtVerifier{audience: "task-manager"},
+			claims:   map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "aud": []interface{}{"other"}},
+			wantErr:  "Zielgruppe",
+		},
+		{
+			name:    "ohne Subjekt",
+			claims:  map[string]interface{}{"role": "viewer", "exp": unix(time.Hour)},
+			wantErr: "Subjekt",
+		},
+		{
+			name:    "unbekannte Rolle",
+			claims:  map[string]interface{}{"sub": "alice", "role": "root", "exp": unix(time.Hour)},
+			wantErr: "Rolle",
+		},
+		{
+			name:    "Rolle public",
+			claims:  map[string]interface{}{"sub": "alice", "role": "public", "exp": unix(time.Hour)},
+			wantErr: "Rolle",
+		},
+		{
+			name:   "höchste Rolle aus Liste",
+			claims: map[string]interface{}{"sub": "alice", "role": []interface{}{"viewer", "ADMIN", "unknown"}, "exp": unix(time.Hour)},
+			want:   &Principal{Name: "alice", Role: RoleAdmin},
+		},
+		{
+			name:     "eigener Rollen-Claim",
+			verifier: jwtVerifier{roleClaim: "groups"},
+			claims:   map[string]interface{}{"sub": "alice", "groups": "operator", "exp": unix(time.Hour)},
+			want:     &Principal{Name: "alice", Role: RoleOperator},
+		},
+		{
+			name:   "Namespaces",
+			claims: map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "namespaces": []interface{}{"team-a", "team-b"}},
+			want:   &Principal{Name: "alice", Role: RoleViewer, Namespaces: []string{"team-a", "team-b"}},
+		},
+		{
+			name:    "ungültiger Namespace",
+			claims:  map[string]interface{}{"sub": "alice", "role": "viewer", "exp": unix(time.Hour), "namespaces": "Team A"},
+			wantErr: "Namespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.verifier
+			if v.roleClaim == "" {
+				v.roleClaim = "role"
+			}
+			if v.namespaceClaim == "" {
+				v.namespaceClaim = "namespaces"
+			}
+
+			principal, err := v.principalFromClaims(tt.claims, now)
+			if tt.wantErr != "" {
+				if err == nil || !errors.Is(err, ErrUnauthenticated) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if !reflect.DeepEqual(principal, tt.want) {
+				t.Fatalf("Principal = %+v, erwartet %+v", principal, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRSAPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		wantErr bool
+	}{
+		{name: "PKIX", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &key.PublicKey)})},
+		{name: "PKCS#1", pem: pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)})},
+		{name: "kein PEM", pem: []byte("kein Schlüssel"), wantErr: true},
+		{name: "kein Public Key", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("x")}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ReplaceAll(tt.name, "#", "")+".pem")
+			if err := ioutil.WriteFile(file, tt.pem, 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadRSAPublicKey(file)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Fehler erwartet")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if !got.Equal(&key.PublicKey) {
+				t.Fatal("falscher Schlüssel geladen")
+			}
+		})
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
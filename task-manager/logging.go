@@ -0,0 +1,33 @@
+package main
+
+import "github.com/scimbe/distributed-task-demo-system/protocol"
+
+// Die Logger der Komponenten; ihre Namen sind die Schlüssel in LOG_LEVELS und
+// in /api/system/logging. mainLogger nimmt auch alle Ausgaben über das Paket log auf.
+var (
+	mainLogger       = protocol.NewLogger("main")
+	auditLogger      = protocol.NewLogger("audit")
+	authLogger       = protocol.NewLogger("auth")
+	brokerLogger     = protocol.NewLogger("broker")
+	configLogger     = protocol.NewLogger("config")
+	encryptionLogger = protocol.NewLogger("encryption")
+	grpcLogger       = protocol.NewLogger("grpc")
+	metricsLogger    = protocol.NewLogger("metrics")
+	namespaceLogger  = protocol.NewLogger("namespace")
+	quotaLogger      = protocol.NewLogger("quota")
+	schemaLogger     = protocol.NewLogger("schema")
+	serviceLogger    = protocol.NewLogger("service")
+	sseLogger        = protocol.NewLogger("sse")
+	statusLogger     = protocol.NewLogger("status")
+	tracingLogger    = protocol.NewLogger("tracing")
+	websocketLogger  = protocol.NewLogger("websocket")
+	workerLogger     = protocol.NewLogger("worker")
+)
+
+// handleLogLevels ist der HTTP-Handler für GET und PUT /api/system/logging
+var handleLogLevels = protocol.LogLevelsHandler(mainLogger)
+
+// init stellt die Ausgabe schon vor dem Start von main auf JSON um
+func init() {
+	protocol.InitLogging(mainLogger)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// metricsEventBuffer ist der Puffer des Ereignis-Abonnements; Status-Wechsel
+	// sollen auch bei vielen gleichzeitigen Updates nicht verloren gehen
+	metricsEventBuffer = 4096
+	// metricsBrokerTimeout begrenzt die Abfrage der Warteschlangen pro Scrape
+	metricsBrokerTimeout = 3 * time.Second
+)
+
+// taskDurationBuckets decken Tasks von Sekunden bis zu einer Stunde ab
+var taskDurationBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600, 1800, 3600}
+
+var (
+	tasksDesc = prometheus.NewDesc("taskmanager_tasks",
+		"Bekannte Tasks nach Namespace, Typ und Status", []string{"namespace", "type", "status"}, nil)
+	workersDesc = prometheus.NewDesc("taskmanager_workers",
+		"Bekannte Worker nach Status", []string{"status"}, nil)
+	queueMessagesDesc = prometheus.NewDesc("taskmanager_queue_messages",
+		"Nachrichten in einer Warteschlange (wartend und unbestätigt)", []string{"vhost", "queue"}, nil)
+	queueReadyDesc = prometheus.NewDesc("taskmanager_queue_messages_ready",
+		"Wartende, noch nicht zugestellte Nachrichten in einer Warteschlange", []string{"vhost", "queue"}, nil)
+	queueConsumersDesc = prometheus.NewDesc("taskmanager_queue_consumers",
+		"Consumer einer Warteschlange", []string{"vhost", "queue"}, nil)
+	wsClientsDesc = prometheus.NewDesc("taskmanager_websocket_clients",
+		"Verbundene WebSocket-Clients", nil, nil)
+	wsMessagesDroppedDesc = prometheus.NewDesc("taskmanager_websocket_messages_dropped_total",
+		"Wegen voller Warteschlangen verworfene oder ersetzte Broadcast-Nachrichten", nil, nil)
+	wsConnectionsDroppedDesc = prometheus.NewDesc("taskmanager_websocket_connections_dropped_total",
+		"Wegen voller Warteschlange oder Schreibfehlern getrennte WebSocket-Verbindungen", nil, nil)
+)
+
+// Metrics stellt Kennzahlen des Task-Managers im Prometheus-Format unter /metrics bereit.
+// Bestände (Tasks, Worker, Warteschlangen, WebSocket-Clients) werden bei jedem Scrape
+// gelesen; Raten und Latenzen entstehen aus den verteilten Task-Updates.
+type Metrics struct {
+	registry   *prometheus.Registry
+	tm         *TaskManager
+	namespaces *NamespaceRegistry
+	broker     *BrokerInspector
+
+	tasksCreated       *prometheus.CounterVec
+	tasksFinished      *prometheus.CounterVec
+	migrations         *prometheus.CounterVec
+	recoveries         *prometheus.CounterVec
+	taskDuration       *prometheus.HistogramVec
+	taskQueueWait      *prometheus.HistogramVec
+	brokerScrapeErrors prometheus.Counter
+
+	// lastStatus ist der zuletzt gesehene Status je Task, um Wechsel zu erkennen
+	lastStatus map[string]string
+	mutex      sync.Mutex
+}
+
+// NewMetrics erstellt die Metriken und registriert sie
+func NewMetrics(tm *TaskManager, namespaces *NamespaceRegistry, broker *BrokerInspector) *Metrics {
+	m := &Metrics{
+		registry:   prometheus.NewRegistry(),
+		tm:         tm,
+		namespaces: namespaces,
+		broker:     broker,
+		lastStatus: make(map[string]string),
+
+		tasksCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "taskmanager_tasks_created_total",
+			Help: "Erstellte Tasks",
+		}, []string{"namespace", "type"}),
+		tasksFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "taskmanager_tasks_finished_total",
+			Help: "Beendete Tasks nach Endstatus (COMPLETED, FAILED, CANCELLED)",
+		}, []string{"namespace", "type", "status"}),
+		migrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "taskmanager_task_migrations_total",
+			Help: "Eingeleitete Task-Migrationen",
+		}, []string{"namespace"}),
+		recoveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "taskmanager_task_recoveries_total",
+			Help: "Wiederherstellungen von Tasks nach Worker-Ausfällen (Wechsel nach RECOVERING)",
+		}, []string{"namespace"}),
+		taskDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "taskmanager_task_duration_seconds",
+			Help:    "Zeit von der Erstellung bis zum Endstatus eines Tasks",
+			Buckets: taskDurationBuckets,
+		}, []string{"type", "status"}),
+		taskQueueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "taskmanager_task_queue_wait_seconds",
+			Help:    "Zeit von der Erstellung bis zum Start auf einem Worker",
+			Buckets: taskDurationBuckets,
+		}, []string{"type"}),
+		brokerScrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "taskmanager_broker_scrape_errors_total",
+			Help: "Fehlgeschlagene Abfragen der Warteschlangen über die RabbitMQ-Management-API",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.tasksCreated, m.tasksFinished, m.migrations, m.recoveries,
+		m.taskDuration, m.taskQueueWait, m.brokerScrapeErrors,
+		m,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Start wertet die verteilten Task-Updates aus
+func (m *Metrics) Start() {
+	events, _ := m.tm.wsHandler.Subscribe(metricsEventBuffer)
+	go func() {
+		for event := range events {
+			if task, ok := event.Content.(*Task); ok && event.Type == "task_update" {
+				m.observeTask(task)
+			}
+		}
+	}()
+}
+
+// TaskCreated zählt einen neu erstellten Task
+func (m *Metrics) TaskCreated(task *Task) {
+	m.mutex.Lock()
+	m.lastStatus[task.ID] = task.Status
+	m.mutex.Unlock()
+	m.tasksCreated.WithLabelValues(m.namespaces.TaskNamespace(task.ID), task.Type).Inc()
+}
+
+// TaskMigrated zählt eine eingeleitete Migration
+func (m *Metrics) TaskMigrated(taskID string) {
+	m.migrations.WithLabelValues(m.namespaces.TaskNamespace(taskID)).Inc()
+}
+
+// observeTask zählt Status-Wechsel eines Tasks und misst dabei Wartezeit und Gesamtdauer
+func (m *Metrics) observeTask(task *Task) {
+	m.mutex.Lock()
+	previous, known := m.lastStatus[task.ID]
+	m.lastStatus[task.ID] = task.Status
+	m.mutex.Unlock()
+	if previous == task.Status {
+		return
+	}
+
+	namespace := m.namespaces.TaskNamespace(task.ID)
+	elapsed := time.Time(task.UpdatedAt).Sub(time.Time(task.CreatedAt)).Seconds()
+	switch {
+	case task.Status == "RUNNING" && known && previous == "CREATED":
+		// Nur für Tasks, deren Erstellung dieser Prozess gesehen hat
+		m.taskQueueWait.WithLabelValues(task.Type).Observe(elapsed)
+	case task.Status == "RECOVERING":
+		m.recoveries.WithLabelValues(namespace).Inc()
+	case terminalTaskStates[task.Status]:
+		m.tasksFinished.WithLabelValues(namespace, task.Type, task.Status).Inc()
+		m.taskDuration.WithLabelValues(task.Type, task.Status).Observe(elapsed)
+	}
+}
+
+// Describe implementiert prometheus.Collector für die beim Scrape gelesenen Bestände
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		tasksDesc, workersDesc, queueMessagesDesc, queueReadyDesc, queueConsumersDesc,
+		wsClientsDesc, wsMessagesDroppedDesc, wsConnectionsDroppedDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implementiert prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	type taskKey struct{ namespace, taskType, status string }
+	tasks := make(map[taskKey]int)
+	m.tm.taskMutex.RLock()
+	for id, task := range m.tm.tasks {
+		tasks[taskKey{m.namespaces.TaskNamespace(id), task.Type, task.Status}]++
+	}
+	m.tm.taskMutex.RUnlock()
+	for key, count := range tasks {
+		ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(count), key.namespace, key.taskType, key.status)
+	}
+
+	workers := make(map[string]int)
+	m.tm.workerMutex.RLock()
+	for _, worker := range m.tm.workerStatus {
+		workers[worker.Status]++
+	}
+	m.tm.workerMutex.RUnlock()
+	for status, count := range workers {
+		ch <- prometheus.MustNewConstMetric(workersDesc, prometheus.GaugeValue, float64(count), status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsBrokerTimeout)
+	defer cancel()
+	queues, err := m.broker.Queues(ctx)
+	if err != nil {
+		metricsLogger.Warnf("Metriken: %v", err)
+		m.brokerScrapeErrors.Inc()
+	}
+	for _, queue := range queues {
+		ch <- prometheus.MustNewConstMetric(queueMessagesDesc, prometheus.GaugeValue, float64(queue.Messages), queue.VHost, queue.Name)
+		ch <- prometheus.MustNewConstMetric(queueReadyDesc, prometheus.GaugeValue, float64(queue.MessagesReady), queue.VHost, queue.Name)
+		ch <- prometheus.MustNewConstMetric(queueConsumersDesc, prometheus.GaugeValue, float64(queue.Consumers), queue.VHost, queue.Name)
+	}
+
+	wsh := m.tm.wsHandler
+	stats := wsh.stats.snapshot()
+	ch <- prometheus.MustNewConstMetric(wsClientsDesc, prometheus.GaugeValue, float64(wsh.activeConnections()))
+	ch <- prometheus.MustNewConstMetric(wsMessagesDroppedDesc, prometheus.CounterValue, float64(stats.MessagesDropped))
+	ch <- prometheus.MustNewConstMetric(wsConnectionsDroppedDesc, prometheus.CounterValue, float64(stats.ConnectionsDropped))
+}
+
+// Handler liefert den HTTP-Handler für GET /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
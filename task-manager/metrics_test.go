@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newTestMetrics erstellt Metriken mit leerem Task-Manager und dem Broker unter brokerURL
+func newTestMetrics(t *testing.T, brokerURL string) *Metrics {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	namespaces := NewNamespaceRegistry(client)
+	if err := namespaces.SetTask(context.Background(), "t-a", "team-a"); err != nil {
+		t.Fatal(err)
+	}
+	tm := &TaskManager{
+		redisClient:  client,
+		tasks:        make(map[string]*Task),
+		workerStatus: make(map[string]*Worker),
+		wsHandler:    newTestWSHandler(16, 16),
+	}
+	broker := &BrokerInspector{baseURL: brokerURL, username: "guest", password: "guest", client: &http.Client{Timeout: time.Second}}
+	return NewMetrics(tm, namespaces, broker)
+}
+
+// metricValue liefert den Wert der Metrik name mit genau den angegebenen Labels
+// (Zähler und Gauges: Wert, Histogramme: Anzahl der Beobachtungen)
+func metricValue(t *testing.T, m *Metrics, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !labelsMatch(metric.GetLabel(), labels) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.Counter.GetValue(), true
+			case metric.Gauge != nil:
+				return metric.Gauge.GetValue(), true
+			case metric.Histogram != nil:
+				return float64(metric.Histogram.GetSampleCount()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func labelsMatch(pairs []*dto.LabelPair, labels map[string]string) bool {
+	if len(pairs) != len(labels) {
+		return false
+	}
+	for _, pair := range pairs {
+		if labels[pair.GetName()] != pair.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMetricsObserveTask(t *testing.T) {
+	created := TimeJSON(time.Now().Add(-time.Minute))
+	task := func(id, status string) *Task {
+		return &Task{ID: id, Type: "sleep", Status: status, CreatedAt: created, UpdatedAt: TimeJSON(time.Now())}
+	}
+
+	tests := []struct {
+		name string
+		// created sind die über TaskCreated gemeldeten Tasks
+		created []*Task
+		updates []*Task
+		metric  string
+		labels  map[string]string
+		want    float64
+	}{
+		{
+			name:    "erstellte Tasks je Namespace",
+			created: []*Task{task("t-a", "CREATED"), task("t-b", "CREATED")},
+			metric:  "taskmanager_tasks_created_total",
+			labels:  map[string]string{"namespace": "team-a", "type": "sleep"},
+			want:    1,
+		},
+		{
+			name:    "Wartezeit nur nach gesehener Erstellung",
+			created: []*Task{task("t-a", "CREATED")},
+			updates: []*Task{task("t-a", "RUNNING"), task("t-b", "RUNNING")},
+			metric:  "taskmanager_task_queue_wait_seconds",
+			labels:  map[string]string{"type": "sleep"},
+			want:    1,
+		},
+		{
+			name:    "Endstatus wird einmal gezählt",
+			updates: []*Task{task("t-b", "RUNNING"), task("t-b", "COMPLETED"), task("t-b", "COMPLETED")},
+			metric:  "taskmanager_tasks_finished_total",
+			labels:  map[string]string{"namespace": "default", "type": "sleep", "status": "COMPLETED"},
+			want:    1,
+		},
+		{
+			name:    "Dauer bis zum Endstatus",
+			updates: []*Task{task("t-a", "RUNNING"), task("t-a", "FAILED")},
+			metric:  "taskmanager_task_duration_seconds",
+			labels:  map[string]string{"type": "sleep", "status": "FAILED"},
+			want:    1,
+		},
+		{
+			name:    "Wiederherstellungen",
+			updates: []*Task{task("t-a", "RUNNING"), task("t-a", "RECOVERING"), task("t-a", "RUNNING"), task("t-a", "RECOVERING")},
+			metric:  "taskmanager_task_recoveries_total",
+			labels:  map[string]string{"namespace": "team-a"},
+			want:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMetrics(t, "http://127.0.0.1:0")
+			for _, task := range tt.created {
+				m.TaskCreated(task)
+			}
+			for _, task := range tt.updates {
+				m.observeTask(task)
+			}
+
+			got, ok := metricValue(t, m, tt.metric, tt.labels)
+			if !ok {
+				t.Fatalf("%s%v nicht gefunden", tt.metric, tt.labels)
+			}
+			if got != tt.want {
+				t.Errorf("%s%v = %v, erwartet %v", tt.metric, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsCollect(t *testing.T) {
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "guest" || password != "guest" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"name":"task_queue","vhost":"/","messages":5,"messages_ready":3,"messages_unacknowledged":2,"consumers":2}]`))
+	}))
+	defer broker.Close()
+
+	tests := []struct {
+		name   string
+		metric string
+		labels map[string]string
+		want   float64
+	}{
+		{name: "Tasks je Status", metric: "taskmanager_tasks", labels: map[string]string{"namespace": "team-a", "type": "sleep", "status": "RUNNING"}, want: 1},
+		{name: "Tasks im Standard-Namespace", metric: "taskmanager_tasks", labels: map[string]string{"namespace": "default", "type": "sleep", "status": "RUNNING"}, want: 2},
+		{name: "Worker je Status", metric: "taskmanager_workers", labels: map[string]string{"status": "ACTIVE"}, want: 2},
+		{name: "wartende Nachrichten", metric: "taskmanager_queue_messages_ready", labels: map[string]string{"vhost": "/", "queue": "task_queue"}, want: 3},
+		{name: "Consumer", metric: "taskmanager_queue_consumers", labels: map[string]string{"vhost": "/", "queue": "task_queue"}, want: 2},
+		{name: "WebSocket-Clients", metric: "taskmanager_websocket_clients", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMetrics(t, broker.URL)
+			m.tm.tasks["t-a"] = &Task{ID: "t-a", Type: "sleep", Status: "RUNNING"}
+			m.tm.tasks["t-b"] = &Task{ID: "t-b", Type: "sleep", Status: "RUNNING"}
+			m.tm.tasks["t-c"] = &Task{ID: "t-c", Type: "sleep", Status: "RUNNING"}
+			m.tm.workerStatus["w1"] = &Worker{ID: "w1", Status: "ACTIVE"}
+			m.tm.workerStatus["w2"] = &Worker{ID: "w2", Status: "ACTIVE"}
+			m.tm.workerStatus["w3"] = &Worker{ID: "w3", Status: "FAILED"}
+
+			got, ok := metricValue(t, m, tt.metric, tt.labels)
+			if !ok {
+				t.Fatalf("%s%v nicht gefunden", tt.metric, tt.labels)
+			}
+			if got != tt.want {
+				t.Errorf("%s%v = %v, erwartet %v", tt.metric, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsBrokerScrapeErrors(t *testing.T) {
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer broker.Close()
+
+	tests := []struct {
+		name      string
+		brokerURL string
+		want      float64
+	}{
+		{name: "Broker erreichbar", brokerURL: broker.URL, want: 0},
+		{name: "Broker nicht erreichbar", brokerURL: "http://127.0.0.1:1", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMetrics(t, tt.brokerURL)
+			if _, err := m.registry.Gather(); err != nil {
+				t.Fatalf("Gather: %v", err)
+			}
+
+			// Der Zähler wird während des Scrapes erhöht und erst beim nächsten sicher ausgeliefert
+			var metric dto.Metric
+			if err := m.brokerScrapeErrors.Write(&metric); err != nil {
+				t.Fatal(err)
+			}
+			if got := metric.GetCounter().GetValue(); got != tt.want {
+				t.Errorf("taskmanager_broker_scrape_errors_total = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
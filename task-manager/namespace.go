@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+const (
+	// taskNamespacesKey ist der Redis-Hash Task-ID → Namespace; Tasks ohne Eintrag
+	// gehören zum Default-Namespace
+	taskNamespacesKey = "task_namespaces"
+	// workerNamespacesPrefix ist der Präfix der Schlüssel, unter denen Worker ihre Namespaces hinterlegen
+	workerNamespacesPrefix = "worker_namespaces:"
+	// workerNamespacesRefresh ist der Abstand, in dem die Namespaces der Worker neu gelesen werden
+	workerNamespacesRefresh = 10 * time.Second
+)
+
+// NamespaceRegistry kennt den Namespace jedes Tasks und die Namespaces jedes Workers.
+// Abfragen werden aus dem Speicher beantwortet, damit sie auch beim Verteilen von
+// Ereignissen ohne Redis-Zugriff möglich sind.
+type NamespaceRegistry struct {
+	redisClient *redis.Client
+	mutex       sync.RWMutex
+	tasks       map[string]string
+	workers     map[string][]string
+}
+
+// NewNamespaceRegistry erstellt eine NamespaceRegistry und lädt die bekannten Zuordnungen aus Redis
+func NewNamespaceRegistry(redisClient *redis.Client) *NamespaceRegistry {
+	nr := &NamespaceRegistry{
+		redisClient: redisClient,
+		tasks:       make(map[string]string),
+		workers:     make(map[string][]string),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tasks, err := redisClient.HGetAll(ctx, taskNamespacesKey).Result()
+	if err != nil {
+		namespaceLogger.Errorf("Fehler beim Laden der Task-Namespaces: %v", err)
+	} else {
+		nr.tasks = tasks
+	}
+	nr.refreshWorkers(ctx)
+	return nr
+}
+
+// Start liest die Namespaces der Worker regelmäßig neu
+func (nr *NamespaceRegistry) Start() {
+	go func() {
+		ticker := time.NewTicker(workerNamespacesRefresh)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			nr.refreshWorkers(ctx)
+			cancel()
+		}
+	}()
+}
+
+// refreshWorkers liest die von den Workern beim Start hinterlegten Namespaces. SCAN statt
+// KEYS, damit Redis bei vielen Schlüsseln nicht blockiert; doppelt gelieferte Schlüssel
+// überschreiben nur denselben Eintrag.
+func (nr *NamespaceRegistry) refreshWorkers(ctx context.Context) {
+	workers := make(map[string][]string)
+	iter := nr.redisClient.Scan(ctx, 0, workerNamespacesPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		value, err := nr.redisClient.Get(ctx, iter.Val()).Result()
+		if err != nil {
+			continue
+		}
+		workers[strings.TrimPrefix(iter.Val(), workerNamespacesPrefix)] = strings.Split(value, ",")
+	}
+	if err := iter.Err(); err != nil {
+		namespaceLogger.Errorf("Fehler beim Laden der Worker-Namespaces: %v", err)
+		return
+	}
+
+	nr.mutex.Lock()
+	nr.workers = workers
+	nr.mutex.Unlock()
+}
+
+// SetTask ordnet einen neuen Task einem Namespace zu
+func (nr *NamespaceRegistry) SetTask(ctx context.Context, taskID, namespace string) error {
+	if namespace != protocol.DefaultNamespace {
+		if err := nr.redisClient.HSet(ctx, taskNamespacesKey, taskID, namespace).Err(); err != nil {
+			return fmt.Errorf("Fehler beim Speichern des Task-Namespace: %w", err)
+		}
+	}
+	nr.mutex.Lock()
+	nr.tasks[taskID] = namespace
+	nr.mutex.Unlock()
+	return nil
+}
+
+// RemoveTask entfernt die Zuordnung eines gelöschten Tasks
+func (nr *NamespaceRegistry) RemoveTask(ctx context.Context, taskID string) error {
+	nr.mutex.Lock()
+	delete(nr.tasks, taskID)
+	nr.mutex.Unlock()
+	if err := nr.redisClient.HDel(ctx, taskNamespacesKey, taskID).Err(); err != nil {
+		return fmt.Errorf("Fehler beim Löschen des Task-Namespace: %w", err)
+	}
+	return nil
+}
+
+// TaskNamespace liefert den Namespace eines Tasks. Tasks, die erst nach dem Start
+// z.B. von einer anderen Instanz erstellt wurden, werden in Redis nachgeschlagen und
+// zwischengespeichert. Tasks ohne Eintrag gehören zum Default-Namespace; sie werden nicht
+// zwischengespeichert, damit Anfragen mit beliebigen IDs den Speicher nicht füllen.
+func (nr *NamespaceRegistry) TaskNamespace(taskID string) string {
+	nr.mutex.RLock()
+	namespace, ok := nr.tasks[taskID]
+	nr.mutex.RUnlock()
+	if ok {
+		return namespace
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	namespace, err := nr.redisClient.HGet(ctx, taskNamespacesKey, taskID).Result()
+	if err == redis.Nil {
+		return protocol.DefaultNamespace
+	}
+	if err != nil {
+		namespaceLogger.Task(taskID).Errorf("Fehler beim Laden des Namespace von Task %s: %v", taskID, err)
+		return protocol.DefaultNamespace
+	}
+
+	nr.mutex.Lock()
+	nr.tasks[taskID] = namespace
+	nr.mutex.Unlock()
+	return namespace
+}
+
+// WorkerNamespaces liefert die Namespaces, deren Tasks ein Worker annimmt
+func (nr *NamespaceRegistry) WorkerNamespaces(workerID string) []string {
+	nr.mutex.RLock()
+	defer nr.mutex.RUnlock()
+	if namespaces, ok := nr.workers[workerID]; ok {
+		return namespaces
+	}
+	return []string{protocol.DefaultNamespace}
+}
+
+// WorkerServes gibt an, ob ein Worker Tasks des Namespace annimmt
+func (nr *NamespaceRegistry) WorkerServes(workerID, namespace string) bool {
+	for _, ns := range nr.WorkerNamespaces(workerID) {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// eventNamespaces liefert die Namespaces, in denen ein Ereignis sichtbar ist;
+// nil bedeutet, dass es keinem Task oder Worker zugeordnet und überall sichtbar ist
+func (nr *NamespaceRegistry) eventNamespaces(event Event) []string {
+	switch {
+	case event.Type == "worker_update":
+		return nr.WorkerNamespaces(event.WorkerID)
+	case event.TaskID != "":
+		return []string{nr.TaskNamespace(event.TaskID)}
+	case event.WorkerID != "":
+		return nr.WorkerNamespaces(event.WorkerID)
+	}
+	return nil
+}
+
+type namespaceContextKey struct{}
+
+func withNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceContextKey{}, namespace)
+}
+
+// namespaceFromContext liefert den Namespace einer Anfrage (Standard: default)
+func namespaceFromContext(ctx context.Context) string {
+	if namespace, ok := ctx.Value(namespaceContextKey{}).(string); ok {
+		return namespace
+	}
+	return protocol.DefaultNamespace
+}
+
+// requestNamespace liest den Namespace aus dem Header X-Namespace oder, für Browser-Clients
+// ohne eigene Header (WebSocket, EventSource), aus dem Query-Parameter namespace
+func requestNamespace(r *http.Request) (string, error) {
+	namespace := r.Header.Get("X-Namespace")
+	if namespace == "" {
+		namespace = r.URL.Query().Get("namespace")
+	}
+	if namespace == "" {
+		return protocol.DefaultNamespace, nil
+	}
+	if err := protocol.ValidateNamespace(namespace); err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+// NamespaceMiddleware ordnet jede Anfrage einem Namespace zu, prüft, ob der Aufrufer
+// ihn verwenden darf, und beschränkt Task- und Worker-Routen auf diesen Namespace.
+// Sie muss nach der Authentifizierung eingehängt werden.
+func (ts *TaskService) NamespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace, err := requestNamespace(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if principal := principalFromContext(r.Context()); principal != nil && !principal.allows(namespace) {
+			http.Error(w, fmt.Sprintf("Kein Zugriff auf Namespace %s", namespace), http.StatusForbidden)
+			return
+		}
+		r = r.WithContext(withNamespace(r.Context(), namespace))
+
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := route.GetPathTemplate()
+		vars := mux.Vars(r)
+
+		// Tasks und Worker anderer Namespaces sind nicht sichtbar
+		switch {
+		case strings.HasPrefix(template, "/api/tasks/{id}"):
+			if ts.namespaces.TaskNamespace(vars["id"]) != namespace {
+				writeServiceError(w, ErrTaskNotFound)
+				return
+			}
+		case strings.HasPrefix(template, "/api/workers/{id}"):
+			if !ts.namespaces.WorkerServes(vars["id"], namespace) {
+				writeServiceError(w, ErrWorkerNotFound)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestNamespaceRegistryRefreshWorkers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	// Mehr Worker als ein SCAN-Aufruf liefert
+	const workers = 250
+	for i := 0; i < workers; i++ {
+		mr.Set(fmt.Sprintf("%sworker-%d", workerNamespacesPrefix, i), "default,team-a")
+	}
+	mr.Set("schema:other", "{}")
+
+	nr := NewNamespaceRegistry(client)
+	if len(nr.workers) != workers {
+		t.Fatalf("%d Worker geladen, erwartet %d", len(nr.workers), workers)
+	}
+	if !nr.WorkerServes("worker-249", "team-a") || nr.WorkerServes("worker-249", "team-b") {
+		t.Fatalf("Namespaces von worker-249 = %v", nr.WorkerNamespaces("worker-249"))
+	}
+
+	// Entfernte Worker verschwinden beim nächsten Lesen
+	mr.Del(workerNamespacesPrefix + "worker-0")
+	nr.refreshWorkers(context.Background())
+	if got := strings.Join(nr.WorkerNamespaces("worker-0"), ","); got != "default" {
+		t.Fatalf("Namespaces eines entfernten Workers = %s, erwartet default", got)
+	}
+}
+
+func TestNamespaceRegistryTaskNamespace(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	mr.HSet(taskNamespacesKey, "task-1", "team-a")
+	nr := NewNamespaceRegistry(client)
+
+	// Nach dem Start von einer anderen Instanz erstellt
+	mr.HSet(taskNamespacesKey, "task-2", "team-b")
+	tests := []struct {
+		taskID string
+		want   string
+	}{
+		{"task-1", "team-a"},
+		{"task-2", "team-b"},
+		{"task-3", "default"},
+	}
+	for _, tt := range tests {
+		if got := nr.TaskNamespace(tt.taskID); got != tt.want {
+			t.Errorf("TaskNamespace(%s) = %s, erwartet %s", tt.taskID, got, tt.want)
+		}
+	}
+	if got := nr.tasks["task-2"]; got != "team-b" {
+		t.Fatalf("task-2 nicht zwischengespeichert: %q", got)
+	}
+	if _, ok := nr.tasks["task-3"]; ok {
+		t.Fatal("Task ohne Eintrag zwischengespeichert")
+	}
+
+	// Gelöschte Tasks verschwinden aus Speicher und Redis
+	if err := nr.RemoveTask(context.Background(), "task-2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nr.tasks["task-2"]; ok {
+		t.Fatal("task-2 nach RemoveTask noch im Speicher")
+	}
+	if mr.HGet(taskNamespacesKey, "task-2") != "" {
+		t.Fatal("task-2 nach RemoveTask noch in Redis")
+	}
+	if got := nr.TaskNamespace("task-2"); got != "default" {
+		t.Fatalf("TaskNamespace(task-2) nach RemoveTask = %s, erwartet default", got)
+	}
+}
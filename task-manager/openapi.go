@@ -0,0 +1,91 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPISpec ist die maschinenlesbare Beschreibung der REST-API.
+// Der Go-Client im Paket client wird aus derselben Datei generiert.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// pathParamPattern erkennt Pfadparameter wie {id} in Routen und im Dokument
+var pathParamPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+// HandleOpenAPISpec ist der HTTP-Handler für GET /api/openapi.json
+func HandleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// checkOpenAPIRoutes vergleicht die registrierten /api-Routen und /metrics mit dem OpenAPI-Dokument
+// und liefert eine Beschreibung jeder Abweichung in beide Richtungen
+func checkOpenAPIRoutes(r *mux.Router) ([]string, error) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("OpenAPI-Dokument ist ungültig: %w", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			switch method {
+			case "get", "put", "post", "delete", "patch", "head", "options":
+				documented[routeKey(method, path)] = true
+			}
+		}
+	}
+
+	registered := make(map[string]bool)
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil || (!strings.HasPrefix(tpl, "/api/") && tpl != "/metrics") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Routen ohne Methodenbeschränkung gelten als GET
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			registered[routeKey(method, tpl)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []string
+	for key := range registered {
+		if !documented[key] {
+			drift = append(drift, "nicht dokumentiert: "+key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			drift = append(drift, "nicht registriert: "+key)
+		}
+	}
+	sort.Strings(drift)
+	return drift, nil
+}
+
+// routeKey normalisiert Methode und Pfad, damit {id} und {task_id} gleich behandelt werden
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + pathParamPattern.ReplaceAllString(path, "{}")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newRoleRouter registriert jede Route aus routeRoles mit einem leeren Handler
+func newRoleRouter() *mux.Router {
+	r := mux.NewRouter()
+	for key := range routeRoles {
+		parts := strings.SplitN(key, " ", 2)
+		r.HandleFunc(parts[1], func(http.ResponseWriter, *http.Request) {}).Methods(parts[0])
+	}
+	return r
+}
+
+// Der Task-Manager startet nur, wenn Routen, routeRoles und openapi.json übereinstimmen
+func TestOpenAPIMatchesRouteRoles(t *testing.T) {
+	r := newRoleRouter()
+
+	drift, err := checkOpenAPIRoutes(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) > 0 {
+		t.Errorf("Abweichungen zwischen routeRoles und openapi.json:\n%s", strings.Join(drift, "\n"))
+	}
+
+	unprotected, err := checkRouteRoles(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unprotected) > 0 {
+		t.Errorf("Routen ohne Rolle: %v", unprotected)
+	}
+}
+
+func TestRouteChecksReportDrift(t *testing.T) {
+	r := newRoleRouter()
+	r.HandleFunc("/api/undocumented/{name}", func(http.ResponseWriter, *http.Request) {}).Methods("DELETE")
+
+	drift, err := checkOpenAPIRoutes(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) != 1 || drift[0] != "nicht dokumentiert: DELETE /api/undocumented/{}" {
+		t.Fatalf("Abweichungen = %v", drift)
+	}
+
+	unprotected, err := checkRouteRoles(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unprotected) != 1 || unprotected[0] != "DELETE /api/undocumented/{name}" {
+		t.Fatalf("Routen ohne Rolle = %v", unprotected)
+	}
+}
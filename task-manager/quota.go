@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// quotaLimitsKey ist der Redis-Hash Client → QuotaLimits mit abweichenden Limits einzelner Clients
+	quotaLimitsKey = "quota_limits"
+	// rateLimitPrefix ist der Präfix der Token-Buckets je Client
+	rateLimitPrefix = "ratelimit:"
+	// outstandingPrefix ist der Präfix der Mengen offener Tasks je Client
+	outstandingPrefix = "quota_outstanding:"
+	// outstandingRetryAfter ist die empfohlene Wartezeit, wenn zu viele Tasks offen sind;
+	// wann ein Task endet, ist nicht vorhersehbar
+	outstandingRetryAfter = 5 * time.Second
+)
+
+// tokenBucketScript entnimmt ARGV[3] Tokens aus dem Bucket KEYS[1] (Rate ARGV[1] pro
+// Sekunde, höchstens ARGV[2] Tokens). Die Zeit stammt von Redis, damit alle Instanzen
+// des Task-Managers dieselbe Uhr verwenden. Liefert {erlaubt, Tokens, Wartezeit in s}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+if cost == 0 then
+	return {1, tostring(tokens), '0'}
+end
+if tokens < cost then
+	return {0, tostring(tokens), tostring((cost - tokens) / rate)}
+end
+tokens = tokens - cost
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(burst / rate) + 1)
+return {1, tostring(tokens), '0'}
+`)
+
+// admitOutstandingScript nimmt die Task-ID ARGV[2] in die Menge KEYS[1] auf, solange
+// sie weniger als ARGV[1] Einträge hat
+var admitOutstandingScript = redis.NewScript(`
+if redis.call('SCARD', KEYS[1]) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[2])
+return 1
+`)
+
+// QuotaLimits sind die Limits eines Clients; 0 bedeutet unbegrenzt
+type QuotaLimits struct {
+	// Rate ist die Anzahl neuer Tasks pro Sekunde, die dauerhaft erlaubt ist
+	Rate float64 `json:"rate"`
+	// Burst ist die Anzahl neuer Tasks, die kurzzeitig auf einmal erlaubt ist
+	Burst int `json:"burst"`
+	// MaxOutstanding ist die Anzahl gleichzeitig offener (nicht beendeter) Tasks
+	MaxOutstanding int `json:"maxOutstanding"`
+}
+
+// QuotaUsage ist der aktuelle Verbrauch eines Clients
+type QuotaUsage struct {
+	Client string      `json:"client"`
+	Limits QuotaLimits `json:"limits"`
+	// Tokens ist die Anzahl Tasks, die sofort erstellt werden können (-1: unbegrenzt)
+	Tokens float64 `json:"tokens"`
+	// Outstanding ist die Anzahl offener Tasks des Clients
+	Outstanding int64 `json:"outstanding"`
+}
+
+// QuotaExceededError wird geliefert, wenn ein Client ein Limit überschreitet
+type QuotaExceededError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s, erneut versuchen in %d s", e.Reason, e.retryAfterSeconds())
+}
+
+// retryAfterSeconds liefert die Wartezeit aufgerundet auf ganze Sekunden, mindestens 1
+func (e *QuotaExceededError) retryAfterSeconds() int {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// Quotas begrenzt, wie schnell und wie viele Tasks ein Client erstellen darf. Zähler
+// und abweichende Limits liegen in Redis, damit alle Instanzen dieselben verwenden.
+type Quotas struct {
+	redisClient *redis.Client
+	defaults    QuotaLimits
+	// openFunc prüft, ob ein Task noch nicht beendet ist
+	openFunc func(ctx context.Context, taskID string) bool
+}
+
+// NewQuotasFromEnv liest die Standardlimits aus RATE_LIMIT_RATE, RATE_LIMIT_BURST
+// und TASK_QUOTA_MAX_OUTSTANDING
+func NewQuotasFromEnv(redisClient *redis.Client) *Quotas {
+	q := &Quotas{
+		redisClient: redisClient,
+		defaults: QuotaLimits{
+			Rate:           envFloat("RATE_LIMIT_RATE", 5),
+			Burst:          envInt("RATE_LIMIT_BURST", 20),
+			MaxOutstanding: envInt("TASK_QUOTA_MAX_OUTSTANDING", 100),
+		},
+	}
+	quotaLogger.Infof("Task-Limits je Client: %.1f/s (Burst %d), höchstens %d offene Tasks",
+		q.defaults.Rate, q.defaults.Burst, q.defaults.MaxOutstanding)
+	return q
+}
+
+// SetOpenFunc legt fest, wie geprüft wird, ob ein gezählter Task noch offen ist
+func (q *Quotas) SetOpenFunc(openFunc func(ctx context.Context, taskID string) bool) {
+	q.openFunc = openFunc
+}
+
+// Limits liefert die Limits eines Clients: abweichende aus Redis oder die Standardlimits
+func (q *Quotas) Limits(ctx context.Context, client string) (QuotaLimits, error) {
+	limitsJSON, err := q.redisClient.HGet(ctx, quotaLimitsKey, client).Result()
+	if err == redis.Nil {
+		return q.defaults, nil
+	}
+	if err != nil {
+		return QuotaLimits{}, fmt.Errorf("Fehler beim Laden der Limits: %w", err)
+	}
+	var limits QuotaLimits
+	if err := json.Unmarshal([]byte(limitsJSON), &limits); err != nil {
+		return QuotaLimits{}, fmt.Errorf("Fehler beim Deserialisieren der Limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetLimits legt abweichende Limits für einen Client fest
+func (q *Quotas) SetLimits(ctx context.Context, client string, limits QuotaLimits) error {
+	limitsJSON, err := json.Marshal(limits)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Serialisieren der Limits: %w", err)
+	}
+	if err := q.redisClient.HSet(ctx, quotaLimitsKey, client, limitsJSON).Err(); err != nil {
+		return fmt.Errorf("Fehler beim Speichern der Limits: %w", err)
+	}
+	return nil
+}
+
+// Admit prüft vor dem Erstellen eines Tasks beide Limits und zählt den Task zu den
+// offenen Tasks des Clients. Schlägt das Erstellen danach fehl, muss Release folgen.
+func (q *Quotas) Admit(ctx context.Context, client, taskID string) error {
+	limits, err := q.Limits(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxOutstanding > 0 {
+		key := outstandingPrefix + client
+		admitted, err := admitOutstandingScript.Run(ctx, q.redisClient, []string{key}, limits.MaxOutstanding, taskID).Int()
+		if err != nil {
+			return fmt.Errorf("Fehler beim Prüfen der offenen Tasks: %w", err)
+		}
+		if admitted == 0 {
+			// Beendete Tasks werden erst entfernt, wenn das Limit erreicht ist
+			if _, err := q.prune(ctx, client); err != nil {
+				return err
+			}
+			admitted, err = admitOutstandingScript.Run(ctx, q.redisClient, []string{key}, limits.MaxOutstanding, taskID).Int()
+			if err != nil {
+				return fmt.Errorf("Fehler beim Prüfen der offenen Tasks: %w", err)
+			}
+		}
+		if admitted == 0 {
+			return &QuotaExceededError{
+				Reason:     fmt.Sprintf("Höchstens %d offene Tasks erlaubt", limits.MaxOutstanding),
+				RetryAfter: outstandingRetryAfter,
+			}
+		}
+	}
+
+	if limits.Rate > 0 {
+		allowed, _, wait, err := q.takeToken(ctx, client, limits, 1)
+		if err != nil {
+			q.Release(ctx, client, taskID)
+			return err
+		}
+		if !allowed {
+			q.Release(ctx, client, taskID)
+			return &QuotaExceededError{
+				Reason:     fmt.Sprintf("Höchstens %.1f neue Tasks pro Sekunde erlaubt", limits.Rate),
+				RetryAfter: wait,
+			}
+		}
+	}
+	return nil
+}
+
+// Release nimmt einen nicht erstellten Task wieder aus den offenen Tasks des Clients
+func (q *Quotas) Release(ctx context.Context, client, taskID string) {
+	if err := q.redisClient.SRem(ctx, outstandingPrefix+client, taskID).Err(); err != nil {
+		quotaLogger.Errorf("Fehler beim Freigeben des Task-Kontingents von %s: %v", client, err)
+	}
+}
+
+// Usage liefert Limits und Verbrauch eines Clients
+func (q *Quotas) Usage(ctx context.Context, client string) (*QuotaUsage, error) {
+	limits, err := q.Limits(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	usage := &QuotaUsage{Client: client, Limits: limits, Tokens: -1}
+	if limits.Rate > 0 {
+		if _, usage.Tokens, _, err = q.takeToken(ctx, client, limits, 0); err != nil {
+			return nil, err
+		}
+	}
+	if usage.Outstanding, err = q.prune(ctx, client); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// takeToken entnimmt cost Tokens aus dem Bucket des Clients; cost 0 liest nur den Stand
+func (q *Quotas) takeToken(ctx context.Context, client string, limits QuotaLimits, cost int) (bool, float64, time.Duration, error) {
+	burst := limits.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	result, err := tokenBucketScript.Run(ctx, q.redisClient, []string{rateLimitPrefix + client}, limits.Rate, burst, cost).Slice()
+	if err != nil || len(result) != 3 {
+		return false, 0, 0, fmt.Errorf("Fehler beim Prüfen des Ratenlimits: %v", err)
+	}
+	allowed, _ := result[0].(int64)
+	tokensStr, _ := result[1].(string)
+	waitStr, _ := result[2].(string)
+	tokens, _ := strconv.ParseFloat(tokensStr, 64)
+	wait, _ := strconv.ParseFloat(waitStr, 64)
+	return allowed == 1, math.Floor(tokens*100) / 100, time.Duration(wait * float64(time.Second)), nil
+}
+
+// prune entfernt beendete Tasks aus den offenen Tasks eines Clients und liefert deren Anzahl
+func (q *Quotas) prune(ctx context.Context, client string) (int64, error) {
+	key := outstandingPrefix + client
+	taskIDs, err := q.redisClient.SMembers(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("Fehler beim Laden der offenen Tasks: %w", err)
+	}
+	var finished []interface{}
+	for _, taskID := range taskIDs {
+		if q.openFunc != nil && !q.openFunc(ctx, taskID) {
+			finished = append(finished, taskID)
+		}
+	}
+	if len(finished) > 0 {
+		if err := q.redisClient.SRem(ctx, key, finished...).Err(); err != nil {
+			return 0, fmt.Errorf("Fehler beim Entfernen beendeter Tasks: %w", err)
+		}
+	}
+	return int64(len(taskIDs) - len(finished)), nil
+}
+
+type clientAddrContextKey struct{}
+
+// withClientAddr legt die Netzwerkadresse des Aufrufers im Kontext ab
+func withClientAddr(ctx context.Context, remoteAddr string) context.Context {
+	return context.WithValue(ctx, clientAddrContextKey{}, remoteAddr)
+}
+
+// clientIdentity bestimmt, wessen Kontingent ein Aufruf belastet: den API-Schlüssel
+// bzw. das JWT-Subjekt oder, ohne Authentifizierung, die IP-Adresse des Aufrufers.
+// X-Forwarded-For wird nicht beachtet, da der Header frei wählbar ist.
+func clientIdentity(ctx context.Context) string {
+	if principal := principalFromContext(ctx); principal != nil && principal != anonymousPrincipal {
+		return "key:" + principal.Name
+	}
+	addr, _ := ctx.Value(clientAddrContextKey{}).(string)
+	if addr == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if addr == "" {
+		return "anonym"
+	}
+	return "ip:" + addr
+}
+
+// writeQuotaExceeded antwortet mit 429 und Retry-After in ganzen Sekunden
+func writeQuotaExceeded(w http.ResponseWriter, err *QuotaExceededError) {
+	w.Header().Set("Retry-After", strconv.Itoa(err.retryAfterSeconds()))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+// HandleGetOwnUsage ist der HTTP-Handler für GET /api/quotas
+func (q *Quotas) HandleGetOwnUsage(w http.ResponseWriter, r *http.Request) {
+	q.writeUsage(w, r, clientIdentity(withClientAddr(r.Context(), r.RemoteAddr)))
+}
+
+// HandleGetUsage ist der HTTP-Handler für GET /api/quotas/{client}
+func (q *Quotas) HandleGetUsage(w http.ResponseWriter, r *http.Request) {
+	q.writeUsage(w, r, mux.Vars(r)["client"])
+}
+
+func (q *Quotas) writeUsage(w http.ResponseWriter, r *http.Request, client string) {
+	usage, err := q.Usage(r.Context(), client)
+	if err != nil {
+		quotaLogger.Errorf("Fehler beim Abrufen des Kontingents von %s: %v", client, err)
+		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// HandleSetLimits ist der HTTP-Handler für PUT /api/quotas/{client}
+func (q *Quotas) HandleSetLimits(w http.ResponseWriter, r *http.Request) {
+	var limits QuotaLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		http.Error(w, "Ungültige Anfrage", http.StatusBadRequest)
+		return
+	}
+	if limits.Rate < 0 || limits.Burst < 0 || limits.MaxOutstanding < 0 {
+		http.Error(w, "Limits dürfen nicht negativ sein", http.StatusBadRequest)
+		return
+	}
+
+	client := mux.Vars(r)["client"]
+	if err := q.SetLimits(r.Context(), client, limits); err != nil {
+		quotaLogger.Errorf("Fehler beim Setzen der Limits von %s: %v", client, err)
+		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
+		return
+	}
+	quotaLogger.Infof("Limits für %s gesetzt: %.1f/s (Burst %d), höchstens %d offene Tasks",
+		client, limits.Rate, limits.Burst, limits.MaxOutstanding)
+
+	q.writeUsage(w, r, client)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestQuotas startet ein Redis im Speicher mit fester Uhr für die Lua-Skripte
+func newTestQuotas(t *testing.T, defaults QuotaLimits) (*Quotas, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Unix(1700000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Quotas{redisClient: client, defaults: defaults}, mr
+}
+
+func TestQuotasAdmitRate(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		allowed bool
+		// retryAfter ist die erwartete Wartezeit einer Ablehnung
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name   string
+		limits QuotaLimits
+		steps  []step
+	}{
+		{
+			name:   "Burst, danach Rate",
+			limits: QuotaLimits{Rate: 2, Burst: 3},
+			steps: []step{
+				{allowed: true}, {allowed: true}, {allowed: true},
+				{allowed: false, retryAfter: 500 * time.Millisecond},
+				{advance: 250 * time.Millisecond, allowed: false, retryAfter: 250 * time.Millisecond},
+				{advance: 250 * time.Millisecond, allowed: true},
+				{allowed: false, retryAfter: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:   "Bucket füllt sich höchstens bis Burst",
+			limits: QuotaLimits{Rate: 10, Burst: 2},
+			steps: []step{
+				{advance: time.Hour, allowed: true}, {allowed: true},
+				{allowed: false, retryAfter: 100 * time.Millisecond},
+			},
+		},
+		{
+			name:   "Burst 0 erlaubt einen Task",
+			limits: QuotaLimits{Rate: 1},
+			steps: []step{
+				{allowed: true},
+				{allowed: false, retryAfter: time.Second},
+				{advance: time.Second, allowed: true},
+			},
+		},
+		{
+			name:   "Rate 0 ist unbegrenzt",
+			limits: QuotaLimits{},
+			steps:  []step{{allowed: true}, {allowed: true}, {allowed: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, mr := newTestQuotas(t, tt.limits)
+			ctx := context.Background()
+			now := time.Unix(1700000000, 0)
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				mr.SetTime(now)
+				err := q.Admit(ctx, "key:alice", "task-"+string(rune('a'+i)))
+
+				var quotaErr *QuotaExceededError
+				switch {
+				case s.allowed && err != nil:
+					t.Fatalf("Schritt %d: abgelehnt: %v", i, err)
+				case !s.allowed && !errors.As(err, &quotaErr):
+					t.Fatalf("Schritt %d: Fehler = %v, erwartet QuotaExceededError", i, err)
+				case !s.allowed && absDuration(quotaErr.RetryAfter-s.retryAfter) > time.Millisecond:
+					t.Fatalf("Schritt %d: RetryAfter = %v, erwartet %v", i, quotaErr.RetryAfter, s.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestQuotasAdmitOutstanding(t *testing.T) {
+	q, _ := newTestQuotas(t, QuotaLimits{MaxOutstanding: 2})
+	ctx := context.Background()
+	open := map[string]bool{}
+	q.SetOpenFunc(func(ctx context.Context, taskID string) bool { return open[taskID] })
+
+	admit := func(taskID string) error {
+		err := q.Admit(ctx, "key:alice", taskID)
+		if err == nil {
+			open[taskID] = true
+		}
+		return err
+	}
+
+	if err := admit("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := admit("b"); err != nil {
+		t.Fatal(err)
+	}
+	var quotaErr *QuotaExceededError
+	if err := admit("c"); !errors.As(err, &quotaErr) || quotaErr.RetryAfter != outstandingRetryAfter {
+		t.Fatalf("dritter Task: Fehler = %v, erwartet QuotaExceededError", err)
+	}
+
+	// Andere Clients haben ein eigenes Kontingent
+	if err := q.Admit(ctx, "key:bob", "x"); err != nil {
+		t.Fatalf("anderer Client abgelehnt: %v", err)
+	}
+
+	// Beendete Tasks werden beim Erreichen des Limits entfernt
+	open["a"] = false
+	if err := admit("c"); err != nil {
+		t.Fatalf("nach Ende von a abgelehnt: %v", err)
+	}
+
+	// Release gibt einen nicht erstellten Task frei
+	q.Release(ctx, "key:alice", "c")
+	delete(open, "c")
+	if err := admit("d"); err != nil {
+		t.Fatalf("nach Release abgelehnt: %v", err)
+	}
+
+	usage, err := q.Usage(ctx, "key:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Outstanding != 2 || usage.Tokens != -1 {
+		t.Fatalf("Usage = %+v, erwartet 2 offene Tasks und unbegrenzte Tokens", usage)
+	}
+}
+
+func TestQuotasRateRejectionReleasesOutstanding(t *testing.T) {
+	q, _ := newTestQuotas(t, QuotaLimits{Rate: 1, Burst: 1, MaxOutstanding: 10})
+	ctx := context.Background()
+
+	if err := q.Admit(ctx, "key:alice", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Admit(ctx, "key:alice", "b"); err == nil {
+		t.Fatal("zweiter Task trotz Ratenlimit angenommen")
+	}
+
+	members, err := q.redisClient.SMembers(ctx, outstandingPrefix+"key:alice").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != "a" {
+		t.Fatalf("offene Tasks = %v, erwartet [a]", members)
+	}
+}
+
+func TestQuotasUsageDoesNotConsumeTokens(t *testing.T) {
+	q, _ := newTestQuotas(t, QuotaLimits{Rate: 1, Burst: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		usage, err := q.Usage(ctx, "key:alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Tokens != 2 {
+			t.Fatalf("Tokens = %v, erwartet 2", usage.Tokens)
+		}
+	}
+	if err := q.Admit(ctx, "key:alice", "a"); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := q.Usage(ctx, "key:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Tokens != 1 {
+		t.Fatalf("Tokens nach einem Task = %v, erwartet 1", usage.Tokens)
+	}
+}
+
+func TestQuotasClientLimits(t *testing.T) {
+	q, _ := newTestQuotas(t, QuotaLimits{Rate: 1, Burst: 1})
+	ctx := context.Background()
+
+	if err := q.SetLimits(ctx, "key:batch", QuotaLimits{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := q.Admit(ctx, "key:batch", "t"); err != nil {
+			t.Fatalf("Client ohne Limits abgelehnt: %v", err)
+		}
+	}
+
+	limits, err := q.Limits(ctx, "key:other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits != q.defaults {
+		t.Fatalf("Limits = %+v, erwartet Standardlimits", limits)
+	}
+}
+
+func TestQuotaExceededRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       int
+	}{
+		{0, 1},
+		{100 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{5 * time.Second, 5},
+	}
+	for _, tt := range tests {
+		err := &QuotaExceededError{RetryAfter: tt.retryAfter}
+		if got := err.retryAfterSeconds(); got != tt.want {
+			t.Errorf("retryAfterSeconds(%v) = %d, erwartet %d", tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// routeRoles legt für jede Route die mindestens erforderliche Rolle fest.
+// Nicht aufgeführte Routen erfordern admin; checkRouteRoles findet sie, und der
+// Task-Manager startet dann nicht.
+var routeRoles = map[string]Role{
+	"GET /api/tasks":               RoleViewer,
+	"POST /api/tasks":              RoleOperator,
+	"GET /api/tasks/{id}":          RoleViewer,
+	"POST /api/tasks/{id}/migrate": RoleOperator,
+	"POST /api/tasks/{id}/cancel":  RoleOperator,
+	"POST /api/tasks/{id}/pause":   RoleOperator,
+	"POST /api/tasks/{id}/resume":  RoleOperator,
+
+	"GET /api/workers":                RoleViewer,
+	"GET /api/workers/{id}":           RoleViewer,
+	"POST /api/workers/{id}/overload": RoleAdmin,
+	"POST /api/workers/{id}/fail":     RoleAdmin,
+	"POST /api/workers/{id}/recover":  RoleAdmin,
+
+	"GET /api/schemas":        RoleViewer,
+	"GET /api/schemas/{type}": RoleViewer,
+	"PUT /api/schemas/{type}": RoleAdmin,
+
+	"GET /api/quotas":          RoleViewer,
+	"GET /api/quotas/{client}": RoleAdmin,
+	"PUT /api/quotas/{client}": RoleAdmin,
+
+	"GET /api/audit":        RoleAdmin,
+	"GET /api/audit/export": RoleAdmin,
+
+	"GET /api/encryption":         RoleAdmin,
+	"POST /api/encryption/rewrap": RoleAdmin,
+
+	"GET /api/system/status":    RoleViewer,
+	"GET /api/system/events":    RoleViewer,
+	"GET /api/system/websocket": RoleViewer,
+	"GET /api/events/stream":    RoleViewer,
+	"GET /api/openapi.json":     RoleViewer,
+
+	"GET /api/system/logging": RoleAdmin,
+	"PUT /api/system/logging": RoleAdmin,
+
+	// Prometheus sendet das Token als Bearer-Token (authorization im Scrape-Job)
+	"GET /metrics": RoleViewer,
+
+	// Die WebSocket-Verbindung prüft die Zugangsdaten selbst beim Upgrade
+	"GET /ws": RolePublic,
+}
+
+// queryTokenRoutes sind Routen, die das Token auch als ?token= annehmen, weil
+// Browser-Clients (EventSource) keine Header setzen können
+var queryTokenRoutes = map[string]bool{
+	"/api/events/stream": true,
+}
+
+// routeRoleIndex sind die Einträge von routeRoles mit normalisierten Pfadparametern
+var routeRoleIndex = func() map[string]Role {
+	index := make(map[string]Role, len(routeRoles))
+	for key, role := range routeRoles {
+		parts := strings.SplitN(key, " ", 2)
+		index[routeKey(parts[0], parts[1])] = role
+	}
+	return index
+}()
+
+// requiredRole liefert die Rolle, die die Route einer Anfrage erfordert, und das Pfad-Template
+func requiredRole(r *http.Request) (Role, string) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return RoleAdmin, r.URL.Path
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return RoleAdmin, r.URL.Path
+	}
+	if role, ok := routeRoleIndex[routeKey(r.Method, template)]; ok {
+		return role, template
+	}
+	return RoleAdmin, template
+}
+
+// checkRouteRoles liefert alle registrierten Routen ohne Eintrag in routeRoles
+func checkRouteRoles(r *mux.Router) ([]string, error) {
+	var missing []string
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Routen ohne Methodenbeschränkung gelten als GET
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			if _, ok := routeRoleIndex[routeKey(method, template)]; !ok {
+				missing = append(missing, method+" "+template)
+			}
+		}
+		return nil
+	})
+	sort.Strings(missing)
+	return missing, err
+}
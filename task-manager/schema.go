@@ -0,0 +1,685 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// schemaKeyPrefix ist der Redis-Präfix, unter dem registrierte Schemas liegen; Schemas
+// anderer Namespaces als default tragen zusätzlich das Präfix ihres Namespace
+const schemaKeyPrefix = "schema:"
+
+// JSONSchema ist die unterstützte Teilmenge von JSON Schema (Draft 7)
+type JSONSchema struct {
+	Type                 SchemaTypes            `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// SchemaTypes erlaubt "type" als einzelnen String oder als Liste
+type SchemaTypes []string
+
+// UnmarshalJSON akzeptiert sowohl "string" als auch ["string", "null"]
+func (st *SchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*st = SchemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("\"type\" muss ein String oder eine Liste von Strings sein")
+	}
+	*st = multiple
+	return nil
+}
+
+// MarshalJSON gibt einzelne Typen wieder als String aus
+func (st SchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(st) == 1 {
+		return json.Marshal(st[0])
+	}
+	return json.Marshal([]string(st))
+}
+
+// allowedSchemaKeywords sind alle Schlüsselwörter, die der Validator kennt.
+// Unbekannte Schlüsselwörter werden bei der Registrierung abgelehnt, damit
+// niemand annimmt, dass sie geprüft werden.
+var allowedSchemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true,
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "enum": true, "minimum": true, "maximum": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minItems": true, "maxItems": true,
+}
+
+var validSchemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// FieldError beschreibt einen Validierungsfehler an einem bestimmten Feld
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// TaskSchema ist ein registriertes Schema für einen Task-Typ
+type TaskSchema struct {
+	TaskType  string          `json:"task_type"`
+	Namespace string          `json:"namespace"`
+	Version   int             `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	UpdatedAt TimeJSON        `json:"updated_at"`
+
+	compiled *JSONSchema
+}
+
+// SchemaRegistry ordnet Task-Typen ihren Payload-Schemas zu. Jeder Namespace kann eigene
+// Schemas registrieren; ohne eigenes Schema gilt das des Namespace default.
+type SchemaRegistry struct {
+	redisClient *redis.Client
+	// schemas ist nach schemaKey geordnet, also nach Namespace und Task-Typ
+	schemas map[string]*TaskSchema
+	mutex   sync.RWMutex
+}
+
+// schemaKey liefert den Redis-Schlüssel des Schemas eines Task-Typs in einem Namespace
+func schemaKey(namespace, taskType string) string {
+	return protocol.NamespaceKey(namespace, schemaKeyPrefix+taskType)
+}
+
+// schemaKeyNamespace liefert den Namespace eines Schema-Schlüssels aus Redis
+func schemaKeyNamespace(key string) (string, bool) {
+	if strings.HasPrefix(key, schemaKeyPrefix) {
+		return protocol.DefaultNamespace, true
+	}
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) != 3 || parts[0] != "ns" || !strings.HasPrefix(parts[2], schemaKeyPrefix) {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// defaultTaskSchemas sind die Schemas der Task-Typen, die das Frontend anbietet
+var defaultTaskSchemas = map[string]string{
+	"computation": `{
+		"type": "object",
+		"required": ["operation"],
+		"properties": {
+			"operation": {"type": "string", "minLength": 1},
+			"input": {"type": "array", "items": {"type": "number"}},
+			"iterations": {"type": "integer", "minimum": 1}
+		}
+	}`,
+	"io": `{
+		"type": "object",
+		"required": ["operation"],
+		"properties": {
+			"operation": {"type": "string", "minLength": 1},
+			"filePath": {"type": "string", "minLength": 1},
+			"encoding": {"type": "string"}
+		}
+	}`,
+	"network": `{
+		"type": "object",
+		"required": ["operation", "url"],
+		"properties": {
+			"operation": {"type": "string", "minLength": 1},
+			"url": {"type": "string", "pattern": "^https?://"},
+			"method": {"enum": ["GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"]}
+		}
+	}`,
+}
+
+// NewSchemaRegistry erstellt eine neue Registry und lädt gespeicherte Schemas aus Redis
+func NewSchemaRegistry(redisClient *redis.Client) *SchemaRegistry {
+	sr := &SchemaRegistry{
+		redisClient: redisClient,
+		schemas:     make(map[string]*TaskSchema),
+	}
+
+	for taskType, raw := range defaultTaskSchemas {
+		compiled, err := compileSchema([]byte(raw))
+		if err != nil {
+			schemaLogger.Fatalf("Ungültiges Standard-Schema für Task-Typ %s: %v", taskType, err)
+		}
+		sr.schemas[schemaKey(protocol.DefaultNamespace, taskType)] = &TaskSchema{
+			TaskType:  taskType,
+			Namespace: protocol.DefaultNamespace,
+			Version:   1,
+			Schema:    json.RawMessage(raw),
+			UpdatedAt: TimeJSON(time.Now()),
+			compiled:  compiled,
+		}
+	}
+
+	if err := sr.load(); err != nil {
+		schemaLogger.Errorf("Fehler beim Laden der Schemas aus Redis: %v", err)
+	}
+
+	return sr
+}
+
+// load übernimmt alle in Redis gespeicherten Schemas aller Namespaces; sie überschreiben
+// die Standard-Schemas. Die Schlüssel werden mit SCAN statt KEYS gelesen, damit Redis nicht blockiert.
+func (sr *SchemaRegistry) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, pattern := range []string{schemaKeyPrefix + "*", protocol.NamespaceKey("*", schemaKeyPrefix+"*")} {
+		if err := sr.loadKeys(ctx, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadKeys übernimmt die Schemas unter allen Schlüsseln, die auf pattern passen
+func (sr *SchemaRegistry) loadKeys(ctx context.Context, pattern string) error {
+	iter := sr.redisClient.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		namespace, ok := schemaKeyNamespace(key)
+		if !ok {
+			continue
+		}
+		raw, err := sr.redisClient.Get(ctx, key).Result()
+		if err != nil {
+			schemaLogger.Errorf("Fehler beim Lesen von %s: %v", key, err)
+			continue
+		}
+
+		var stored TaskSchema
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			schemaLogger.Errorf("Fehler beim Deserialisieren von %s: %v", key, err)
+			continue
+		}
+
+		compiled, err := compileSchema(stored.Schema)
+		if err != nil {
+			schemaLogger.Warnf("Gespeichertes Schema für Task-Typ %s ist ungültig: %v", stored.TaskType, err)
+			continue
+		}
+		stored.Namespace = namespace
+		stored.compiled = compiled
+
+		sr.mutex.Lock()
+		sr.schemas[schemaKey(namespace, stored.TaskType)] = &stored
+		sr.mutex.Unlock()
+	}
+
+	return iter.Err()
+}
+
+// Register speichert ein neues Schema für einen Task-Typ im Namespace oder ersetzt das
+// bestehende. Die Version zählt ab dem bisher im Namespace gültigen Schema weiter.
+func (sr *SchemaRegistry) Register(namespace, taskType string, raw json.RawMessage) (*TaskSchema, error) {
+	compiled, err := compileSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	version := 1
+	if existing, ok := sr.get(namespace, taskType); ok {
+		version = existing.Version + 1
+	}
+
+	schema := &TaskSchema{
+		TaskType:  taskType,
+		Namespace: namespace,
+		Version:   version,
+		Schema:    raw,
+		UpdatedAt: TimeJSON(time.Now()),
+		compiled:  compiled,
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sr.redisClient.Set(ctx, schemaKey(namespace, taskType), schemaJSON, 0).Err(); err != nil {
+		return nil, fmt.Errorf("Fehler beim Speichern des Schemas in Redis: %w", err)
+	}
+
+	sr.schemas[schemaKey(namespace, taskType)] = schema
+	return schema, nil
+}
+
+// Get liefert das im Namespace gültige Schema eines Task-Typs
+func (sr *SchemaRegistry) Get(namespace, taskType string) (*TaskSchema, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+	return sr.get(namespace, taskType)
+}
+
+// get ist Get ohne Sperre: das Schema des Namespace, sonst das des Namespace default
+func (sr *SchemaRegistry) get(namespace, taskType string) (*TaskSchema, bool) {
+	if schema, ok := sr.schemas[schemaKey(namespace, taskType)]; ok {
+		return schema, true
+	}
+	schema, ok := sr.schemas[schemaKey(protocol.DefaultNamespace, taskType)]
+	return schema, ok
+}
+
+// List liefert alle im Namespace gültigen Schemas, sortiert nach Task-Typ
+func (sr *SchemaRegistry) List(namespace string) []*TaskSchema {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	taskTypes := make(map[string]bool)
+	for _, schema := range sr.schemas {
+		if schema.Namespace == namespace || schema.Namespace == protocol.DefaultNamespace {
+			taskTypes[schema.TaskType] = true
+		}
+	}
+	schemas := make([]*TaskSchema, 0, len(taskTypes))
+	for taskType := range taskTypes {
+		schema, _ := sr.get(namespace, taskType)
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].TaskType < schemas[j].TaskType
+	})
+	return schemas
+}
+
+// Validate prüft Typ und Daten eines neuen Tasks gegen das im Namespace gültige Schema
+func (sr *SchemaRegistry) Validate(namespace, taskType string, data map[string]interface{}) []FieldError {
+	if taskType == "" {
+		return []FieldError{{Field: "type", Message: "Task-Typ fehlt"}}
+	}
+
+	schema, ok := sr.Get(namespace, taskType)
+	if !ok {
+		return []FieldError{{Field: "type", Message: fmt.Sprintf("unbekannter Task-Typ %q", taskType)}}
+	}
+
+	var value interface{} = data
+	if data == nil {
+		value = map[string]interface{}{}
+	}
+
+	var errs []FieldError
+	schema.compiled.validate("data", value, &errs)
+	return errs
+}
+
+// HandleListSchemas ist der HTTP-Handler für GET /api/schemas
+func (sr *SchemaRegistry) HandleListSchemas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sr.List(namespaceFromContext(r.Context())))
+}
+
+// HandleGetSchema ist der HTTP-Handler für GET /api/schemas/{type}
+func (sr *SchemaRegistry) HandleGetSchema(w http.ResponseWriter, r *http.Request) {
+	taskType := mux.Vars(r)["type"]
+
+	schema, ok := sr.Get(namespaceFromContext(r.Context()), taskType)
+	if !ok {
+		http.Error(w, "Schema nicht gefunden", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema)
+}
+
+// HandleRegisterSchema ist der HTTP-Handler für PUT /api/schemas/{type}.
+// Der Request-Body ist das JSON Schema für das Feld "data" dieses Task-Typs;
+// es gilt nur im Namespace der Anfrage.
+func (sr *SchemaRegistry) HandleRegisterSchema(w http.ResponseWriter, r *http.Request) {
+	taskType := mux.Vars(r)["type"]
+	namespace := namespaceFromContext(r.Context())
+
+	raw, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Fehler beim Lesen der Anfrage", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := sr.Register(namespace, taskType, json.RawMessage(raw))
+	if err != nil {
+		writeValidationErrors(w, []FieldError{{Field: "schema", Message: err.Error()}})
+		return
+	}
+
+	schemaLogger.Infof("Schema für Task-Typ %s im Namespace %s registriert (Version %d)", taskType, namespace, schema.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema)
+}
+
+// writeValidationErrors sendet eine 422-Antwort mit feldgenauen Fehlern
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "Validierung fehlgeschlagen",
+		"fields": errs,
+	})
+}
+
+// compileSchema parst ein Schema und prüft, ob nur unterstützte Schlüsselwörter verwendet werden
+func compileSchema(raw []byte) (*JSONSchema, error) {
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, fmt.Errorf("ungültiges JSON: %v", err)
+	}
+	if err := checkSchemaKeywords("", generic); err != nil {
+		return nil, err
+	}
+
+	var schema JSONSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, err
+	}
+	if err := schema.prepare(""); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func checkSchemaKeywords(path string, node interface{}) error {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: Schema muss ein Objekt sein", schemaPath(path))
+	}
+
+	for key, value := range obj {
+		if !allowedSchemaKeywords[key] {
+			return fmt.Errorf("%s: nicht unterstütztes Schlüsselwort %q", schemaPath(path), key)
+		}
+
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s: \"properties\" muss ein Objekt sein", schemaPath(path))
+			}
+			for name, prop := range props {
+				if err := checkSchemaKeywords(path+"/properties/"+name, prop); err != nil {
+					return err
+				}
+			}
+		case "items":
+			if err := checkSchemaKeywords(path+"/items", value); err != nil {
+				return err
+			}
+		case "additionalProperties":
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("%s: \"additionalProperties\" muss true oder false sein", schemaPath(path))
+			}
+		}
+	}
+	return nil
+}
+
+func schemaPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// prepare prüft die Typen und kompiliert reguläre Ausdrücke
+func (s *JSONSchema) prepare(path string) error {
+	for _, t := range s.Type {
+		if !validSchemaTypes[t] {
+			return fmt.Errorf("%s: unbekannter Typ %q", schemaPath(path), t)
+		}
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: ungültiges Pattern: %v", schemaPath(path), err)
+		}
+		s.pattern = re
+	}
+
+	for name, prop := range s.Properties {
+		if err := prop.prepare(path + "/properties/" + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.prepare(path + "/items"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate prüft einen Wert gegen das Schema und sammelt alle Fehler
+func (s *JSONSchema) validate(field string, value interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Secret-Referenzen stehen für einen String, dessen Wert erst der Worker kennt;
+	// Länge und Muster lassen sich daher nicht prüfen
+	if name, ok := protocol.SecretRef(value); ok {
+		if err := protocol.ValidateSecretName(name); err != nil {
+			fail("%v", err)
+		} else if len(s.Type) > 0 && !matchesAnyType("", s.Type) {
+			fail("erwartet %s, erhalten Secret-Referenz", joinTypes(s.Type))
+		} else if len(s.Enum) > 0 {
+			fail("Secret-Referenz nicht erlaubt, Wert muss in %v enthalten sein", s.Enum)
+		}
+		return
+	}
+
+	if len(s.Type) > 0 && !matchesAnyType(value, s.Type) {
+		fail("erwartet %s, erhalten %s", joinTypes(s.Type), jsonTypeOf(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		fail("Wert ist nicht in %v enthalten", s.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, FieldError{Field: field + "." + name, Message: "Pflichtfeld fehlt"})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, FieldError{Field: field + "." + name, Message: "Feld ist nicht erlaubt"})
+				}
+				continue
+			}
+			prop.validate(field+"."+name, v[name], errs)
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("mindestens %d Elemente erwartet", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("höchstens %d Elemente erlaubt", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item, errs)
+			}
+		}
+
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("mindestens %d Zeichen erwartet", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("höchstens %d Zeichen erlaubt", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("entspricht nicht dem Muster %q", s.Pattern)
+		}
+
+	default:
+		if n, ok := toFloat(value); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				fail("Wert muss >= %v sein", *s.Minimum)
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				fail("Wert muss <= %v sein", *s.Maximum)
+			}
+		}
+	}
+}
+
+func matchesAnyType(value interface{}, types SchemaTypes) bool {
+	actual := jsonTypeOf(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		// Ganzzahlen sind auch gültige Zahlen
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func joinTypes(types SchemaTypes) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("%v", []string(types))
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if n, ok := toFloat(v); ok {
+			if n == math.Trunc(n) && !math.IsInf(n, 0) {
+				return "integer"
+			}
+			return "number"
+		}
+		return "unknown"
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// enumContains prüft, ob ein Wert einem der erlaubten Werte entspricht
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if jsonEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual vergleicht zwei dekodierte JSON-Werte; Zahlen werden nach ihrem Wert
+// verglichen, sodass 1, 1.0 und json.Number("1") gleich sind
+func jsonEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Strings, Wahrheitswerte und null; Listen und Objekte sind oben behandelt
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCompileSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "gültig", schema: `{"type": "object", "properties": {"n": {"type": "integer", "minimum": 1}}}`},
+		{name: "Typ als Liste", schema: `{"type": ["string", "null"]}`},
+		{name: "kein JSON", schema: `{`, wantErr: "ungültiges JSON"},
+		{name: "kein Objekt", schema: `[]`, wantErr: "Schema muss ein Objekt sein"},
+		{name: "unbekanntes Schlüsselwort", schema: `{"oneOf": []}`, wantErr: `nicht unterstütztes Schlüsselwort "oneOf"`},
+		{name: "unbekanntes Schlüsselwort in properties", schema: `{"properties": {"a": {"format": "email"}}}`, wantErr: "/properties/a"},
+		{name: "unbekanntes Schlüsselwort in items", schema: `{"items": {"const": 1}}`, wantErr: "/items"},
+		{name: "unbekannter Typ", schema: `{"type": "float"}`, wantErr: `unbekannter Typ "float"`},
+		{name: "ungültiges Pattern", schema: `{"pattern": "("}`, wantErr: "ungültiges Pattern"},
+		{name: "additionalProperties als Schema", schema: `{"additionalProperties": {}}`, wantErr: "true oder false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileSchema([]byte(tt.schema))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unerwarteter Fehler: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["operation"],
+		"additionalProperties": false,
+		"properties": {
+			"operation": {"type": "string", "minLength": 2, "maxLength": 5},
+			"url": {"type": "string", "pattern": "^https?://"},
+			"iterations": {"type": "integer", "minimum": 1, "maximum": 10},
+			"ratio": {"type": "number"},
+			"input": {"type": "array", "items": {"type": "number"}, "minItems": 1, "maxItems": 2},
+			"method": {"enum": ["GET", "POST"]},
+			"level": {"enum": [1, 2.5, null]},
+			"options": {"enum": [{"a": 1}, [1, "x"]]},
+			"note": {"type": ["string", "null"]}
+		}
+	}`
+
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "gültig", data: `{"operation": "sum", "iterations": 3, "input": [1, 2.5]}`},
+		{name: "Pflichtfeld fehlt", data: `{}`, want: []string{"data.operation: Pflichtfeld fehlt"}},
+		{name: "falscher Typ", data: `{"operation": 1}`, want: []string{"data.operation: erwartet string, erhalten integer"}},
+		{name: "zu kurz", data: `{"operation": "a"}`, want: []string{"data.operation: mindestens 2 Zeichen erwartet"}},
+		{name: "Länge in Zeichen statt Bytes", data: `{"operation": "äöüäö"}`},
+		{name: "zu lang", data: `{"operation": "abcdef"}`, want: []string{"data.operation: höchstens 5 Zeichen erlaubt"}},
+		{name: "Muster", data: `{"operation": "get", "url": "ftp://x"}`, want: []string{`data.url: entspricht nicht dem Muster "^https?://"`}},
+		{name: "Ganzzahl mit Nachkommastellen", data: `{"operation": "sum", "iterations": 1.5}`, want: []string{"data.iterations: erwartet integer, erhalten number"}},
+		{name: "Ganzzahl als 2.0", data: `{"operation": "sum", "iterations": 2.0}`},
+		{name: "Ganzzahl ist auch number", data: `{"operation": "sum", "ratio": 2}`},
+		{name: "Minimum", data: `{"operation": "sum", "iterations": 0}`, want: []string{"data.iterations: Wert muss >= 1 sein"}},
+		{name: "Maximum", data: `{"operation": "sum", "iterations": 11}`, want: []string{"data.iterations: Wert muss <= 10 sein"}},
+		{name: "zu wenige Elemente", data: `{"operation": "sum", "input": []}`, want: []string{"data.input: mindestens 1 Elemente erwartet"}},
+		{name: "zu viele Elemente", data: `{"operation": "sum", "input": [1, 2, 3]}`, want: []string{"data.input: höchstens 2 Elemente erlaubt"}},
+		{name: "Elementtyp", data: `{"operation": "sum", "input": [1, "x"]}`, want: []string{"data.input[1]: erwartet number, erhalten string"}},
+		{name: "Enum", data: `{"operation": "get", "method": "PUT"}`, want: []string{"data.method: Wert ist nicht in [GET POST] enthalten"}},
+		{name: "Enum mit 1.0 statt 1", data: `{"operation": "get", "level": 1.0}`},
+		{name: "Enum mit null", data: `{"operation": "get", "level": null}`},
+		{name: "Enum mit Objekt", data: `{"operation": "get", "options": {"a": 1.0}}`},
+		{name: "Enum mit Liste", data: `{"operation": "get", "options": [1, "x"]}`},
+		{name: "Enum mit abweichender Liste", data: `{"operation": "get", "options": ["x", 1]}`, want: []string{`data.options: Wert ist nicht in [map[a:1] [1 x]] enthalten`}},
+		{name: "Typliste", data: `{"operation": "get", "note": null}`},
+		{name: "zusätzliches Feld", data: `{"operation": "get", "extra": true}`, want: []string{"data.extra: Feld ist nicht erlaubt"}},
+		{name: "Secret-Referenz als String", data: `{"operation": "get", "url": {"$secret": "api-url"}}`},
+		{name: "Secret-Referenz als Zahl", data: `{"operation": "sum", "iterations": {"$secret": "n"}}`, want: []string{"data.iterations: erwartet integer, erhalten Secret-Referenz"}},
+		{name: "Secret-Referenz im Enum", data: `{"operation": "get", "method": {"$secret": "m"}}`, want: []string{"data.method: Secret-Referenz nicht erlaubt, Wert muss in [GET POST] enthalten sein"}},
+		{name: "mehrere Fehler sortiert", data: `{"operation": "", "iterations": "x"}`, want: []string{
+			"data.iterations: erwartet integer, erhalten string",
+			"data.operation: mindestens 2 Zeichen erwartet",
+		}},
+	}
+
+	sr := &SchemaRegistry{schemas: map[string]*TaskSchema{}}
+	compiled, err := compileSchema([]byte(schema))
+	if err != nil {
+		t.Fatalf("Schema ungültig: %v", err)
+	}
+	sr.schemas[schemaKey("default", "test")] = &TaskSchema{TaskType: "test", Namespace: "default", compiled: compiled}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("Testdaten ungültig: %v", err)
+			}
+			assertFieldErrors(t, sr.Validate("default", "test", data), tt.want)
+		})
+	}
+}
+
+func TestSchemaValidateTaskType(t *testing.T) {
+	sr := &SchemaRegistry{schemas: map[string]*TaskSchema{}}
+	assertFieldErrors(t, sr.Validate("default", "", nil), []string{"type: Task-Typ fehlt"})
+	assertFieldErrors(t, sr.Validate("default", "unknown", nil), []string{`type: unbekannter Task-Typ "unknown"`})
+}
+
+func TestEnumContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		enum  []interface{}
+		value interface{}
+		want  bool
+	}{
+		{name: "1 und 1.0", enum: []interface{}{float64(1)}, value: 1.0, want: true},
+		{name: "int und float64", enum: []interface{}{1}, value: float64(1), want: true},
+		{name: "json.Number", enum: []interface{}{float64(2.5)}, value: json.Number("2.50"), want: true},
+		{name: "Zahl und String", enum: []interface{}{"1"}, value: float64(1), want: false},
+		{name: "String", enum: []interface{}{"a", "b"}, value: "b", want: true},
+		{name: "Wahrheitswert", enum: []interface{}{true}, value: false, want: false},
+		{name: "null", enum: []interface{}{nil}, value: nil, want: true},
+		{name: "null und Objekt", enum: []interface{}{nil}, value: map[string]interface{}{}, want: false},
+		{name: "String und Liste", enum: []interface{}{"a"}, value: []interface{}{"a"}, want: false},
+		{name: "Objekt mit Zahlen", enum: []interface{}{map[string]interface{}{"n": float64(1)}}, value: map[string]interface{}{"n": json.Number("1.0")}, want: true},
+		{name: "Objekt mit anderem Schlüssel", enum: []interface{}{map[string]interface{}{"n": 1}}, value: map[string]interface{}{"m": 1}, want: false},
+		{name: "Liste in Reihenfolge", enum: []interface{}{[]interface{}{1, "x"}}, value: []interface{}{float64(1), "x"}, want: true},
+		{name: "Liste anderer Länge", enum: []interface{}{[]interface{}{1}}, value: []interface{}{1, 1}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enumContains(tt.enum, tt.value); got != tt.want {
+				t.Fatalf("enumContains(%v, %v) = %v, erwartet %v", tt.enum, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTaskSchemas(t *testing.T) {
+	for taskType, raw := range defaultTaskSchemas {
+		if _, err := compileSchema([]byte(raw)); err != nil {
+			t.Errorf("Standard-Schema für %s ist ungültig: %v", taskType, err)
+		}
+	}
+}
+
+// assertFieldErrors vergleicht Fehler in der Form "<feld>: <meldung>"
+func assertFieldErrors(t *testing.T, errs []FieldError, want []string) {
+	t.Helper()
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Field + ": " + e.Message
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Fehler = %q, erwartet %q", got, want)
+	}
+}
+
+func TestSchemaRegistryLoad(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	// Mehr Schemas als ein SCAN-Aufruf liefert, dazu ein ungültiges und ein kaputtes
+	const stored = 150
+	for i := 0; i < stored; i++ {
+		mr.Set(fmt.Sprintf("%stype-%d", schemaKeyPrefix, i),
+			fmt.Sprintf(`{"task_type":"type-%d","version":2,"schema":{"type":"object","required":["n"]}}`, i))
+	}
+	mr.Set(schemaKeyPrefix+"invalid", `{"task_type":"invalid","version":1,"schema":{"type":"banana"}}`)
+	mr.Set(schemaKeyPrefix+"broken", `{`)
+
+	sr := NewSchemaRegistry(client)
+	for i := 0; i < stored; i++ {
+		schema, ok := sr.Get("default", fmt.Sprintf("type-%d", i))
+		if !ok || schema.Version != 2 || schema.compiled == nil {
+			t.Fatalf("Schema type-%d nicht geladen: %+v", i, schema)
+		}
+	}
+	if _, ok := sr.Get("default", "invalid"); ok {
+		t.Fatal("ungültiges Schema geladen")
+	}
+	for taskType := range defaultTaskSchemas {
+		if _, ok := sr.Get("default", taskType); !ok {
+			t.Fatalf("Standard-Schema %s fehlt", taskType)
+		}
+	}
+}
+
+func TestSchemaKeyNamespace(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"schema:computation", "default", true},
+		{"ns:team-a:schema:computation", "team-a", true},
+		{"ns:team-a:task:schema:x", "", false},
+		{"ns:team-a", "", false},
+		{"task:schema:x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := schemaKeyNamespace(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("schemaKeyNamespace(%s) = %q, %v, erwartet %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSchemaRegistryNamespaces(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	sr := NewSchemaRegistry(client)
+	strict := `{"type": "object", "required": ["operation", "iterations"]}`
+	schema, err := sr.Register("team-a", "computation", json.RawMessage(strict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Version != 2 || schema.Namespace != "team-a" {
+		t.Fatalf("Schema %+v, erwartet Version 2 in team-a", schema)
+	}
+	if !mr.Exists("ns:team-a:schema:computation") || mr.Exists("schema:computation") {
+		t.Fatalf("Schlüssel in Redis: %v", mr.Keys())
+	}
+
+	data := map[string]interface{}{"operation": "sum"}
+	for _, registry := range []*SchemaRegistry{sr, NewSchemaRegistry(client)} {
+		// team-a verwendet sein eigenes Schema, alle anderen das Standard-Schema
+		assertFieldErrors(t, registry.Validate("team-a", "computation", data), []string{"data.iterations: Pflichtfeld fehlt"})
+		assertFieldErrors(t, registry.Validate("default", "computation", data), nil)
+		assertFieldErrors(t, registry.Validate("team-b", "computation", data), nil)
+		if _, ok := registry.Get("team-a", "io"); !ok {
+			t.Fatal("Standard-Schema io fehlt in team-a")
+		}
+	}
+
+	for _, tt := range []struct {
+		namespace, want string
+	}{
+		{"team-a", "team-a"},
+		{"team-b", "default"},
+	} {
+		schemas := sr.List(tt.namespace)
+		if len(schemas) != len(defaultTaskSchemas) {
+			t.Fatalf("%s: %d Schemas, erwartet %d", tt.namespace, len(schemas), len(defaultTaskSchemas))
+		}
+		for _, schema := range schemas {
+			if schema.TaskType == "computation" && schema.Namespace != tt.want {
+				t.Fatalf("%s: Schema computation aus %s, erwartet %s", tt.namespace, schema.Namespace, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,714 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fehler des Service-Layers; REST- und gRPC-Handler übersetzen sie in ihre Statuscodes
+var (
+	ErrTaskNotFound   = errors.New("Task nicht gefunden")
+	ErrWorkerNotFound = errors.New("Worker nicht gefunden")
+	ErrInvalidState   = errors.New("Operation im aktuellen Task-Status nicht möglich")
+)
+
+// ValidationFailedError enthält die feldgenauen Fehler einer abgelehnten Task-Erstellung
+type ValidationFailedError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationFailedError) Error() string {
+	return fmt.Sprintf("Validierung fehlgeschlagen (%d Fehler)", len(e.Fields))
+}
+
+// CreateTaskRequest enthält die Parameter für einen neuen Task
+type CreateTaskRequest struct {
+	Type     string                 `json:"type"`
+	Priority int                    `json:"priority"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// controlKeyPrefix ist der Redis-Präfix, über den Worker Steuerbefehle für einen Task erhalten
+const controlKeyPrefix = "control:"
+
+// terminalTaskStates sind Status, aus denen ein Task nicht mehr verändert werden kann
+var terminalTaskStates = map[string]bool{
+	"COMPLETED": true,
+	"FAILED":    true,
+	"CANCELLED": true,
+}
+
+// TaskService bündelt die Task-Operationen, die von allen Schnittstellen
+// (REST, gRPC) gemeinsam genutzt werden, damit sie dieselbe Semantik haben
+type TaskService struct {
+	tm         *TaskManager
+	schemas    *SchemaRegistry
+	namespaces *NamespaceRegistry
+	quotas     *Quotas
+	encryption *Encryption
+	metrics    *Metrics
+	status     *SystemStatus
+}
+
+// NewTaskService erstellt einen neuen TaskService
+func NewTaskService(tm *TaskManager, schemas *SchemaRegistry, namespaces *NamespaceRegistry, quotas *Quotas, encryption *Encryption, metrics *Metrics, status *SystemStatus) *TaskService {
+	return &TaskService{
+		tm:         tm,
+		schemas:    schemas,
+		namespaces: namespaces,
+		quotas:     quotas,
+		encryption: encryption,
+		metrics:    metrics,
+		status:     status,
+	}
+}
+
+// CreateTask validiert, speichert und verteilt einen neuen Task im Namespace des Kontexts.
+// Der Task wird auf die Limits des Aufrufers angerechnet.
+func (ts *TaskService) CreateTask(ctx context.Context, req CreateTaskRequest) (task *Task, err error) {
+	ctx, span := tracer.Start(ctx, "task.create", trace.WithAttributes(attribute.String("task.type", req.Type)))
+	defer func() { endSpan(span, err) }()
+
+	namespace := namespaceFromContext(ctx)
+	if errs := ts.schemas.Validate(namespace, req.Type, req.Data); len(errs) > 0 {
+		return nil, &ValidationFailedError{Fields: errs}
+	}
+
+	taskID := uuid.New().String()
+	client := clientIdentity(ctx)
+	if err := ts.quotas.Admit(ctx, client, taskID); err != nil {
+		var quotaErr *QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			serviceLogger.Ctx(ctx).Task(taskID).Event("task_rejected").Field("client", client).
+				Warnf("Task von %s abgelehnt: %s", client, quotaErr.Reason)
+		}
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			ts.quotas.Release(ctx, client, taskID)
+		}
+	}()
+
+	now := TimeJSON(time.Now())
+	task = &Task{
+		ID:        taskID,
+		Type:      req.Type,
+		Status:    "CREATED",
+		Priority:  req.Priority,
+		Data:      req.Data,
+		Progress:  0,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	span.SetAttributes(taskAttributes(task, namespace)...)
+	if err := ts.namespaces.SetTask(ctx, task.ID, namespace); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			// Nicht verteilte Tasks verwerfen, damit weder der Task noch seine Zuordnung verwaist
+			if delErr := ts.deleteTask(ctx, taskID); delErr != nil {
+				serviceLogger.Ctx(ctx).Task(taskID).Errorf("Fehler beim Verwerfen von Task %s: %v", taskID, delErr)
+			}
+		}
+	}()
+	if err := ts.saveTask(ctx, task); err != nil {
+		return nil, err
+	}
+
+	if err := ts.publish(ctx, protocol.TypeTaskCreated, task.ID, ts.protocolTask(task)); err != nil {
+		return nil, err
+	}
+
+	serviceLogger.Ctx(ctx).Task(task.ID).Event("task_created").Field("namespace", namespace).
+		Infof("Task %s erstellt (Typ: %s, Priorität: %d, Namespace: %s)", task.ID, task.Type, task.Priority, namespace)
+	ts.metrics.TaskCreated(task)
+	ts.tm.wsHandler.BroadcastTaskUpdate(task)
+	return task, nil
+}
+
+// GetTask liefert einen Task aus dem Speicher des Task-Managers oder aus Redis.
+// Tasks anderer Namespaces als dem des Kontexts gelten als nicht vorhanden.
+func (ts *TaskService) GetTask(ctx context.Context, id string) (*Task, error) {
+	if ts.namespaces.TaskNamespace(id) != namespaceFromContext(ctx) {
+		return nil, ErrTaskNotFound
+	}
+
+	ts.tm.taskMutex.RLock()
+	task, ok := ts.tm.tasks[id]
+	var taskCopy Task
+	if ok {
+		taskCopy = *task
+	}
+	ts.tm.taskMutex.RUnlock()
+	if ok {
+		ts.encryption.openTask(&taskCopy)
+		return &taskCopy, nil
+	}
+
+	taskJSON, err := ts.tm.redisClient.Get(ctx, ts.taskKey(id, "task:")).Result()
+	if err == redis.Nil {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Fehler beim Laden des Tasks aus Redis: %w", err)
+	}
+
+	var stored Task
+	if err := json.Unmarshal([]byte(taskJSON), &stored); err != nil {
+		return nil, fmt.Errorf("Fehler beim Deserialisieren des Tasks: %w", err)
+	}
+	ts.encryption.openTask(&stored)
+	return &stored, nil
+}
+
+// CancelTask bricht einen Task ab. Der ausführende Worker erkennt den Abbruch
+// über den Steuerschlüssel in Redis vor dem nächsten Verarbeitungsschritt.
+func (ts *TaskService) CancelTask(ctx context.Context, id string) (*Task, error) {
+	task, err := ts.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if terminalTaskStates[task.Status] {
+		return nil, fmt.Errorf("%w: Task ist bereits %s", ErrInvalidState, task.Status)
+	}
+
+	if err := ts.tm.redisClient.Set(ctx, ts.taskKey(id, controlKeyPrefix), "cancel", 24*time.Hour).Err(); err != nil {
+		return nil, fmt.Errorf("Fehler beim Setzen des Steuerbefehls: %w", err)
+	}
+
+	task.Status = "CANCELLED"
+	task.UpdatedAt = TimeJSON(time.Now())
+	if err := ts.saveTask(ctx, task); err != nil {
+		return nil, err
+	}
+
+	serviceLogger.Ctx(ctx).Task(id).Event("task_cancelled").Infof("Task %s abgebrochen", id)
+	ts.tm.wsHandler.BroadcastTaskUpdate(task)
+	return task, nil
+}
+
+// PauseTask fordert den ausführenden Worker auf, einen Checkpoint zu speichern und
+// den Task freizugeben. Den Status PAUSED meldet der Worker selbst, sobald er angehalten
+// hat; bis dahin bleibt der Task im aktuellen Status.
+func (ts *TaskService) PauseTask(ctx context.Context, id string) (*Task, error) {
+	task, err := ts.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if terminalTaskStates[task.Status] || task.Status == "PAUSED" {
+		return nil, fmt.Errorf("%w: Task ist bereits %s", ErrInvalidState, task.Status)
+	}
+
+	if err := ts.tm.redisClient.Set(ctx, ts.taskKey(id, controlKeyPrefix), "pause", 24*time.Hour).Err(); err != nil {
+		return nil, fmt.Errorf("Fehler beim Setzen des Steuerbefehls: %w", err)
+	}
+
+	serviceLogger.Ctx(ctx).Task(id).Event("task_pause_requested").Infof("Pause für Task %s angefordert", id)
+	return task, nil
+}
+
+// ResumeTask stellt einen pausierten Task wieder in die Warteschlange. Ein beliebiger
+// Worker übernimmt ihn und setzt ab dem beim Pausieren gespeicherten Checkpoint fort.
+func (ts *TaskService) ResumeTask(ctx context.Context, id string) (*Task, error) {
+	task, err := ts.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != "PAUSED" {
+		return nil, fmt.Errorf("%w: Task ist %s, nicht PAUSED", ErrInvalidState, task.Status)
+	}
+
+	if err := ts.tm.redisClient.Del(ctx, ts.taskKey(id, controlKeyPrefix)).Err(); err != nil {
+		return nil, fmt.Errorf("Fehler beim Löschen des Steuerbefehls: %w", err)
+	}
+
+	// Wie bei der Wiederherstellung nach einem Worker-Ausfall ab dem Checkpoint fortsetzen
+	if err := ts.requeue(ctx, task); err != nil {
+		return nil, err
+	}
+
+	serviceLogger.Ctx(ctx).Task(id).Event("task_resumed").Infof("Task %s wird ab Fortschritt %d%% fortgesetzt", id, task.Progress)
+	return task, nil
+}
+
+// RecoverWorkerTasks stellt alle unvollendeten Tasks eines ausgefallenen Workers wieder
+// in die Warteschlange; ein anderer Worker setzt sie ab ihrem Checkpoint fort. Pausierte
+// Tasks bleiben pausiert, bis sie fortgesetzt werden.
+func (ts *TaskService) RecoverWorkerTasks(ctx context.Context, workerID string) {
+	var orphaned []*Task
+	ts.tm.taskMutex.RLock()
+	for _, task := range ts.tm.tasks {
+		if task.WorkerID == workerID && !terminalTaskStates[task.Status] && task.Status != "PAUSED" {
+			taskCopy := *task
+			orphaned = append(orphaned, &taskCopy)
+		}
+	}
+	ts.tm.taskMutex.RUnlock()
+
+	for _, task := range orphaned {
+		if err := ts.requeue(ctx, task); err != nil {
+			serviceLogger.Ctx(ctx).Task(task.ID).Worker(workerID).Errorf("Fehler beim Wiederherstellen von Task %s: %v", task.ID, err)
+			continue
+		}
+		serviceLogger.Ctx(ctx).Task(task.ID).Worker(workerID).Event("task_recovery_started").
+			Infof("Task %s von ausgefallenem Worker %s wird ab Fortschritt %d%% wiederhergestellt", task.ID, workerID, task.Progress)
+		ts.tm.wsHandler.BroadcastMessage(string(protocol.TypeTaskRecovery), map[string]interface{}{
+			"taskId":   task.ID,
+			"workerId": workerID,
+			"content": map[string]interface{}{
+				"message":  fmt.Sprintf("Task %s wird nach Ausfall von Worker %s wiederhergestellt", task.ID, workerID),
+				"progress": task.Progress,
+			},
+		})
+	}
+}
+
+// requeue setzt einen Task auf RECOVERING und sendet ihn als task_recovery an die
+// Worker seines Namespace, die ihn ab dem gespeicherten Checkpoint fortsetzen
+func (ts *TaskService) requeue(ctx context.Context, task *Task) error {
+	task.Status = "RECOVERING"
+	task.WorkerID = ""
+	task.UpdatedAt = TimeJSON(time.Now())
+	if err := ts.saveTask(ctx, task); err != nil {
+		return err
+	}
+
+	if err := ts.publish(ctx, protocol.TypeTaskRecovery, task.ID, ts.protocolTask(task)); err != nil {
+		return err
+	}
+
+	ts.tm.wsHandler.BroadcastTaskUpdate(task)
+	return nil
+}
+
+// MigrateTask verschiebt einen laufenden Task auf den angegebenen Worker, der
+// Tasks aus dem Namespace des Tasks annehmen muss
+func (ts *TaskService) MigrateTask(ctx context.Context, id, targetWorkerID string) (*Task, error) {
+	task, err := ts.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if terminalTaskStates[task.Status] {
+		return nil, fmt.Errorf("%w: Task ist bereits %s", ErrInvalidState, task.Status)
+	}
+
+	ts.tm.workerMutex.Lock()
+	_, exists := ts.tm.workerStatus[targetWorkerID]
+	ts.tm.workerMutex.Unlock()
+	namespace := ts.namespaces.TaskNamespace(id)
+	if !exists || !ts.namespaces.WorkerServes(targetWorkerID, namespace) {
+		return nil, ErrWorkerNotFound
+	}
+	if task.WorkerID == targetWorkerID {
+		return nil, fmt.Errorf("%w: Task läuft bereits auf %s", ErrInvalidState, targetWorkerID)
+	}
+
+	fromWorker := task.WorkerID
+	task.Status = "MIGRATING"
+	task.UpdatedAt = TimeJSON(time.Now())
+	if err := ts.saveTask(ctx, task); err != nil {
+		return nil, err
+	}
+
+	migration := &protocol.Migration{
+		TaskID:         id,
+		FromWorker:     fromWorker,
+		ToWorker:       targetWorkerID,
+		TargetWorkerID: targetWorkerID,
+		Namespace:      namespace,
+	}
+	if err := ts.publish(ctx, protocol.TypeTaskMigration, id, migration); err != nil {
+		return nil, err
+	}
+
+	serviceLogger.Ctx(ctx).Task(id).Worker(targetWorkerID).Event("task_migration_started").
+		Infof("Migration von Task %s von %s zu %s eingeleitet", id, fromWorker, targetWorkerID)
+	ts.metrics.TaskMigrated(id)
+	ts.tm.wsHandler.BroadcastTaskUpdate(task)
+	ts.tm.wsHandler.BroadcastMessage(string(protocol.TypeTaskMigration), migration)
+	return task, nil
+}
+
+// ApplyTaskStatus übernimmt ein task_status-Update eines Workers in den Speicher des
+// Task-Managers und verteilt es an die Clients. Liefert false, wenn das Update verworfen
+// wurde, weil der Task bereits einen Endstatus hat oder pausiert ist (siehe acceptTaskStatus).
+func (ts *TaskService) ApplyTaskStatus(ctx context.Context, update *Task) bool {
+	ts.tm.taskMutex.Lock()
+	current, ok := ts.tm.tasks[update.ID]
+	if ok && !acceptTaskStatus(current.Status, update.Status) {
+		kept := *current
+		ts.tm.taskMutex.Unlock()
+
+		// Der Worker hat das Update bereits in Redis geschrieben; dort gilt wieder der bisherige Status
+		if err := ts.saveTask(ctx, &kept); err != nil {
+			serviceLogger.Ctx(ctx).Task(update.ID).Errorf("Fehler beim Wiederherstellen von Task %s: %v", update.ID, err)
+		}
+		serviceLogger.Ctx(ctx).Task(update.ID).Debugf("Task %s: Status-Update %s nach %s verworfen", update.ID, update.Status, kept.Status)
+		return false
+	}
+	taskCopy := *update
+	ts.tm.tasks[update.ID] = &taskCopy
+	ts.tm.taskMutex.Unlock()
+
+	ts.tm.wsHandler.BroadcastTaskUpdate(update)
+	return true
+}
+
+// acceptTaskStatus gibt an, ob ein Worker den Status current durch update ersetzen darf.
+// Endstatus bleiben bestehen; so setzt ein Update aus einem Schritt, der vor dem Abbruch
+// begonnen hat, den Task nicht wieder auf RUNNING. Einzige Ausnahme ist CANCELLED, mit
+// dem der Worker einen Abbruch des Task-Managers bestätigt. Ebenso bleibt ein pausierter
+// Task pausiert, bis ResumeTask ihn wieder in die Warteschlange stellt.
+func acceptTaskStatus(current, update string) bool {
+	switch {
+	case current == "PAUSED":
+		return update == "PAUSED"
+	case terminalTaskStates[current]:
+		return current == "CANCELLED" && update == "CANCELLED"
+	}
+	return true
+}
+
+// ListTasks liefert Kopien aller Tasks eines Namespace mit entschlüsselten Feldern
+func (ts *TaskService) ListTasks(namespace string) []*Task {
+	tasks := []*Task{}
+	ts.tm.taskMutex.RLock()
+	for id, task := range ts.tm.tasks {
+		if ts.namespaces.TaskNamespace(id) != namespace {
+			continue
+		}
+		taskCopy := *task
+		tasks = append(tasks, &taskCopy)
+	}
+	ts.tm.taskMutex.RUnlock()
+	for _, task := range tasks {
+		ts.encryption.openTask(task)
+	}
+	return tasks
+}
+
+// ListWorkers liefert Kopien aller Worker, die Tasks eines Namespace annehmen
+func (ts *TaskService) ListWorkers(namespace string) []*Worker {
+	workers := []*Worker{}
+	ts.tm.workerMutex.Lock()
+	for id, worker := range ts.tm.workerStatus {
+		if !ts.namespaces.WorkerServes(id, namespace) {
+			continue
+		}
+		workerCopy := *worker
+		workers = append(workers, &workerCopy)
+	}
+	ts.tm.workerMutex.Unlock()
+	return workers
+}
+
+// GetWorker liefert eine Kopie eines Workers; Worker, die keine Tasks im Namespace
+// des Kontexts annehmen, gelten als nicht vorhanden
+func (ts *TaskService) GetWorker(ctx context.Context, id string) (*Worker, error) {
+	if !ts.namespaces.WorkerServes(id, namespaceFromContext(ctx)) {
+		return nil, ErrWorkerNotFound
+	}
+
+	ts.tm.workerMutex.Lock()
+	defer ts.tm.workerMutex.Unlock()
+	worker, ok := ts.tm.workerStatus[id]
+	if !ok {
+		return nil, ErrWorkerNotFound
+	}
+	workerCopy := *worker
+	return &workerCopy, nil
+}
+
+// Snapshot liefert Kopien aller Tasks und Worker eines Namespace für neue WebSocket-Verbindungen
+func (ts *TaskService) Snapshot(namespace string) StateSnapshot {
+	return StateSnapshot{Tasks: ts.ListTasks(namespace), Workers: ts.ListWorkers(namespace)}
+}
+
+// saveTask speichert einen Task im Speicher des Task-Managers und in Redis;
+// in Redis sind Data und CheckpointData verschlüsselt, sofern ein Schlüsselbund geladen ist
+func (ts *TaskService) saveTask(ctx context.Context, task *Task) error {
+	sealed, err := ts.encryption.sealTask(task)
+	if err != nil {
+		return err
+	}
+	taskJSON, err := json.Marshal(sealed)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Serialisieren des Tasks: %w", err)
+	}
+	if err := ts.tm.redisClient.Set(ctx, ts.taskKey(task.ID, "task:"), taskJSON, 0).Err(); err != nil {
+		return fmt.Errorf("Fehler beim Speichern des Tasks in Redis: %w", err)
+	}
+
+	ts.tm.taskMutex.Lock()
+	taskCopy := *task
+	ts.tm.tasks[task.ID] = &taskCopy
+	ts.tm.taskMutex.Unlock()
+	return nil
+}
+
+// deleteTask entfernt einen Task aus Redis, aus dem Speicher des Task-Managers und
+// aus der Namespace-Zuordnung
+func (ts *TaskService) deleteTask(ctx context.Context, id string) error {
+	if err := ts.tm.redisClient.Del(ctx, ts.taskKey(id, "task:")).Err(); err != nil {
+		return fmt.Errorf("Fehler beim Löschen des Tasks aus Redis: %w", err)
+	}
+	ts.tm.taskMutex.Lock()
+	delete(ts.tm.tasks, id)
+	ts.tm.taskMutex.Unlock()
+	return ts.namespaces.RemoveTask(ctx, id)
+}
+
+// taskOpen prüft, ob ein Task noch nicht beendet ist; unbekannte Tasks gelten als beendet
+func (ts *TaskService) taskOpen(ctx context.Context, id string) bool {
+	ts.tm.taskMutex.RLock()
+	task, ok := ts.tm.tasks[id]
+	var status string
+	if ok {
+		status = task.Status
+	}
+	ts.tm.taskMutex.RUnlock()
+	if ok {
+		return !terminalTaskStates[status]
+	}
+
+	taskJSON, err := ts.tm.redisClient.Get(ctx, ts.taskKey(id, "task:")).Result()
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		// Im Zweifel weiter zählen, damit ein Redis-Fehler das Limit nicht aufhebt
+		return true
+	}
+	var stored Task
+	if err := json.Unmarshal([]byte(taskJSON), &stored); err != nil {
+		return false
+	}
+	return !terminalTaskStates[stored.Status]
+}
+
+// taskKey liefert den Redis-Schlüssel prefix+id im Namespace des Tasks
+func (ts *TaskService) taskKey(id, prefix string) string {
+	return protocol.NamespaceKey(ts.namespaces.TaskNamespace(id), prefix+id)
+}
+
+// publish sendet eine Nachricht an die Worker über die Warteschlange des Namespace des Tasks
+func (ts *TaskService) publish(ctx context.Context, messageType protocol.Type, taskID string, content interface{}) (err error) {
+	queue := protocol.DispatchQueue(ts.namespaces.TaskNamespace(taskID))
+	ctx, span := tracer.Start(ctx, queue+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationNameKey.String(queue),
+			attribute.String("messaging.message.type", string(messageType)),
+			attribute.String("task.id", taskID),
+		))
+	defer func() { endSpan(span, err) }()
+
+	msgJSON, err := protocol.Encode(messageType, taskID, "", content)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Serialisieren der Nachricht: %w", err)
+	}
+
+	// Der Worker setzt den Trace über die Header fort
+	err = ts.tm.publisher.Publish(
+		"",    // Exchange
+		queue, // Routing-Schlüssel
+		false, // Mandatory
+		false, // Immediate
+		amqp.Publishing{
+			Headers:      traceHeaders(ctx),
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msgJSON,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Fehler beim Senden der Nachricht %s: %w", messageType, err)
+	}
+	return nil
+}
+
+// toProtocolTask überträgt einen Task in das Nachrichtenformat des Brokers
+func toProtocolTask(task *Task) protocol.Task {
+	return protocol.Task{
+		ID:             task.ID,
+		Type:           task.Type,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		Data:           task.Data,
+		Progress:       task.Progress,
+		WorkerID:       task.WorkerID,
+		CreatedAt:      time.Time(task.CreatedAt),
+		UpdatedAt:      time.Time(task.UpdatedAt),
+		CheckpointData: task.CheckpointData,
+	}
+}
+
+// fromProtocolTask übernimmt einen Task aus dem Nachrichtenformat des Brokers
+func fromProtocolTask(task *protocol.Task) *Task {
+	return &Task{
+		ID:             task.ID,
+		Type:           task.Type,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		Data:           task.Data,
+		Progress:       task.Progress,
+		WorkerID:       task.WorkerID,
+		CreatedAt:      TimeJSON(task.CreatedAt),
+		UpdatedAt:      TimeJSON(task.UpdatedAt),
+		CheckpointData: task.CheckpointData,
+	}
+}
+
+// protocolTask überträgt einen Task samt seinem Namespace in das Nachrichtenformat des Brokers
+func (ts *TaskService) protocolTask(task *Task) protocol.Task {
+	msg := toProtocolTask(task)
+	msg.Namespace = ts.namespaces.TaskNamespace(task.ID)
+	return msg
+}
+
+// HandleListTasks ist der HTTP-Handler für GET /api/tasks
+func (ts *TaskService) HandleListTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ts.ListTasks(namespaceFromContext(r.Context())))
+}
+
+// HandleGetTask ist der HTTP-Handler für GET /api/tasks/{id}
+func (ts *TaskService) HandleGetTask(w http.ResponseWriter, r *http.Request) {
+	task, err := ts.GetTask(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// HandleCreateTask ist der HTTP-Handler für POST /api/tasks
+func (ts *TaskService) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeValidationErrors(w, []FieldError{{Field: "", Message: "ungültiges JSON: " + err.Error()}})
+		return
+	}
+
+	task, err := ts.CreateTask(withClientAddr(r.Context(), r.RemoteAddr), req)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// HandleMigrateTask ist der HTTP-Handler für POST /api/tasks/{id}/migrate
+func (ts *TaskService) HandleMigrateTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkerID string `json:"worker_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.WorkerID == "" {
+		http.Error(w, "worker_id fehlt", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := ts.MigrateTask(r.Context(), mux.Vars(r)["id"], req.WorkerID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// HandleListWorkers ist der HTTP-Handler für GET /api/workers
+func (ts *TaskService) HandleListWorkers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ts.ListWorkers(namespaceFromContext(r.Context())))
+}
+
+// HandleGetWorker ist der HTTP-Handler für GET /api/workers/{id}
+func (ts *TaskService) HandleGetWorker(w http.ResponseWriter, r *http.Request) {
+	worker, err := ts.GetWorker(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(worker)
+}
+
+// HandleCancelTask ist der HTTP-Handler für POST /api/tasks/{id}/cancel
+func (ts *TaskService) HandleCancelTask(w http.ResponseWriter, r *http.Request) {
+	task, err := ts.CancelTask(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// HandlePauseTask ist der HTTP-Handler für POST /api/tasks/{id}/pause
+func (ts *TaskService) HandlePauseTask(w http.ResponseWriter, r *http.Request) {
+	task, err := ts.PauseTask(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// 202: der Worker hält den Task erst vor seinem nächsten Schritt an
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(task)
+}
+
+// HandleResumeTask ist der HTTP-Handler für POST /api/tasks/{id}/resume
+func (ts *TaskService) HandleResumeTask(w http.ResponseWriter, r *http.Request) {
+	task, err := ts.ResumeTask(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// writeServiceError übersetzt Fehler des Service-Layers in HTTP-Statuscodes
+func writeServiceError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationFailedError
+	var quotaErr *QuotaExceededError
+	switch {
+	case errors.As(err, &validationErr):
+		writeValidationErrors(w, validationErr.Fields)
+	case errors.As(err, &quotaErr):
+		writeQuotaExceeded(w, quotaErr)
+	case errors.Is(err, ErrTaskNotFound), errors.Is(err, ErrWorkerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		serviceLogger.Errorf("Interner Fehler: %v", err)
+		http.Error(w, "Interner Fehler", http.StatusInternalServerError)
+	}
+}
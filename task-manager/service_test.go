@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// recordingPublisher hält gesendete Nachrichten fest, statt sie an RabbitMQ zu senden
+type recordingPublisher struct {
+	mutex    sync.Mutex
+	queues   []string
+	messages []*protocol.Envelope
+	// err ist der Fehler, mit dem Publish scheitert
+	err error
+}
+
+func (p *recordingPublisher) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if p.err != nil {
+		return p.err
+	}
+	env, err := protocol.Decode(msg.Body)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.queues = append(p.queues, key)
+	p.messages = append(p.messages, env)
+	return nil
+}
+
+// newTestTaskService erstellt einen TaskService mit Redis im Speicher und ohne Broker
+func newTestTaskService(t *testing.T) (*TaskService, *miniredis.Miniredis, *recordingPublisher) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	pub := &recordingPublisher{}
+	tm := &TaskManager{
+		publisher:    pub,
+		redisClient:  client,
+		tasks:        map[string]*Task{},
+		workerStatus: map[string]*Worker{},
+		wsHandler:    newTestWSHandler(16, 16),
+	}
+	ts := NewTaskService(tm, nil, NewNamespaceRegistry(client), nil, &Encryption{}, nil, nil)
+	tm.service = ts
+	return ts, mr, pub
+}
+
+// deliverTaskStatus stellt dem Consumer des Task-Managers ein task_status-Update zu
+func deliverTaskStatus(t *testing.T, tm *TaskManager, workerID string, task protocol.Task) {
+	t.Helper()
+	body, err := protocol.Encode(protocol.TypeTaskStatus, task.ID, workerID, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.handleDelivery(amqp.Delivery{RoutingKey: string(protocol.TypeTaskStatus), Body: body}, tm.handleTaskStatus)
+}
+
+func TestAcceptTaskStatus(t *testing.T) {
+	tests := []struct {
+		current, update string
+		want            bool
+	}{
+		{"PENDING", "RUNNING", true},
+		{"RUNNING", "RUNNING", true},
+		{"RUNNING", "COMPLETED", true},
+		{"RUNNING", "PAUSED", true},
+		// Pausierte Tasks bleiben pausiert, bis ResumeTask sie neu einstellt
+		{"PAUSED", "PAUSED", true},
+		{"PAUSED", "RUNNING", false},
+		{"PAUSED", "COMPLETED", false},
+		{"MIGRATING", "RUNNING", true},
+		{"RECOVERING", "FAILED", true},
+		// Bestätigung des Abbruchs durch den Worker
+		{"CANCELLED", "CANCELLED", true},
+		{"CANCELLED", "RUNNING", false},
+		{"CANCELLED", "COMPLETED", false},
+		{"CANCELLED", "PAUSED", false},
+		{"COMPLETED", "RUNNING", false},
+		{"COMPLETED", "CANCELLED", false},
+		{"FAILED", "RUNNING", false},
+		{"FAILED", "FAILED", false},
+	}
+	for _, tt := range tests {
+		if got := acceptTaskStatus(tt.current, tt.update); got != tt.want {
+			t.Errorf("acceptTaskStatus(%s, %s) = %v, erwartet %v", tt.current, tt.update, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTaskStatusAfterCancel(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	tm := &TaskManager{
+		redisClient: client,
+		tasks:       map[string]*Task{"task-1": {ID: "task-1", Status: "CANCELLED", Progress: 30}},
+		wsHandler:   newTestWSHandler(16, 16),
+	}
+	ts := NewTaskService(tm, nil, NewNamespaceRegistry(client), nil, &Encryption{}, nil, nil)
+	events, _ := tm.wsHandler.Subscribe(16)
+
+	// Der Worker hat RUNNING bereits in Redis geschrieben, bevor der Abbruch ankam
+	mr.Set("task:task-1", `{"id":"task-1","status":"RUNNING","progress":40}`)
+	if ts.ApplyTaskStatus(context.Background(), &Task{ID: "task-1", Status: "RUNNING", Progress: 40}) {
+		t.Fatal("Update RUNNING nach CANCELLED übernommen")
+	}
+	if got := tm.tasks["task-1"]; got.Status != "CANCELLED" || got.Progress != 30 {
+		t.Fatalf("Task im Speicher: %s bei %d%%", got.Status, got.Progress)
+	}
+	var stored Task
+	raw, _ := mr.Get("task:task-1")
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil || stored.Status != "CANCELLED" {
+		t.Fatalf("Task in Redis: %s (%v)", raw, err)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("verworfenes Update verteilt: %+v", event)
+	default:
+	}
+
+	// Die Bestätigung des Workers wird übernommen und verteilt
+	if !ts.ApplyTaskStatus(context.Background(), &Task{ID: "task-1", Status: "CANCELLED", Progress: 40}) {
+		t.Fatal("Bestätigung CANCELLED verworfen")
+	}
+	if got := tm.tasks["task-1"]; got.Progress != 40 {
+		t.Fatalf("Fortschritt nach Bestätigung %d%%, erwartet 40%%", got.Progress)
+	}
+	if event := <-events; event.Type != "task_update" || event.TaskID != "task-1" {
+		t.Fatalf("Ereignis %+v, erwartet task_update für task-1", event)
+	}
+
+	// Unbekannte und laufende Tasks übernehmen jedes Update
+	if !ts.ApplyTaskStatus(context.Background(), &Task{ID: "task-2", Status: "RUNNING"}) {
+		t.Fatal("Update für unbekannten Task verworfen")
+	}
+}
+
+func TestHandleTaskStatus(t *testing.T) {
+	ts, mr, _ := newTestTaskService(t)
+	tm := ts.tm
+
+	tests := []struct {
+		name       string
+		update     protocol.Task
+		wantStatus string
+		wantProg   int
+	}{
+		{"Start", protocol.Task{ID: "task-1", Status: "RUNNING", WorkerID: "worker-1", Progress: 10}, "RUNNING", 10},
+		{"Fortschritt", protocol.Task{ID: "task-1", Status: "RUNNING", WorkerID: "worker-1", Progress: 40}, "RUNNING", 40},
+		{"Pause", protocol.Task{ID: "task-1", Status: "PAUSED", Progress: 40}, "PAUSED", 40},
+		// Ein Schritt, der vor der Pause begonnen hat, setzt den Task nicht fort
+		{"verspätetes Update", protocol.Task{ID: "task-1", Status: "RUNNING", WorkerID: "worker-1", Progress: 50}, "PAUSED", 40},
+		{"verspäteter Abschluss", protocol.Task{ID: "task-1", Status: "COMPLETED", WorkerID: "worker-1", Progress: 100}, "PAUSED", 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Wie der Worker das Update vor dem Senden in Redis schreiben
+			raw, _ := json.Marshal(fromProtocolTask(&tt.update))
+			mr.Set("task:task-1", string(raw))
+
+			deliverTaskStatus(t, tm, "worker-1", tt.update)
+
+			got := tm.tasks["task-1"]
+			if got == nil || got.Status != tt.wantStatus || got.Progress != tt.wantProg {
+				t.Fatalf("Task im Speicher: %+v, erwartet %s bei %d%%", got, tt.wantStatus, tt.wantProg)
+			}
+			var stored Task
+			raw2, _ := mr.Get("task:task-1")
+			if err := json.Unmarshal([]byte(raw2), &stored); err != nil || stored.Status != tt.wantStatus {
+				t.Fatalf("Task in Redis: %s (%v), erwartet %s", raw2, err, tt.wantStatus)
+			}
+		})
+	}
+
+	// Nachrichten einer inkompatiblen Version werden abgelehnt
+	tm.handleDelivery(amqp.Delivery{Body: []byte(`{"version":99,"type":"task_status","task_id":"task-1"}`)}, tm.handleTaskStatus)
+	if got := tm.tasks["task-1"]; got.Status != "PAUSED" {
+		t.Fatalf("Status nach abgelehnter Nachricht %s, erwartet PAUSED", got.Status)
+	}
+}
+
+func TestPauseResumeTask(t *testing.T) {
+	ts, mr, pub := newTestTaskService(t)
+	tm := ts.tm
+	ctx := context.Background()
+
+	deliverTaskStatus(t, tm, "worker-1", protocol.Task{ID: "task-1", Type: "demo", Status: "RUNNING", WorkerID: "worker-1", Progress: 40})
+
+	// Pausieren setzt nur den Steuerbefehl; den Status meldet der Worker
+	if _, err := ts.PauseTask(ctx, "task-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("control:task-1"); got != "pause" {
+		t.Fatalf("Steuerbefehl %q, erwartet pause", got)
+	}
+	if got := tm.tasks["task-1"].Status; got != "RUNNING" {
+		t.Fatalf("Status vor der Meldung des Workers %s, erwartet RUNNING", got)
+	}
+
+	// Fortsetzen ist nur für pausierte Tasks möglich
+	if _, err := ts.ResumeTask(ctx, "task-1"); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("ResumeTask auf RUNNING: %v, erwartet ErrInvalidState", err)
+	}
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/api/tasks/task-1/resume", nil), map[string]string{"id": "task-1"})
+	rec := httptest.NewRecorder()
+	ts.HandleResumeTask(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("HTTP-Status %d, erwartet 409", rec.Code)
+	}
+	if len(pub.messages) != 0 {
+		t.Fatalf("%d Nachrichten gesendet, erwartet keine", len(pub.messages))
+	}
+
+	// Der Worker hat angehalten, den Checkpoint gespeichert und den Task freigegeben
+	checkpoint := map[string]interface{}{"progress": float64(40), "step": "step_4"}
+	deliverTaskStatus(t, tm, "worker-1", protocol.Task{ID: "task-1", Type: "demo", Status: "PAUSED", Progress: 40, CheckpointData: checkpoint})
+	mr.Del("control:task-1")
+
+	task, err := ts.ResumeTask(ctx, "task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != "RECOVERING" || task.WorkerID != "" || task.Progress != 40 {
+		t.Fatalf("Task nach ResumeTask: %+v", task)
+	}
+	if len(pub.messages) != 1 {
+		t.Fatalf("%d Nachrichten gesendet, erwartet 1", len(pub.messages))
+	}
+	if pub.queues[0] != protocol.DispatchQueue(protocol.DefaultNamespace) || pub.messages[0].Type != protocol.TypeTaskRecovery {
+		t.Fatalf("Nachricht %s an %s, erwartet task_recovery an %s",
+			pub.messages[0].Type, pub.queues[0], protocol.DispatchQueue(protocol.DefaultNamespace))
+	}
+	recovery, err := pub.messages[0].Task()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovery.Status != "RECOVERING" || recovery.Progress != 40 || recovery.CheckpointData["progress"] != float64(40) {
+		t.Fatalf("task_recovery: %+v, erwartet RECOVERING ab Checkpoint bei 40%%", recovery)
+	}
+
+	// Ein Worker übernimmt den Task und setzt ab dem Checkpoint fort
+	deliverTaskStatus(t, tm, "worker-2", protocol.Task{ID: "task-1", Type: "demo", Status: "RUNNING", WorkerID: "worker-2", Progress: 50})
+	if got := tm.tasks["task-1"]; got.Status != "RUNNING" || got.WorkerID != "worker-2" || got.Progress != 50 {
+		t.Fatalf("Task nach Übernahme: %+v", got)
+	}
+}
+
+func TestCreateTaskPublishFailure(t *testing.T) {
+	ts, mr, pub := newTestTaskService(t)
+	ts.schemas = NewSchemaRegistry(ts.tm.redisClient)
+	ts.quotas = &Quotas{redisClient: ts.tm.redisClient}
+	pub.err = errors.New("Kanal geschlossen")
+
+	ctx := withNamespace(context.Background(), "team-a")
+	_, err := ts.CreateTask(ctx, CreateTaskRequest{Type: "computation", Data: map[string]interface{}{"operation": "sum"}})
+	if !errors.Is(err, pub.err) {
+		t.Fatalf("Fehler %v, erwartet %v", err, pub.err)
+	}
+
+	// Der nicht verteilte Task hinterlässt weder Task noch Namespace-Zuordnung
+	if len(ts.tm.tasks) != 0 {
+		t.Fatalf("Tasks im Speicher: %v", ts.tm.tasks)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Fatalf("Schlüssel in Redis: %v", keys)
+	}
+	if len(ts.namespaces.tasks) != 0 {
+		t.Fatalf("Namespace-Zuordnungen: %v", ts.namespaces.tasks)
+	}
+}
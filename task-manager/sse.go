@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sseReplaySize ist die Anzahl der Ereignisse, die für Wiederaufnahmen vorgehalten werden
+	sseReplaySize = 1000
+	// sseClientBuffer ist die Anzahl der Ereignisse, die pro Client gepuffert werden
+	sseClientBuffer = 256
+	// sseKeepAlive ist der Abstand der Kommentarzeilen, die Proxys die Verbindung offen halten lassen
+	sseKeepAlive = 15 * time.Second
+)
+
+// sseResyncEvent fordert Clients auf, ihren Zustand neu zu laden; es hat keine ID
+var sseResyncEvent = Event{Type: "resync_required", Payload: []byte(`{"type":"resync_required"}`)}
+
+// sseClient ist ein verbundener SSE-Client mit seinen Filtern
+type sseClient struct {
+	events chan Event
+	filter eventFilter
+}
+
+// SSEHandler liefert dieselben Ereignisse wie der WebSocketHandler als Server-Sent Events.
+// Als Ereignis-ID dient die Sequenznummer des WebSocketHandlers zusammen mit seiner Epoche.
+type SSEHandler struct {
+	wsHandler *WebSocketHandler
+	clients   map[*sseClient]bool
+	// replay enthält lückenlos aufeinanderfolgende Ereignisse bis einschließlich lastSeq
+	replay  []Event
+	lastSeq uint64
+	mutex   sync.Mutex
+}
+
+// NewSSEHandler erstellt einen neuen SSEHandler
+func NewSSEHandler(wsHandler *WebSocketHandler) *SSEHandler {
+	return &SSEHandler{
+		wsHandler: wsHandler,
+		clients:   make(map[*sseClient]bool),
+		replay:    make([]Event, 0, sseReplaySize),
+	}
+}
+
+// Start abonniert die Ereignisse des WebSocketHandlers und verteilt sie an die SSE-Clients.
+// Hat das Abonnement Ereignisse verworfen, werden sie aus dem Replay-Puffer des
+// WebSocketHandlers ergänzt; ist das nicht möglich, erhalten alle Clients resync_required.
+func (sh *SSEHandler) Start() {
+	events, _ := sh.wsHandler.Subscribe(sseReplaySize)
+	go func() {
+		for event := range events {
+			sh.receive(event)
+		}
+	}()
+}
+
+// receive übernimmt ein Ereignis des Abonnements und schließt vorher eine Lücke zum
+// zuletzt übernommenen Ereignis
+func (sh *SSEHandler) receive(event Event) {
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	if sh.lastSeq != 0 && event.Seq > sh.lastSeq+1 {
+		missed, ok := sh.wsHandler.eventsBetween(sh.lastSeq, event.Seq)
+		if ok {
+			for _, e := range missed {
+				sh.deliver(e)
+			}
+		} else {
+			sseLogger.Warnf("Ereignisse %d bis %d verpasst, Clients müssen ihren Zustand neu laden", sh.lastSeq+1, event.Seq-1)
+			sh.resync()
+		}
+	}
+	sh.deliver(event)
+}
+
+// deliver nimmt ein Ereignis in den Replay-Puffer auf und verteilt es an die Clients.
+// Muss unter sh.mutex aufgerufen werden.
+func (sh *SSEHandler) deliver(event Event) {
+	if len(sh.replay) == sseReplaySize {
+		copy(sh.replay, sh.replay[1:])
+		sh.replay = sh.replay[:sseReplaySize-1]
+	}
+	sh.replay = append(sh.replay, event)
+	sh.lastSeq = event.Seq
+
+	for client := range sh.clients {
+		if client.filter.matches(event) {
+			sh.send(client, event)
+		}
+	}
+}
+
+// resync leert den Replay-Puffer, damit keine Wiederaufnahme über die Lücke hinweg
+// möglich ist, und fordert alle Clients auf, ihren Zustand neu zu laden.
+// Muss unter sh.mutex aufgerufen werden.
+func (sh *SSEHandler) resync() {
+	sh.replay = sh.replay[:0]
+	for client := range sh.clients {
+		sh.send(client, sseResyncEvent)
+	}
+}
+
+// send stellt ein Ereignis in die Warteschlange eines Clients. Muss unter sh.mutex aufgerufen werden.
+func (sh *SSEHandler) send(client *sseClient, event Event) {
+	select {
+	case client.events <- event:
+	default:
+		// Client zu langsam: Verbindung beenden, damit er mit Last-Event-ID neu aufsetzt
+		delete(sh.clients, client)
+		close(client.events)
+	}
+}
+
+// HandleStream ist der HTTP-Handler für GET /api/events/stream.
+//
+// Query-Parameter task_id, worker_id, type und task_type filtern die Ereignisse (mehrfach
+// oder kommagetrennt); es erscheinen nur Ereignisse aus dem Namespace der Anfrage. Mit dem Header Last-Event-ID (oder dem Parameter
+// last_event_id) werden verpasste Ereignisse aus dem Replay-Puffer nachgeliefert.
+func (sh *SSEHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming wird nicht unterstützt", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	client := &sseClient{
+		events: make(chan Event, sseClientBuffer),
+		filter: eventFilter{
+			namespace: namespaceFromContext(r.Context()),
+			taskIDs:   splitQueryValues(query["task_id"]),
+			workerIDs: splitQueryValues(query["worker_id"]),
+			types:     splitQueryValues(query["type"]),
+			taskTypes: splitQueryValues(query["task_type"]),
+		},
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Puffern in nginx deaktivieren
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+
+	// Registrierung und Replay unter demselben Lock, damit kein Ereignis fehlt oder doppelt kommt
+	sh.mutex.Lock()
+	missed, complete := sh.eventsSince(lastEventID)
+	sh.clients[client] = true
+	sh.mutex.Unlock()
+
+	defer func() {
+		sh.mutex.Lock()
+		if _, ok := sh.clients[client]; ok {
+			delete(sh.clients, client)
+			close(client.events)
+		}
+		sh.mutex.Unlock()
+	}()
+
+	if !complete {
+		// Die angefragten Ereignisse sind nicht mehr im Puffer; der Client muss seinen Zustand neu laden
+		writeSSE(w, "", sseResyncEvent.Type, sseResyncEvent.Payload)
+	}
+	for _, e := range missed {
+		if client.filter.matches(e) {
+			writeSSE(w, sh.formatID(e.Seq), e.Type, e.Payload)
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprintf(w, ": keepalive\n\n")
+			flusher.Flush()
+		case e, ok := <-client.events:
+			if !ok {
+				sseLogger.Warnf("SSE-Client zu langsam, Verbindung wird beendet")
+				return
+			}
+			writeSSE(w, sh.formatID(e.Seq), e.Type, e.Payload)
+			flusher.Flush()
+		}
+	}
+}
+
+// eventsSince liefert alle Ereignisse nach lastEventID. complete ist false, wenn
+// Ereignisse dazwischen nicht mehr im Puffer liegen. Muss unter sh.mutex aufgerufen werden.
+func (sh *SSEHandler) eventsSince(lastEventID string) ([]Event, bool) {
+	if lastEventID == "" {
+		return nil, true
+	}
+
+	parts := strings.SplitN(lastEventID, "-", 2)
+	if len(parts) != 2 || parts[0] != sh.wsHandler.epoch {
+		// ID aus einem früheren Prozesslauf, z.B. nach einem Neustart des Task-Managers
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq > sh.lastSeq {
+		return nil, false
+	}
+	if len(sh.replay) == 0 {
+		return nil, seq == sh.lastSeq
+	}
+
+	oldest := sh.replay[0].Seq
+	if seq+1 < oldest {
+		return append([]Event(nil), sh.replay...), false
+	}
+
+	start := int(seq + 1 - oldest)
+	return append([]Event(nil), sh.replay[start:]...), true
+}
+
+// formatID erzeugt die nach außen sichtbare Ereignis-ID "<epoch>-<seq>"; Ereignisse
+// ohne Sequenznummer erhalten keine ID
+func (sh *SSEHandler) formatID(seq uint64) string {
+	if seq == 0 {
+		return ""
+	}
+	return sh.wsHandler.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// writeSSE schreibt ein Ereignis im text/event-stream-Format
+func writeSSE(w http.ResponseWriter, id string, eventType string, data []byte) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\n", eventType)
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// splitQueryValues fasst mehrfach angegebene und kommagetrennte Werte zusammen
+func splitQueryValues(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				set[part] = true
+			}
+		}
+	}
+	return set
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// drainSSEClient liefert die wartenden Ereignisse eines Clients als "<type>:<id>"
+func drainSSEClient(sh *SSEHandler, client *sseClient) []string {
+	var events []string
+	for {
+		select {
+		case e := <-client.events:
+			events = append(events, fmt.Sprintf("%s:%s", e.Type, sh.formatID(e.Seq)))
+		default:
+			return events
+		}
+	}
+}
+
+func TestSSEReceiveFillsGaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		replaySize int
+		// received sind die Sequenznummern, die das Abonnement tatsächlich liefert
+		received []int
+		want     []string
+		// wantReplay ist der Inhalt des Replay-Puffers danach
+		wantReplay string
+	}{
+		{
+			name:       "ohne Lücke",
+			replaySize: 10,
+			received:   []int{1, 2, 3, 4, 5},
+			want:       []string{"e1:1", "e2:2", "e3:3", "e4:4", "e5:5"},
+			wantReplay: "1,2,3,4,5",
+		},
+		{
+			name:       "Lücke aus dem WebSocket-Puffer ergänzt",
+			replaySize: 10,
+			received:   []int{1, 4, 5},
+			want:       []string{"e1:1", "e2:2", "e3:3", "e4:4", "e5:5"},
+			wantReplay: "1,2,3,4,5",
+		},
+		{
+			name:       "Lücke nicht mehr im WebSocket-Puffer",
+			replaySize: 2,
+			received:   []int{1, 5},
+			want:       []string{"e1:1", "resync_required:", "e5:5"},
+			wantReplay: "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wsh := newTestWSHandler(tt.replaySize, 10)
+			var dispatched []Event
+			events, unsubscribe := wsh.Subscribe(10)
+			for i := 1; i <= 5; i++ {
+				wsh.BroadcastMessage(fmt.Sprintf("e%d", i), map[string]string{})
+				dispatched = append(dispatched, <-events)
+			}
+			unsubscribe()
+
+			sh := NewSSEHandler(wsh)
+			client := &sseClient{events: make(chan Event, 10)}
+			sh.clients[client] = true
+			for _, seq := range tt.received {
+				sh.receive(dispatched[seq-1])
+			}
+
+			prefix := wsh.epoch + "-"
+			var got []string
+			for _, e := range drainSSEClient(sh, client) {
+				got = append(got, strings.Replace(e, prefix, "", 1))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("Ereignisse = %v, erwartet %v", got, tt.want)
+			}
+
+			var replay []string
+			for _, e := range sh.replay {
+				replay = append(replay, fmt.Sprint(e.Seq))
+			}
+			if strings.Join(replay, ",") != tt.wantReplay {
+				t.Fatalf("Replay = %v, erwartet %s", replay, tt.wantReplay)
+			}
+		})
+	}
+}
+
+func TestSSEEventsSince(t *testing.T) {
+	wsh := newTestWSHandler(10, 10)
+	sh := NewSSEHandler(wsh)
+	for i := 1; i <= 5; i++ {
+		sh.receive(Event{Type: "e", Seq: uint64(i)})
+	}
+	sh.replay = sh.replay[2:] // Ereignisse 1 und 2 sind verdrängt
+
+	tests := []struct {
+		name         string
+		lastEventID  string
+		wantSeqs     string
+		wantComplete bool
+	}{
+		{name: "ohne ID", lastEventID: "", wantSeqs: "", wantComplete: true},
+		{name: "im Puffer", lastEventID: sh.formatID(3), wantSeqs: "4,5", wantComplete: true},
+		{name: "direkt vor dem Puffer", lastEventID: sh.formatID(2), wantSeqs: "3,4,5", wantComplete: true},
+		{name: "aktuell", lastEventID: sh.formatID(5), wantSeqs: "", wantComplete: true},
+		{name: "verdrängt", lastEventID: sh.formatID(1), wantSeqs: "3,4,5", wantComplete: false},
+		{name: "aus der Zukunft", lastEventID: sh.formatID(6), wantSeqs: "", wantComplete: false},
+		{name: "andere Epoche", lastEventID: "alt-3", wantSeqs: "", wantComplete: false},
+		{name: "ohne Epoche", lastEventID: "3", wantSeqs: "", wantComplete: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, complete := sh.eventsSince(tt.lastEventID)
+			var seqs []string
+			for _, e := range events {
+				seqs = append(seqs, fmt.Sprint(e.Seq))
+			}
+			if strings.Join(seqs, ",") != tt.wantSeqs || complete != tt.wantComplete {
+				t.Fatalf("eventsSince = %v, %v, erwartet %s, %v", seqs, complete, tt.wantSeqs, tt.wantComplete)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+	"github.com/streadway/amqp"
+)
+
+// Gesamturteil von GET /api/system/status
+const (
+	systemHealthy  = "healthy"
+	systemDegraded = "degraded"
+	systemDown     = "down"
+)
+
+// statusCheckTimeout begrenzt jede Prüfung einer Abhängigkeit
+const statusCheckTimeout = 3 * time.Second
+
+// stuckTaskExamples ist die Höchstzahl der aufgeführten IDs hängender Tasks
+const stuckTaskExamples = 20
+
+// stalledTaskStates sind Status, in denen ein Worker regelmäßig Updates schicken müsste
+var stalledTaskStates = map[string]bool{
+	"ASSIGNED":   true,
+	"RUNNING":    true,
+	"RECOVERING": true,
+	"MIGRATING":  true,
+}
+
+// statusQueues sind die Warteschlangen, die der Task-Manager selbst verarbeitet;
+// dazu kommt die Verteil-Warteschlange des abgefragten Namespace
+var statusQueues = []protocol.Type{protocol.TypeTaskStatus, protocol.TypeTaskCheckpoint, protocol.TypeWorkerStatus}
+
+// SystemStatusConfig enthält die Schwellwerte für das Gesamturteil
+type SystemStatusConfig struct {
+	// SlowLatency ist die Antwortzeit, ab der eine Abhängigkeit als langsam gilt
+	SlowLatency time.Duration
+	// WaitingAfter ist die Zeit, nach der ein Task im Status CREATED als hängend gilt
+	WaitingAfter time.Duration
+	// StalledAfter ist die Zeit ohne Update, nach der ein laufender Task als hängend gilt
+	StalledAfter time.Duration
+	// QueueBacklog ist die Zahl wartender Nachrichten, ab der eine Warteschlange als überlastet gilt
+	QueueBacklog int
+}
+
+// DependencyStatus ist die Erreichbarkeit einer Abhängigkeit
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// QueueStatus ist der Zustand einer Warteschlange; Exists ist false, wenn der Broker sie nicht kennt
+type QueueStatus struct {
+	QueueInfo
+	Exists bool `json:"exists"`
+}
+
+// StuckTasks zählt Tasks, die nicht vorankommen
+type StuckTasks struct {
+	// Waiting sind Tasks, die zu lange auf einen Worker warten
+	Waiting int `json:"waiting"`
+	// Stalled sind zugewiesene Tasks, von deren Worker zu lange kein Update kam
+	Stalled int      `json:"stalled"`
+	TaskIDs []string `json:"task_ids,omitempty"`
+}
+
+// SystemStatusReport ist die Antwort von GET /api/system/status
+type SystemStatusReport struct {
+	Status       string                      `json:"status"`
+	Reasons      []string                    `json:"reasons"`
+	Namespace    string                      `json:"namespace"`
+	CheckedAt    TimeJSON                    `json:"checked_at"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+	Queues       []QueueStatus               `json:"queues"`
+	Workers      map[string]int              `json:"workers"`
+	Tasks        map[string]int              `json:"tasks"`
+	StuckTasks   StuckTasks                  `json:"stuck_tasks"`
+}
+
+// SystemStatus prüft Redis und RabbitMQ und beurteilt daraus, aus den Warteschlangen,
+// den Workern und hängenden Tasks den Zustand des Systems
+type SystemStatus struct {
+	tm         *TaskManager
+	namespaces *NamespaceRegistry
+	broker     *BrokerInspector
+	config     SystemStatusConfig
+
+	mutex      sync.RWMutex
+	channelErr error
+	// lastStatus ist das letzte Urteil je Namespace, um Wechsel zu protokollieren
+	lastStatus map[string]string
+}
+
+// NewSystemStatusFromEnv erstellt die Statusprüfung mit den Schwellwerten aus
+// STATUS_SLOW_LATENCY, STATUS_TASK_WAITING_AFTER, STATUS_TASK_STALLED_AFTER und STATUS_QUEUE_BACKLOG
+func NewSystemStatusFromEnv(tm *TaskManager, namespaces *NamespaceRegistry, broker *BrokerInspector) *SystemStatus {
+	return &SystemStatus{
+		tm:         tm,
+		namespaces: namespaces,
+		broker:     broker,
+		config: SystemStatusConfig{
+			SlowLatency:  envDuration("STATUS_SLOW_LATENCY", 250*time.Millisecond),
+			WaitingAfter: envDuration("STATUS_TASK_WAITING_AFTER", 5*time.Minute),
+			StalledAfter: envDuration("STATUS_TASK_STALLED_AFTER", 2*time.Minute),
+			QueueBacklog: envInt("STATUS_QUEUE_BACKLOG", 1000),
+		},
+		lastStatus: make(map[string]string),
+	}
+}
+
+// Start beobachtet den AMQP-Kanal des Task-Managers; ist er geschlossen, können
+// weder Tasks verteilt noch Updates empfangen werden
+func (s *SystemStatus) Start() {
+	go s.watchChannel(s.tm.amqpChannel.NotifyClose(make(chan *amqp.Error, 1)))
+}
+
+// watchChannel merkt sich, dass der AMQP-Kanal geschlossen wurde
+func (s *SystemStatus) watchChannel(closed <-chan *amqp.Error) {
+	err := fmt.Errorf("AMQP-Kanal geschlossen")
+	if amqpErr, ok := <-closed; ok && amqpErr != nil {
+		err = fmt.Errorf("AMQP-Kanal geschlossen: %v", amqpErr)
+	}
+	statusLogger.Event("amqp_channel_closed").Errorf("%v", err)
+	s.mutex.Lock()
+	s.channelErr = err
+	s.mutex.Unlock()
+}
+
+// Check erstellt den Statusbericht für einen Namespace
+func (s *SystemStatus) Check(ctx context.Context, namespace string) *SystemStatusReport {
+	report := &SystemStatusReport{
+		Reasons:      []string{},
+		Namespace:    namespace,
+		CheckedAt:    TimeJSON(time.Now()),
+		Dependencies: make(map[string]DependencyStatus),
+		Queues:       []QueueStatus{},
+		Workers:      make(map[string]int),
+		Tasks:        make(map[string]int),
+	}
+	var down, degraded []string
+
+	// Redis: ohne Redis gehen Tasks und Checkpoints verloren
+	redisStatus, err := s.measure(ctx, func(ctx context.Context) error {
+		return s.tm.redisClient.Ping(ctx).Err()
+	})
+	report.Dependencies["redis"] = redisStatus
+	switch {
+	case err != nil:
+		down = append(down, fmt.Sprintf("Redis nicht erreichbar: %v", err))
+	case redisStatus.LatencyMS > s.slowMS():
+		degraded = append(degraded, fmt.Sprintf("Redis antwortet langsam (%.0f ms)", redisStatus.LatencyMS))
+	}
+
+	// AMQP-Kanal des Task-Managers
+	s.mutex.RLock()
+	channelErr := s.channelErr
+	s.mutex.RUnlock()
+	if channelErr != nil {
+		report.Dependencies["amqp"] = DependencyStatus{Status: "down", Error: channelErr.Error()}
+		down = append(down, channelErr.Error())
+	} else {
+		report.Dependencies["amqp"] = DependencyStatus{Status: "up"}
+	}
+
+	// Warteschlangen über die Management-API; ihre Antwortzeit steht für die des Brokers
+	var queues []QueueInfo
+	brokerStatus, err := s.measure(ctx, func(ctx context.Context) error {
+		var err error
+		queues, err = s.broker.Queues(ctx)
+		return err
+	})
+	report.Dependencies["rabbitmq_management"] = brokerStatus
+	if err != nil {
+		degraded = append(degraded, err.Error())
+	} else {
+		if brokerStatus.LatencyMS > s.slowMS() {
+			degraded = append(degraded, fmt.Sprintf("RabbitMQ antwortet langsam (%.0f ms)", brokerStatus.LatencyMS))
+		}
+		report.Queues, degraded = s.checkQueues(queues, namespace, degraded)
+	}
+
+	// Worker, die Tasks dieses Namespace annehmen
+	available := 0
+	s.tm.workerMutex.RLock()
+	for id, worker := range s.tm.workerStatus {
+		if !s.namespaces.WorkerServes(id, namespace) {
+			continue
+		}
+		report.Workers[worker.Status]++
+		if worker.Status != "FAILING" && worker.Status != "FAILED" && worker.Status != "SHUTDOWN" {
+			available++
+		}
+	}
+	s.tm.workerMutex.RUnlock()
+	if available == 0 {
+		degraded = append(degraded, fmt.Sprintf("Kein Worker für Namespace %s verfügbar", namespace))
+	} else if failing := report.Workers["FAILING"] + report.Workers["FAILED"]; failing > 0 {
+		degraded = append(degraded, fmt.Sprintf("%d Worker ausgefallen", failing))
+	}
+
+	// Tasks und hängende Tasks
+	report.StuckTasks = s.countTasks(namespace, report.Tasks)
+	if report.StuckTasks.Waiting > 0 {
+		degraded = append(degraded, fmt.Sprintf("%d Tasks warten länger als %s auf einen Worker",
+			report.StuckTasks.Waiting, s.config.WaitingAfter))
+	}
+	if report.StuckTasks.Stalled > 0 {
+		degraded = append(degraded, fmt.Sprintf("%d Tasks ohne Update seit mehr als %s",
+			report.StuckTasks.Stalled, s.config.StalledAfter))
+	}
+
+	switch {
+	case len(down) > 0:
+		report.Status = systemDown
+	case len(degraded) > 0:
+		report.Status = systemDegraded
+	default:
+		report.Status = systemHealthy
+	}
+	report.Reasons = append(append(report.Reasons, down...), degraded...)
+	s.logChange(report)
+	return report
+}
+
+// measure führt eine Prüfung mit Zeitlimit aus und misst ihre Dauer
+func (s *SystemStatus) measure(ctx context.Context, check func(ctx context.Context) error) (DependencyStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	status := DependencyStatus{Status: "up", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+	return status, err
+}
+
+func (s *SystemStatus) slowMS() float64 {
+	return float64(s.config.SlowLatency.Microseconds()) / 1000
+}
+
+// checkQueues wählt die Warteschlangen des Namespace aus und ergänzt die Gründe für
+// ein eingeschränktes Urteil: fehlende Warteschlangen, fehlende Consumer, Rückstau
+func (s *SystemStatus) checkQueues(queues []QueueInfo, namespace string, degraded []string) ([]QueueStatus, []string) {
+	dispatch := protocol.DispatchQueue(namespace)
+	names := []string{dispatch}
+	for _, queueType := range statusQueues {
+		names = append(names, string(queueType))
+	}
+
+	result := make([]QueueStatus, 0, len(names))
+	for _, name := range names {
+		status := QueueStatus{QueueInfo: QueueInfo{Name: name}}
+		for _, queue := range queues {
+			if queue.Name == name {
+				status = QueueStatus{QueueInfo: queue, Exists: true}
+				break
+			}
+		}
+		result = append(result, status)
+
+		switch {
+		case !status.Exists:
+			degraded = append(degraded, fmt.Sprintf("Warteschlange %s existiert nicht", name))
+		case status.Consumers == 0 && name == dispatch:
+			degraded = append(degraded, fmt.Sprintf("Warteschlange %s hat keine Worker als Consumer", name))
+		case status.Consumers == 0:
+			degraded = append(degraded, fmt.Sprintf("Warteschlange %s wird nicht verarbeitet", name))
+		case status.MessagesReady > s.config.QueueBacklog:
+			degraded = append(degraded, fmt.Sprintf("Rückstau in %s: %d wartende Nachrichten", name, status.MessagesReady))
+		}
+	}
+	return result, degraded
+}
+
+// countTasks zählt die Tasks eines Namespace je Status in counts und liefert die hängenden
+func (s *SystemStatus) countTasks(namespace string, counts map[string]int) StuckTasks {
+	stuck := StuckTasks{}
+	now := time.Now()
+	s.tm.taskMutex.RLock()
+	for id, task := range s.tm.tasks {
+		if s.namespaces.TaskNamespace(id) != namespace {
+			continue
+		}
+		counts[task.Status]++
+		switch {
+		case task.Status == "CREATED" && now.Sub(time.Time(task.CreatedAt)) > s.config.WaitingAfter:
+			stuck.Waiting++
+		case stalledTaskStates[task.Status] && now.Sub(time.Time(task.UpdatedAt)) > s.config.StalledAfter:
+			stuck.Stalled++
+		default:
+			continue
+		}
+		stuck.TaskIDs = append(stuck.TaskIDs, id)
+	}
+	s.tm.taskMutex.RUnlock()
+
+	sort.Strings(stuck.TaskIDs)
+	if len(stuck.TaskIDs) > stuckTaskExamples {
+		stuck.TaskIDs = stuck.TaskIDs[:stuckTaskExamples]
+	}
+	return stuck
+}
+
+// logChange protokolliert, wenn sich das Urteil für einen Namespace ändert
+func (s *SystemStatus) logChange(report *SystemStatusReport) {
+	s.mutex.Lock()
+	previous, known := s.lastStatus[report.Namespace]
+	s.lastStatus[report.Namespace] = report.Status
+	s.mutex.Unlock()
+	if known && previous == report.Status {
+		return
+	}
+
+	entry := statusLogger.Event("system_status_changed").Field("namespace", report.Namespace)
+	if report.Status == systemHealthy {
+		entry.Infof("System-Status %s", report.Status)
+	} else {
+		entry.Warnf("System-Status %s: %s", report.Status, strings.Join(report.Reasons, "; "))
+	}
+}
+
+// HandleStatus ist der HTTP-Handler für GET /api/system/status im Namespace der Anfrage.
+// Beim Urteil down antwortet er mit 503, damit einfache Health-Checks ausreichen.
+func (s *SystemStatus) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	report := s.Check(r.Context(), namespaceFromContext(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == systemDown {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/streadway/amqp"
+)
+
+// statusFixture ist ein gesundes System: Redis läuft, alle Warteschlangen haben
+// Consumer, ein Worker ist aktiv und kein Task hängt
+type statusFixture struct {
+	redis  *miniredis.Miniredis
+	queues []QueueInfo
+	// brokerDown lässt die Management-API mit 500 antworten
+	brokerDown bool
+	status     *SystemStatus
+}
+
+func newStatusFixture(t *testing.T) *statusFixture {
+	t.Helper()
+	f := &statusFixture{redis: miniredis.RunT(t)}
+	for _, name := range []string{"task_created", "task_status", "task_checkpoint", "worker_status"} {
+		f.queues = append(f.queues, QueueInfo{Name: name, VHost: "/", Consumers: 1})
+	}
+
+	broker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.brokerDown {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(f.queues)
+	}))
+	t.Cleanup(broker.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: f.redis.Addr(), MaxRetries: -1, DialTimeout: 200 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+	tm := &TaskManager{
+		redisClient:  client,
+		tasks:        make(map[string]*Task),
+		workerStatus: map[string]*Worker{"w1": {ID: "w1", Status: "ACTIVE"}},
+		wsHandler:    newTestWSHandler(16, 16),
+	}
+	f.status = &SystemStatus{
+		tm:         tm,
+		namespaces: NewNamespaceRegistry(client),
+		broker:     &BrokerInspector{baseURL: broker.URL, client: &http.Client{Timeout: time.Second}},
+		config: SystemStatusConfig{
+			SlowLatency:  time.Minute,
+			WaitingAfter: 5 * time.Minute,
+			StalledAfter: 2 * time.Minute,
+			QueueBacklog: 100,
+		},
+		lastStatus: make(map[string]string),
+	}
+	return f
+}
+
+func (f *statusFixture) addTask(id, status string, age time.Duration) {
+	at := TimeJSON(time.Now().Add(-age))
+	f.status.tm.tasks[id] = &Task{ID: id, Type: "sleep", Status: status, CreatedAt: at, UpdatedAt: at}
+}
+
+func (f *statusFixture) queue(name string) *QueueInfo {
+	for i := range f.queues {
+		if f.queues[i].Name == name {
+			return &f.queues[i]
+		}
+	}
+	return nil
+}
+
+func TestSystemStatusCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(f *statusFixture)
+		wantStatus  string
+		wantReasons []string
+	}{
+		{
+			name:       "gesund",
+			setup:      func(f *statusFixture) { f.addTask("t1", "RUNNING", time.Second) },
+			wantStatus: systemHealthy,
+		},
+		{
+			name:        "Redis nicht erreichbar",
+			setup:       func(f *statusFixture) { f.redis.Close() },
+			wantStatus:  systemDown,
+			wantReasons: []string{"Redis nicht erreichbar"},
+		},
+		{
+			name: "AMQP-Kanal geschlossen",
+			setup: func(f *statusFixture) {
+				closed := make(chan *amqp.Error, 1)
+				closed <- &amqp.Error{Code: amqp.ChannelError, Reason: "PRECONDITION_FAILED"}
+				f.status.watchChannel(closed)
+			},
+			wantStatus:  systemDown,
+			wantReasons: []string{"AMQP-Kanal geschlossen: Exception (504)"},
+		},
+		{
+			name: "down hat Vorrang vor degraded",
+			setup: func(f *statusFixture) {
+				f.redis.Close()
+				f.status.tm.workerStatus = map[string]*Worker{}
+			},
+			wantStatus:  systemDown,
+			wantReasons: []string{"Redis nicht erreichbar", "Kein Worker für Namespace default verfügbar"},
+		},
+		{
+			name:        "Management-API nicht erreichbar",
+			setup:       func(f *statusFixture) { f.brokerDown = true },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"RabbitMQ-Management-API antwortete mit 500"},
+		},
+		{
+			name:        "Warteschlange fehlt",
+			setup:       func(f *statusFixture) { f.queues = f.queues[1:] },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"Warteschlange task_created existiert nicht"},
+		},
+		{
+			name:        "Verteil-Warteschlange ohne Worker",
+			setup:       func(f *statusFixture) { f.queue("task_created").Consumers = 0 },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"Warteschlange task_created hat keine Worker als Consumer"},
+		},
+		{
+			name:        "Status-Warteschlange ohne Consumer",
+			setup:       func(f *statusFixture) { f.queue("task_status").Consumers = 0 },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"Warteschlange task_status wird nicht verarbeitet"},
+		},
+		{
+			name:        "Rückstau",
+			setup:       func(f *statusFixture) { f.queue("task_created").MessagesReady = 101 },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"Rückstau in task_created: 101 wartende Nachrichten"},
+		},
+		{
+			name:        "kein Worker",
+			setup:       func(f *statusFixture) { f.status.tm.workerStatus["w1"].Status = "FAILED" },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"Kein Worker für Namespace default verfügbar"},
+		},
+		{
+			name: "Worker teilweise ausgefallen",
+			setup: func(f *statusFixture) {
+				f.status.tm.workerStatus["w2"] = &Worker{ID: "w2", Status: "FAILING"}
+			},
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"1 Worker ausgefallen"},
+		},
+		{
+			name:        "Task wartet zu lange",
+			setup:       func(f *statusFixture) { f.addTask("t1", "CREATED", 6*time.Minute) },
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"1 Tasks warten länger als 5m0s auf einen Worker"},
+		},
+		{
+			name: "Task ohne Update",
+			setup: func(f *statusFixture) {
+				f.addTask("t1", "RUNNING", 3*time.Minute)
+				// Abgeschlossene Tasks hängen nie
+				f.addTask("t2", "COMPLETED", time.Hour)
+			},
+			wantStatus:  systemDegraded,
+			wantReasons: []string{"1 Tasks ohne Update seit mehr als 2m0s"},
+		},
+		{
+			name: "Tasks anderer Namespaces zählen nicht",
+			setup: func(f *statusFixture) {
+				f.addTask("t1", "RUNNING", time.Hour)
+				f.status.namespaces.SetTask(context.Background(), "t1", "team-a")
+			},
+			wantStatus: systemHealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newStatusFixture(t)
+			tt.setup(f)
+
+			report := f.status.Check(context.Background(), "default")
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status %s, erwartet %s (Gründe: %v)", report.Status, tt.wantStatus, report.Reasons)
+			}
+			if len(report.Reasons) != len(tt.wantReasons) {
+				t.Fatalf("Gründe %q, erwartet %q", report.Reasons, tt.wantReasons)
+			}
+			for i, want := range tt.wantReasons {
+				if !strings.HasPrefix(report.Reasons[i], want) {
+					t.Errorf("Grund %d = %q, erwartet %q", i, report.Reasons[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSystemStatusHandleStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		down       bool
+		wantStatus int
+	}{
+		{name: "gesund", wantStatus: http.StatusOK},
+		{name: "down", down: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newStatusFixture(t)
+			if tt.down {
+				f.redis.Close()
+			}
+
+			rec := httptest.NewRecorder()
+			f.status.HandleStatus(rec, httptest.NewRequest("GET", "/api/system/status", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("HTTP-Status %d, erwartet %d", rec.Code, tt.wantStatus)
+			}
+			var report SystemStatusReport
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("ungültige Antwort: %v", err)
+			}
+			if report.Namespace != "default" || len(report.Queues) != 4 || report.Dependencies["redis"].Status == "" {
+				t.Errorf("Bericht unvollständig: %+v", report)
+			}
+		})
+	}
+}
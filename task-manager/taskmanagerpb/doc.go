@@ -0,0 +1,5 @@
+// Package taskmanagerpb enthält die aus taskmanager.proto generierten
+// Protobuf-Nachrichten und gRPC-Stubs des Task-Managers.
+package taskmanagerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative taskmanager.proto
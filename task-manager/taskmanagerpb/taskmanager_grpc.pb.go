@@ -0,0 +1,442 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: taskmanager.proto
+
+// gRPC-Schnittstelle des Task-Managers. Sie nutzt denselben Service-Layer wie
+// die REST-API und hat daher dieselbe Semantik.
+
+package taskmanagerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	TaskManager_CreateTask_FullMethodName   = "/taskmanager.v1.TaskManager/CreateTask"
+	TaskManager_GetTask_FullMethodName      = "/taskmanager.v1.TaskManager/GetTask"
+	TaskManager_CancelTask_FullMethodName   = "/taskmanager.v1.TaskManager/CancelTask"
+	TaskManager_MigrateTask_FullMethodName  = "/taskmanager.v1.TaskManager/MigrateTask"
+	TaskManager_PauseTask_FullMethodName    = "/taskmanager.v1.TaskManager/PauseTask"
+	TaskManager_ResumeTask_FullMethodName   = "/taskmanager.v1.TaskManager/ResumeTask"
+	TaskManager_WatchTasks_FullMethodName   = "/taskmanager.v1.TaskManager/WatchTasks"
+	TaskManager_WatchWorkers_FullMethodName = "/taskmanager.v1.TaskManager/WatchWorkers"
+)
+
+// TaskManagerClient is the client API for TaskManager service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TaskManagerClient interface {
+	// Erstellt einen Task; ungültige Daten liefern INVALID_ARGUMENT
+	CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Liefert einen Task; unbekannte IDs liefern NOT_FOUND
+	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Bricht einen Task ab; abgeschlossene Tasks liefern FAILED_PRECONDITION
+	CancelTask(ctx context.Context, in *CancelTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Migriert einen Task auf einen anderen Worker
+	MigrateTask(ctx context.Context, in *MigrateTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Hält einen Task nach dem nächsten Checkpoint an; der Worker meldet anschließend PAUSED
+	PauseTask(ctx context.Context, in *PauseTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Stellt einen pausierten Task wieder in die Warteschlange; nur für PAUSED erlaubt
+	ResumeTask(ctx context.Context, in *ResumeTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	// Streamt Task-Ereignisse (task_update, task_checkpoint, task_migration, task_recovery)
+	WatchTasks(ctx context.Context, in *WatchTasksRequest, opts ...grpc.CallOption) (TaskManager_WatchTasksClient, error)
+	// Streamt Worker-Ereignisse (worker_update)
+	WatchWorkers(ctx context.Context, in *WatchWorkersRequest, opts ...grpc.CallOption) (TaskManager_WatchWorkersClient, error)
+}
+
+type taskManagerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTaskManagerClient(cc grpc.ClientConnInterface) TaskManagerClient {
+	return &taskManagerClient{cc}
+}
+
+func (c *taskManagerClient) CreateTask(ctx context.Context, in *CreateTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_CreateTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_GetTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) CancelTask(ctx context.Context, in *CancelTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_CancelTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) MigrateTask(ctx context.Context, in *MigrateTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_MigrateTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) PauseTask(ctx context.Context, in *PauseTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_PauseTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) ResumeTask(ctx context.Context, in *ResumeTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	out := new(Task)
+	err := c.cc.Invoke(ctx, TaskManager_ResumeTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerClient) WatchTasks(ctx context.Context, in *WatchTasksRequest, opts ...grpc.CallOption) (TaskManager_WatchTasksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskManager_ServiceDesc.Streams[0], TaskManager_WatchTasks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskManagerWatchTasksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TaskManager_WatchTasksClient interface {
+	Recv() (*TaskEvent, error)
+	grpc.ClientStream
+}
+
+type taskManagerWatchTasksClient struct {
+	grpc.ClientStream
+}
+
+func (x *taskManagerWatchTasksClient) Recv() (*TaskEvent, error) {
+	m := new(TaskEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *taskManagerClient) WatchWorkers(ctx context.Context, in *WatchWorkersRequest, opts ...grpc.CallOption) (TaskManager_WatchWorkersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskManager_ServiceDesc.Streams[1], TaskManager_WatchWorkers_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskManagerWatchWorkersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TaskManager_WatchWorkersClient interface {
+	Recv() (*WorkerEvent, error)
+	grpc.ClientStream
+}
+
+type taskManagerWatchWorkersClient struct {
+	grpc.ClientStream
+}
+
+func (x *taskManagerWatchWorkersClient) Recv() (*WorkerEvent, error) {
+	m := new(WorkerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TaskManagerServer is the server API for TaskManager service.
+// All implementations must embed UnimplementedTaskManagerServer
+// for forward compatibility
+type TaskManagerServer interface {
+	// Erstellt einen Task; ungültige Daten liefern INVALID_ARGUMENT
+	CreateTask(context.Context, *CreateTaskRequest) (*Task, error)
+	// Liefert einen Task; unbekannte IDs liefern NOT_FOUND
+	GetTask(context.Context, *GetTaskRequest) (*Task, error)
+	// Bricht einen Task ab; abgeschlossene Tasks liefern FAILED_PRECONDITION
+	CancelTask(context.Context, *CancelTaskRequest) (*Task, error)
+	// Migriert einen Task auf einen anderen Worker
+	MigrateTask(context.Context, *MigrateTaskRequest) (*Task, error)
+	// Hält einen Task nach dem nächsten Checkpoint an; der Worker meldet anschließend PAUSED
+	PauseTask(context.Context, *PauseTaskRequest) (*Task, error)
+	// Stellt einen pausierten Task wieder in die Warteschlange; nur für PAUSED erlaubt
+	ResumeTask(context.Context, *ResumeTaskRequest) (*Task, error)
+	// Streamt Task-Ereignisse (task_update, task_checkpoint, task_migration, task_recovery)
+	WatchTasks(*WatchTasksRequest, TaskManager_WatchTasksServer) error
+	// Streamt Worker-Ereignisse (worker_update)
+	WatchWorkers(*WatchWorkersRequest, TaskManager_WatchWorkersServer) error
+	mustEmbedUnimplementedTaskManagerServer()
+}
+
+// UnimplementedTaskManagerServer must be embedded to have forward compatible implementations.
+type UnimplementedTaskManagerServer struct {
+}
+
+func (UnimplementedTaskManagerServer) CreateTask(context.Context, *CreateTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTask not implemented")
+}
+func (UnimplementedTaskManagerServer) GetTask(context.Context, *GetTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedTaskManagerServer) CancelTask(context.Context, *CancelTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelTask not implemented")
+}
+func (UnimplementedTaskManagerServer) MigrateTask(context.Context, *MigrateTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MigrateTask not implemented")
+}
+func (UnimplementedTaskManagerServer) PauseTask(context.Context, *PauseTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseTask not implemented")
+}
+func (UnimplementedTaskManagerServer) ResumeTask(context.Context, *ResumeTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeTask not implemented")
+}
+func (UnimplementedTaskManagerServer) WatchTasks(*WatchTasksRequest, TaskManager_WatchTasksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTasks not implemented")
+}
+func (UnimplementedTaskManagerServer) WatchWorkers(*WatchWorkersRequest, TaskManager_WatchWorkersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchWorkers not implemented")
+}
+func (UnimplementedTaskManagerServer) mustEmbedUnimplementedTaskManagerServer() {}
+
+// UnsafeTaskManagerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TaskManagerServer will
+// result in compilation errors.
+type UnsafeTaskManagerServer interface {
+	mustEmbedUnimplementedTaskManagerServer()
+}
+
+func RegisterTaskManagerServer(s grpc.ServiceRegistrar, srv TaskManagerServer) {
+	s.RegisterService(&TaskManager_ServiceDesc, srv)
+}
+
+func _TaskManager_CreateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).CreateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_CreateTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).CreateTask(ctx, req.(*CreateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_CancelTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).CancelTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_CancelTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).CancelTask(ctx, req.(*CancelTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_MigrateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).MigrateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_MigrateTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).MigrateTask(ctx, req.(*MigrateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_PauseTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).PauseTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_PauseTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).PauseTask(ctx, req.(*PauseTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_ResumeTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServer).ResumeTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManager_ResumeTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServer).ResumeTask(ctx, req.(*ResumeTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManager_WatchTasks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTasksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TaskManagerServer).WatchTasks(m, &taskManagerWatchTasksServer{stream})
+}
+
+type TaskManager_WatchTasksServer interface {
+	Send(*TaskEvent) error
+	grpc.ServerStream
+}
+
+type taskManagerWatchTasksServer struct {
+	grpc.ServerStream
+}
+
+func (x *taskManagerWatchTasksServer) Send(m *TaskEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TaskManager_WatchWorkers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchWorkersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TaskManagerServer).WatchWorkers(m, &taskManagerWatchWorkersServer{stream})
+}
+
+type TaskManager_WatchWorkersServer interface {
+	Send(*WorkerEvent) error
+	grpc.ServerStream
+}
+
+type taskManagerWatchWorkersServer struct {
+	grpc.ServerStream
+}
+
+func (x *taskManagerWatchWorkersServer) Send(m *WorkerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// TaskManager_ServiceDesc is the grpc.ServiceDesc for TaskManager service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TaskManager_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "taskmanager.v1.TaskManager",
+	HandlerType: (*TaskManagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTask",
+			Handler:    _TaskManager_CreateTask_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _TaskManager_GetTask_Handler,
+		},
+		{
+			MethodName: "CancelTask",
+			Handler:    _TaskManager_CancelTask_Handler,
+		},
+		{
+			MethodName: "MigrateTask",
+			Handler:    _TaskManager_MigrateTask_Handler,
+		},
+		{
+			MethodName: "PauseTask",
+			Handler:    _TaskManager_PauseTask_Handler,
+		},
+		{
+			MethodName: "ResumeTask",
+			Handler:    _TaskManager_ResumeTask_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTasks",
+			Handler:       _TaskManager_WatchTasks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchWorkers",
+			Handler:       _TaskManager_WatchWorkers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "taskmanager.proto",
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate schreibt ein selbstsigniertes Zertifikat mit Schlüssel als PEM
+func writeTestCertificate(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	writeTestFile(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeTestFile(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return certFile, keyFile
+}
+
+func writeTestFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerTLSConfigFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "task-manager")
+	caFile, _ := writeTestCertificate(t, dir, "client-ca")
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	writeTestFile(t, invalidFile, "kein Zertifikat")
+
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantTLS        bool
+		wantClientAuth tls.ClientAuthType
+		wantErr        string
+	}{
+		{name: "ohne TLS", env: map[string]string{}},
+		{
+			name:    "Zertifikat und Schlüssel",
+			env:     map[string]string{"TLS_CERT_FILE": certFile, "TLS_KEY_FILE": keyFile},
+			wantTLS: true,
+		},
+		{
+			name:           "mTLS",
+			env:            map[string]string{"TLS_CERT_FILE": certFile, "TLS_KEY_FILE": keyFile, "TLS_CLIENT_CA_FILE": caFile},
+			wantTLS:        true,
+			wantClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:    "Client-CA ohne Zertifikat",
+			env:     map[string]string{"TLS_CLIENT_CA_FILE": caFile},
+			wantErr: "TLS_CLIENT_CA_FILE erfordert",
+		},
+		{
+			name:    "Schlüssel fehlt",
+			env:     map[string]string{"TLS_CERT_FILE": certFile},
+			wantErr: "TLS_CERT_FILE/TLS_KEY_FILE",
+		},
+		{
+			name:    "ungültige Client-CA",
+			env:     map[string]string{"TLS_CERT_FILE": certFile, "TLS_KEY_FILE": keyFile, "TLS_CLIENT_CA_FILE": invalidFile},
+			wantErr: "keine PEM-Zertifikate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CLIENT_CA_FILE"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			config, err := serverTLSConfigFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			if (config != nil) != tt.wantTLS {
+				t.Fatalf("TLS = %v, erwartet %v", config != nil, tt.wantTLS)
+			}
+			if config == nil {
+				return
+			}
+			if config.MinVersion != tls.VersionTLS12 || len(config.Certificates) != 1 {
+				t.Errorf("MinVersion %x, %d Zertifikate", config.MinVersion, len(config.Certificates))
+			}
+			if config.ClientAuth != tt.wantClientAuth {
+				t.Errorf("ClientAuth = %v, erwartet %v", config.ClientAuth, tt.wantClientAuth)
+			}
+		})
+	}
+}
+
+func TestNewRedisClientTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _ := writeTestCertificate(t, dir, "redis-ca")
+	certFile, keyFile := writeTestCertificate(t, dir, "task-manager")
+
+	tests := []struct {
+		name           string
+		url            string
+		env            map[string]string
+		wantAddr       string
+		wantTLS        bool
+		wantServerName string
+		wantCerts      int
+		wantErr        string
+	}{
+		{name: "host:port", url: "redis:6379", wantAddr: "redis:6379"},
+		{name: "redis-URL", url: "redis://redis:6379/0", wantAddr: "redis:6379"},
+		{name: "rediss-URL", url: "rediss://redis.example:6380", wantAddr: "redis.example:6380", wantTLS: true, wantServerName: "redis.example"},
+		{
+			name:           "REDIS_TLS mit CA und Client-Zertifikat",
+			url:            "redis:6379",
+			env:            map[string]string{"REDIS_TLS": "true", "REDIS_TLS_CA_FILE": caFile, "REDIS_TLS_CERT_FILE": certFile, "REDIS_TLS_KEY_FILE": keyFile},
+			wantAddr:       "redis:6379",
+			wantTLS:        true,
+			wantServerName: "redis",
+			wantCerts:      1,
+		},
+		{
+			name:           "eigener Servername",
+			url:            "rediss://10.0.0.5:6380",
+			env:            map[string]string{"REDIS_TLS_SERVER_NAME": "redis.internal"},
+			wantAddr:       "10.0.0.5:6380",
+			wantTLS:        true,
+			wantServerName: "redis.internal",
+		},
+		{
+			name:    "CA-Datei fehlt",
+			url:     "rediss://redis:6380",
+			env:     map[string]string{"REDIS_TLS_CA_FILE": filepath.Join(dir, "fehlt.pem")},
+			wantErr: "REDIS_TLS_CA_FILE",
+		},
+		{name: "ungültige URL", url: "http://redis:6379", wantErr: "Ungültige REDIS_URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"REDIS_TLS", "REDIS_TLS_CA_FILE", "REDIS_TLS_CERT_FILE", "REDIS_TLS_KEY_FILE", "REDIS_TLS_SERVER_NAME"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			client, err := newRedisClient(tt.url)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			}
+			defer client.Close()
+
+			options := client.Options()
+			if options.Addr != tt.wantAddr {
+				t.Errorf("Addr = %q, erwartet %q", options.Addr, tt.wantAddr)
+			}
+			if (options.TLSConfig != nil) != tt.wantTLS {
+				t.Fatalf("TLS = %v, erwartet %v", options.TLSConfig != nil, tt.wantTLS)
+			}
+			if options.TLSConfig == nil {
+				return
+			}
+			if options.TLSConfig.ServerName != tt.wantServerName {
+				t.Errorf("ServerName = %q, erwartet %q", options.TLSConfig.ServerName, tt.wantServerName)
+			}
+			if len(options.TLSConfig.Certificates) != tt.wantCerts {
+				t.Errorf("%d Client-Zertifikate, erwartet %d", len(options.TLSConfig.Certificates), tt.wantCerts)
+			}
+			if (tt.env["REDIS_TLS_CA_FILE"] != "") != (options.TLSConfig.RootCAs != nil) {
+				t.Errorf("RootCAs = %v bei REDIS_TLS_CA_FILE %q", options.TLSConfig.RootCAs, tt.env["REDIS_TLS_CA_FILE"])
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// tracer erzeugt die Spans des Task-Managers; ohne konfigurierten Exporter sind sie
+// wirkungslos, eingehende Trace-Kontexte werden aber trotzdem weitergegeben
+var tracer = otel.Tracer("github.com/scimbe/distributed-task-demo-system/task-manager")
+
+// untracedRoutes sind langlebige Verbindungen, für die ein Span keine sinnvolle Dauer hätte
+var untracedRoutes = map[string]bool{
+	"/ws":                true,
+	"/api/events/stream": true,
+}
+
+// setupTracing richtet OpenTelemetry nach TRACING_EXPORTER ein:
+//   - "otlp": Export per OTLP/HTTP, konfiguriert über OTEL_EXPORTER_OTLP_ENDPOINT usw.
+//   - "file": eine JSON-Zeile pro Span in TRACING_FILE (Standard traces.json)
+//   - leer oder "none": keine Spans, nur Weitergabe des Trace-Kontexts
+//
+// Die zurückgegebene Funktion exportiert beim Beenden die noch gepufferten Spans.
+func setupTracing(serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	mode := envString("TRACING_EXPORTER", "none")
+	switch mode {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var err error
+		if exporter, err = otlptracehttp.New(context.Background()); err != nil {
+			return nil, fmt.Errorf("OTLP-Exporter: %w", err)
+		}
+	case "file":
+		path := envString("TRACING_FILE", "traces.json")
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("Trace-Datei %s: %w", path, err)
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(file)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unbekannter TRACING_EXPORTER %q (erlaubt: otlp, file, none)", mode)
+	}
+
+	// OTEL_SERVICE_NAME und OTEL_RESOURCE_ATTRIBUTES haben Vorrang vor dem Standardnamen
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	tracingLogger.Infof("Tracing aktiv (Exporter: %s)", mode)
+	return provider.Shutdown, nil
+}
+
+// amqpHeaderCarrier überträgt den Trace-Kontext in den Headern einer AMQP-Nachricht
+type amqpHeaderCarrier amqp.Table
+
+func (c amqpHeaderCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c amqpHeaderCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// traceHeaders liefert die AMQP-Header mit dem Trace-Kontext von ctx (traceparent, tracestate)
+func traceHeaders(ctx context.Context) amqp.Table {
+	headers := amqp.Table{}
+	otel.GetTextMapPropagator().Inject(ctx, amqpHeaderCarrier(headers))
+	return headers
+}
+
+// grpcMetadataCarrier liest den Trace-Kontext aus den Metadaten eines gRPC-Aufrufs
+type grpcMetadataCarrier metadata.MD
+
+func (c grpcMetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startGRPCSpan setzt den Trace des Aufrufers fort, sofern er einen mitgeschickt hat
+func startGRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, grpcMetadataCarrier(md))
+	}
+	service, method := splitGRPCMethod(fullMethod)
+	return tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("grpc"),
+			semconv.RPCServiceKey.String(service),
+			semconv.RPCMethodKey.String(method),
+		))
+}
+
+// splitGRPCMethod zerlegt "/paket.Service/Methode" in Service und Methode
+func splitGRPCMethod(fullMethod string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)
+	if len(parts) != 2 {
+		return "", fullMethod
+	}
+	return parts[0], parts[1]
+}
+
+// endSpan schließt einen Span ab und markiert ihn bei einem Fehler als fehlgeschlagen
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingMiddleware startet für jede Anfrage einen Span, der einen per traceparent
+// mitgeschickten Trace fortsetzt. Die Trace-ID steht im Antwort-Header X-Trace-Id.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if t, err := route.GetPathTemplate(); err == nil {
+				template = t
+			}
+		}
+		if untracedRoutes[template] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+template,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(r.Method),
+				semconv.HTTPRouteKey.String(template),
+			))
+		defer span.End()
+		if span.SpanContext().HasTraceID() {
+			w.Header().Set("X-Trace-Id", span.SpanContext().TraceID().String())
+		}
+
+		recorder := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+	})
+}
+
+// taskAttributes sind die Span-Attribute eines Tasks
+func taskAttributes(task *Task, namespace string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("task.id", task.ID),
+		attribute.String("task.type", task.Type),
+		attribute.String("task.namespace", namespace),
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testSpansOnce sync.Once
+	testSpans     *tracetest.SpanRecorder
+)
+
+// recordSpans richtet einmalig einen TracerProvider ein, der alle Spans aufzeichnet.
+// Der globale tracer wird nur beim ersten SetTracerProvider umgelenkt.
+func recordSpans() *tracetest.SpanRecorder {
+	testSpansOnce.Do(func() {
+		testSpans = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(testSpans)))
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	})
+	return testSpans
+}
+
+// endedSpans liefert die beendeten Spans eines Traces
+func endedSpans(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recordSpans().Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func TestTraceHeaders(t *testing.T) {
+	recordSpans()
+	ctx, span := tracer.Start(context.Background(), "test")
+	defer span.End()
+	sc := span.SpanContext()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "mit Span", ctx: ctx, want: "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"},
+		{name: "ohne Span", ctx: context.Background(), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := traceHeaders(tt.ctx)
+			if got := amqpHeaderCarrier(headers).Get("traceparent"); got != tt.want {
+				t.Errorf("traceparent = %q, erwartet %q", got, tt.want)
+			}
+			if tt.want == "" && len(headers) != 0 {
+				t.Errorf("Header %v, erwartet keine", headers)
+			}
+		})
+	}
+}
+
+func TestAMQPHeaderCarrier(t *testing.T) {
+	carrier := amqpHeaderCarrier(amqp.Table{"traceparent": []byte("00-abc"), "tracestate": "a=b"})
+	if got := carrier.Get("traceparent"); got != "" {
+		t.Errorf("Get liefert %q für einen Nicht-String-Header", got)
+	}
+	if got := carrier.Get("tracestate"); got != "a=b" {
+		t.Errorf("Get(tracestate) = %q", got)
+	}
+	if got := carrier.Get("fehlt"); got != "" {
+		t.Errorf("Get(fehlt) = %q", got)
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recordSpans()
+	const parentTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentSpan = "00f067aa0ba902b7"
+
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantSpan    string
+		wantStatus  codes.Code
+		// wantParent: der Span setzt den mitgeschickten Trace fort
+		wantParent bool
+	}{
+		{name: "neuer Trace", path: "/api/tasks/t1", wantSpan: "GET /api/tasks/{id}", wantStatus: codes.Unset},
+		{
+			name:        "mitgeschickter Trace",
+			path:        "/api/tasks/t1",
+			traceparent: "00-" + parentTrace + "-" + parentSpan + "-01",
+			wantSpan:    "GET /api/tasks/{id}",
+			wantStatus:  codes.Unset,
+			wantParent:  true,
+		},
+		{name: "Serverfehler", path: "/api/fail", wantSpan: "GET /api/fail", wantStatus: codes.Error},
+		{name: "WebSocket ohne Span", path: "/ws"},
+	}
+
+	r := mux.NewRouter()
+	r.Use(TracingMiddleware)
+	r.HandleFunc("/api/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		// Der Handler sieht den Span der Anfrage
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("kein Span im Kontext des Handlers")
+		}
+	})
+	r.HandleFunc("/api/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "kaputt", http.StatusInternalServerError)
+	})
+	r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			header := rec.Header().Get("X-Trace-Id")
+			if tt.wantSpan == "" {
+				if header != "" {
+					t.Errorf("X-Trace-Id = %q, erwartet keinen", header)
+				}
+				return
+			}
+			traceID, err := trace.TraceIDFromHex(header)
+			if err != nil {
+				t.Fatalf("ungültige X-Trace-Id %q: %v", header, err)
+			}
+			if tt.wantParent && header != parentTrace {
+				t.Errorf("X-Trace-Id = %s, erwartet den mitgeschickten Trace %s", header, parentTrace)
+			}
+
+			spans := endedSpans(traceID)
+			if len(spans) != 1 {
+				t.Fatalf("%d Spans im Trace, erwartet 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != tt.wantSpan {
+				t.Errorf("Span %q, erwartet %q", span.Name(), tt.wantSpan)
+			}
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("Status %v, erwartet %v", span.Status().Code, tt.wantStatus)
+			}
+			if got := span.Parent().SpanID().String(); tt.wantParent && got != parentSpan {
+				t.Errorf("Eltern-Span %s, erwartet %s", got, parentSpan)
+			}
+			if !tt.wantParent && span.Parent().IsValid() {
+				t.Errorf("unerwarteter Eltern-Span %s", span.Parent().SpanID())
+			}
+		})
+	}
+}
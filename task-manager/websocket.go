@@ -2,40 +2,92 @@ package main
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
-	
+	"sync/atomic"
+	"time"
+
 	"github.com/gorilla/websocket"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
 )
 
 // WebSocketHandler verwaltet die WebSocket-Verbindungen und Nachrichten
 type WebSocketHandler struct {
-	clients      map[*websocket.Conn]bool
-	broadcast    chan []byte
-	register     chan *websocket.Conn
-	unregister   chan *websocket.Conn
-	mutex        sync.Mutex
-	upgrader     websocket.Upgrader
+	clients    map[*wsClient]bool
+	unregister chan *wsClient
+	// mutex schützt clients, seq und replay; unter ihm werden Ereignisse nummeriert
+	// und in die Warteschlangen gestellt, damit jeder Client sie in Reihenfolge erhält
+	mutex       sync.Mutex
+	upgrader    websocket.Upgrader
+	config      wsConfig
+	stats       wsStats
+	subscribers map[chan Event]bool
+	subMutex    sync.RWMutex
+	// taskTypes merkt sich den Typ jedes bekannten Tasks, damit auch Ereignisse
+	// ohne vollständigen Task (z.B. Checkpoints) nach Task-Typ gefiltert werden können
+	taskTypes     map[string]string
+	taskTypeMutex sync.Mutex
+	// seq ist die Sequenznummer des zuletzt verteilten Ereignisses
+	seq uint64
+	// epoch unterscheidet Sequenznummern verschiedener Prozessläufe
+	epoch string
+	// replay hält die letzten Ereignisse für die Wiederaufnahme nach einem Verbindungsabbruch
+	replay []Event
+	// snapshotFunc liefert den aktuellen Zustand eines Namespace für neue Verbindungen
+	snapshotFunc func(namespace string) StateSnapshot
+	// commandHandler führt Befehle der Clients aus (der Router der REST-API)
+	commandHandler http.Handler
+	// progress fasst Fortschritts-Updates desselben Tasks zusammen
+	progress *progressCoalescer
+	// auth prüft beim Upgrade dieselben Zugangsdaten wie die REST-API
+	auth *Authenticator
+	// namespaces ordnet Ereignisse ihren Namespaces zu
+	namespaces *NamespaceRegistry
+}
+
+// StateSnapshot ist der Zustand aller Tasks und Worker, den neue Verbindungen zuerst erhalten
+type StateSnapshot struct {
+	Tasks   []*Task   `json:"tasks"`
+	Workers []*Worker `json:"workers"`
+}
+
+// Event ist ein verteiltes Ereignis in typisierter Form für interne Abonnenten
+// (z.B. die gRPC-Streams), die nicht mit dem serialisierten JSON arbeiten wollen
+type Event struct {
+	Type     string
+	TaskID   string
+	WorkerID string
+	TaskType string
+	// Namespaces sind die Namespaces, in denen das Ereignis sichtbar ist; nil bedeutet alle
+	Namespaces []string
+	// Seq ist die fortlaufende Sequenznummer, die auch WebSocket-Clients erhalten
+	Seq     uint64
+	Content interface{}
+	// Payload ist die serialisierte Nachricht, wie sie WebSocket-Clients erhalten
+	Payload []byte
 }
 
 // NewWebSocketHandler erstellt einen neuen WebSocketHandler
 func NewWebSocketHandler() *WebSocketHandler {
-	return &WebSocketHandler{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
-		mutex:      sync.Mutex{},
-		upgrader: websocket.Upgrader{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
-			// Erlaube alle Origins für einfachere Entwicklung
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		},
+	wsh := &WebSocketHandler{
+		clients:     make(map[*wsClient]bool),
+		unregister:  make(chan *wsClient),
+		mutex:       sync.Mutex{},
+		subscribers: make(map[chan Event]bool),
+		taskTypes:   make(map[string]string),
+		config:      loadWSConfig(),
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+	wsh.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     wsh.originAllowed,
 	}
+	wsh.progress = newProgressCoalescer(wsh.config.ProgressWindow, wsh.dispatchTaskUpdate)
+	return wsh
 }
 
 // Start startet den WebSocketHandler
@@ -43,127 +95,578 @@ func (wsh *WebSocketHandler) Start() {
 	go func() {
 		for {
 			select {
-			case client := <-wsh.register:
-				wsh.mutex.Lock()
-				wsh.clients[client] = true
-				wsh.mutex.Unlock()
-				log.Printf("Neue WebSocket-Verbindung registriert. Aktive Verbindungen: %d", len(wsh.clients))
-			
 			case client := <-wsh.unregister:
 				wsh.mutex.Lock()
-				if _, ok := wsh.clients[client]; ok {
-					delete(wsh.clients, client)
-					client.Close()
-				}
+				delete(wsh.clients, client)
+				active := len(wsh.clients)
 				wsh.mutex.Unlock()
-				log.Printf("WebSocket-Verbindung geschlossen. Aktive Verbindungen: %d", len(wsh.clients))
-			
-			case message := <-wsh.broadcast:
-				wsh.mutex.Lock()
-				for client := range wsh.clients {
-					err := client.WriteMessage(websocket.TextMessage, message)
-					if err != nil {
-						log.Printf("Fehler beim Senden der WebSocket-Nachricht: %v", err)
-						client.Close()
-						delete(wsh.clients, client)
-					}
+				client.close()
+				if dropped := client.droppedCount(); dropped > 0 {
+					websocketLogger.Warnf("WebSocket-Client hat %d Nachrichten wegen voller Warteschlange verpasst", dropped)
 				}
-				wsh.mutex.Unlock()
+				websocketLogger.Infof("WebSocket-Verbindung geschlossen. Aktive Verbindungen: %d", active)
 			}
 		}
 	}()
 }
 
-// HandleWebSocket ist der HTTP-Handler für die WebSocket-Verbindungen
+// SetSnapshotFunc legt fest, woher neue Verbindungen ihren Anfangszustand erhalten
+func (wsh *WebSocketHandler) SetSnapshotFunc(snapshotFunc func(namespace string) StateSnapshot) {
+	wsh.snapshotFunc = snapshotFunc
+}
+
+// SetNamespaces legt fest, wie Ereignisse ihren Namespaces zugeordnet werden
+func (wsh *WebSocketHandler) SetNamespaces(namespaces *NamespaceRegistry) {
+	wsh.namespaces = namespaces
+}
+
+// SetAuthenticator legt fest, wie Verbindungen beim Upgrade authentifiziert werden
+func (wsh *WebSocketHandler) SetAuthenticator(auth *Authenticator) {
+	wsh.auth = auth
+}
+
+// originAllowed prüft den Origin-Header gegen WS_ALLOWED_ORIGINS. Anfragen ohne
+// Origin stammen nicht aus einem Browser und werden nur über das Token geprüft.
+func (wsh *WebSocketHandler) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(wsh.config.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range wsh.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsToken liest das Token einer Upgrade-Anfrage. Browser können keine Header setzen
+// und übergeben es daher als Subprotokoll ("bearer", "<token>") oder als Parameter
+// ?token=; andere Clients können auch "Authorization: Bearer <token>" verwenden.
+// subprotocol ist das Subprotokoll, das der Server bestätigen muss.
+func wsToken(r *http.Request) (token string, subprotocol string) {
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == "bearer" && i+1 < len(protocols) {
+			return protocols[i+1], "bearer"
+		}
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, ""
+	}
+	return bearerToken(r), ""
+}
+
+// SetCommandHandler legt fest, über welchen Handler Befehle der Clients ausgeführt werden
+func (wsh *WebSocketHandler) SetCommandHandler(handler http.Handler) {
+	wsh.commandHandler = handler
+}
+
+// HandleWebSocket ist der HTTP-Handler für die WebSocket-Verbindungen.
+//
+// Neue Verbindungen erhalten nach der Begrüßung einen Snapshot aller Tasks und
+// Worker. Mit ?epoch=<epoch>&since=<seq> setzt ein Client nach einem Abbruch
+// lückenlos fort; sind die Ereignisse nicht mehr im Puffer, folgt ein Snapshot.
+// Die Query-Parameter task_id, worker_id, type und task_type setzen die
+// anfänglichen Abonnements; namespace wählt den Namespace der Verbindung.
 func (wsh *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := wsh.upgrader.Upgrade(w, r, nil)
+	if !wsh.originAllowed(r) {
+		websocketLogger.Warnf("WebSocket-Verbindung von nicht erlaubtem Origin %q abgelehnt", r.Header.Get("Origin"))
+		wsh.stats.add(&wsh.stats.ConnectionsRejected)
+		http.Error(w, "Origin nicht erlaubt", http.StatusForbidden)
+		return
+	}
+
+	token, subprotocol := wsToken(r)
+	principal := anonymousPrincipal
+	if wsh.auth.Enabled() {
+		p, err := wsh.auth.Authenticate(token)
+		if err != nil {
+			websocketLogger.Warnf("WebSocket-Verbindung ohne gültige Zugangsdaten abgelehnt (%s)", r.RemoteAddr)
+			wsh.stats.add(&wsh.stats.ConnectionsRejected)
+			writeUnauthorized(w, err)
+			return
+		}
+		principal = p
+	}
+	namespace := namespaceFromContext(r.Context())
+	if !principal.allows(namespace) {
+		websocketLogger.Warnf("WebSocket-Verbindung von %s ohne Zugriff auf Namespace %s abgelehnt", principal.Name, namespace)
+		wsh.stats.add(&wsh.stats.ConnectionsRejected)
+		http.Error(w, "Kein Zugriff auf Namespace "+namespace, http.StatusForbidden)
+		return
+	}
+
+	var responseHeader http.Header
+	if subprotocol != "" {
+		responseHeader = http.Header{"Sec-Websocket-Protocol": {subprotocol}}
+	}
+	conn, err := wsh.upgrader.Upgrade(w, r, responseHeader)
 	if err != nil {
-		log.Printf("Fehler beim Upgrade der WebSocket-Verbindung: %v", err)
+		websocketLogger.Errorf("Fehler beim Upgrade der WebSocket-Verbindung: %v", err)
 		return
 	}
-	
-	// Registriere neue Verbindung
-	wsh.register <- conn
-	
-	// Sende eine Begrüßungsnachricht
-	welcomeMsg := map[string]interface{}{
-		"type": "welcome",
-		"content": map[string]string{
-			"message": "Willkommen beim Task-Manager WebSocket-Server",
-		},
+
+	query := r.URL.Query()
+	client := newWSClient(conn, wsh.config, &wsh.stats, wsh.commandHandler)
+	client.token = token
+	client.remoteAddr = r.RemoteAddr
+	client.filter = eventFilter{
+		namespace: namespace,
+		taskIDs:   splitQueryValues(query["task_id"]),
+		workerIDs: splitQueryValues(query["worker_id"]),
+		types:     splitQueryValues(query["type"]),
+		taskTypes: splitQueryValues(query["task_type"]),
+	}
+	wsh.stats.add(&wsh.stats.ConnectionsOpened)
+
+	// Registrierung und Replay unter demselben Lock, damit kein Ereignis fehlt oder doppelt kommt
+	resumeRequested := query.Get("since") != ""
+	wsh.mutex.Lock()
+	wsh.clients[client] = true
+	seq := wsh.seq
+	resumed := resumeRequested && wsh.resume(client, query.Get("epoch"), query.Get("since"))
+	active := len(wsh.clients)
+	wsh.mutex.Unlock()
+	websocketLogger.Infof("Neue WebSocket-Verbindung registriert (%s, Namespace %s). Aktive Verbindungen: %d", principal.Name, namespace, active)
+
+	// Der Snapshot wird außerhalb des Locks erstellt, da die Aufrufer von dispatch
+	// teilweise die Locks des Task-Managers halten. Er enthält daher mindestens den
+	// Stand von seq; die danach folgenden Ereignisse liefern jeweils den vollständigen
+	// Zustand und können gefahrlos erneut angewendet werden.
+	if !resumed {
+		if snapshot, err := wsh.snapshotMessage(namespace, seq, resumeRequested); err == nil {
+			client.prepend(wsMessage{payload: snapshot})
+		} else {
+			websocketLogger.Errorf("Fehler beim Erstellen des Snapshots: %v", err)
+		}
 	}
-	
+
+	// Sende eine Begrüßungsnachricht
+	welcomeMsg := protocol.NewStreamMessage("welcome", map[string]interface{}{
+		"message": "Willkommen beim Task-Manager WebSocket-Server",
+		"epoch":   wsh.epoch,
+		"seq":     seq,
+	})
+
 	msgJSON, err := json.Marshal(welcomeMsg)
 	if err == nil {
-		conn.WriteMessage(websocket.TextMessage, msgJSON)
+		client.prepend(wsMessage{payload: msgJSON})
 	}
-	
+	client.start()
+
 	// Überwache Verbindung in einer Goroutine
 	go func() {
 		defer func() {
-			wsh.unregister <- conn
+			wsh.unregister <- client
 		}()
-		
+
 		for {
 			// Lese Nachrichten vom Client
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket-Lesefehler: %v", err)
-				}
+				client.readFailed(err)
 				break
 			}
-			// Wir nutzen derzeit keine Nachrichten vom Client
+			// Jede Nachricht zeigt ebenso wie ein Pong, dass der Client noch lebt
+			client.extendReadDeadline()
+			client.handleMessage(data)
 		}
 	}()
 }
 
-// BroadcastTaskUpdate sendet ein Task-Update an alle verbundenen Clients
-func (wsh *WebSocketHandler) BroadcastTaskUpdate(task *Task) {
-	msgPayload := map[string]interface{}{
-		"type": "task_update",
-		"taskId": task.ID,
-		"content": task,
+// resume stellt die seit since verpassten Ereignisse in die Warteschlange des Clients.
+// Liefert false, wenn das nicht lückenlos möglich ist. Muss unter wsh.mutex aufgerufen werden.
+func (wsh *WebSocketHandler) resume(client *wsClient, epoch, since string) bool {
+	if epoch != wsh.epoch {
+		// Sequenznummern aus einem früheren Prozesslauf
+		return false
+	}
+	last, err := strconv.ParseUint(since, 10, 64)
+	if err != nil || last > wsh.seq {
+		return false
 	}
-	
-	msgJSON, err := json.Marshal(msgPayload)
+	if len(wsh.replay) > 0 && last+1 < wsh.replay[0].Seq {
+		return false
+	}
+	if len(wsh.replay) == 0 && last != wsh.seq {
+		return false
+	}
+
+	var missed []wsMessage
+	for _, event := range wsh.replay {
+		if event.Seq > last && client.filter.matches(event) {
+			missed = append(missed, wsMessage{key: event.coalesceKey(), payload: event.Payload})
+		}
+	}
+	if len(missed) > wsh.config.SendBuffer {
+		// Mehr als die Warteschlange fasst: ein Snapshot ist günstiger als Lücken durch Überlauf
+		return false
+	}
+
+	resumed := protocol.NewStreamMessage("resumed", map[string]interface{}{
+		"since":    last,
+		"replayed": len(missed),
+	})
+	resumed.Seq = wsh.seq
+	resumeMsg, err := json.Marshal(resumed)
 	if err != nil {
-		log.Printf("Fehler beim Serialisieren des Task-Updates: %v", err)
-		return
+		return false
 	}
-	
-	wsh.broadcast <- msgJSON
+	client.prepend(append([]wsMessage{{payload: resumeMsg}}, missed...)...)
+	return true
 }
 
-// BroadcastWorkerUpdate sendet ein Worker-Update an alle verbundenen Clients
-func (wsh *WebSocketHandler) BroadcastWorkerUpdate(worker *Worker) {
-	msgPayload := map[string]interface{}{
-		"type": "worker_update",
-		"workerId": worker.ID,
-		"content": worker,
+// eventsBetween liefert die Ereignisse mit Sequenznummern zwischen from und to (beide
+// ausschließlich) aus dem Replay-Puffer. ok ist false, wenn sie dort nicht mehr vollständig liegen.
+func (wsh *WebSocketHandler) eventsBetween(from, to uint64) (events []Event, ok bool) {
+	wsh.mutex.Lock()
+	defer wsh.mutex.Unlock()
+
+	if len(wsh.replay) == 0 || from+1 < wsh.replay[0].Seq {
+		return nil, false
 	}
-	
-	msgJSON, err := json.Marshal(msgPayload)
-	if err != nil {
-		log.Printf("Fehler beim Serialisieren des Worker-Updates: %v", err)
-		return
+	for _, event := range wsh.replay {
+		if event.Seq > from && event.Seq < to {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+// snapshotMessage serialisiert den aktuellen Zustand eines Namespace als Snapshot-Nachricht
+func (wsh *WebSocketHandler) snapshotMessage(namespace string, seq uint64, resync bool) ([]byte, error) {
+	snapshot := StateSnapshot{Tasks: []*Task{}, Workers: []*Worker{}}
+	if wsh.snapshotFunc != nil {
+		snapshot = wsh.snapshotFunc(namespace)
+	}
+	msg := protocol.NewStreamMessage("snapshot", snapshot)
+	msg.Seq = seq
+	msg.Epoch = wsh.epoch
+	msg.Resync = resync
+	return json.Marshal(msg)
+}
+
+// Subscribe registriert einen internen Abonnenten für alle verteilten Ereignisse.
+// Ist der Puffer voll, werden Ereignisse für diesen Abonnenten verworfen, damit
+// ein langsamer Abonnent die Verteilung nicht blockiert. Die zurückgegebene
+// Funktion beendet das Abonnement.
+func (wsh *WebSocketHandler) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	wsh.subMutex.Lock()
+	wsh.subscribers[ch] = true
+	wsh.subMutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			wsh.subMutex.Lock()
+			delete(wsh.subscribers, ch)
+			wsh.subMutex.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// publish verteilt ein Ereignis an alle internen Abonnenten
+func (wsh *WebSocketHandler) publish(event Event) {
+	wsh.subMutex.RLock()
+	defer wsh.subMutex.RUnlock()
+
+	for ch := range wsh.subscribers {
+		select {
+		case ch <- event:
+		default:
+			websocketLogger.Warnf("Abonnent zu langsam, Ereignis %s verworfen", event.Type)
+		}
 	}
-	
-	wsh.broadcast <- msgJSON
+}
+
+// BroadcastTaskUpdate sendet ein Task-Update an alle verbundenen Clients.
+// Reine Fortschritts-Updates werden innerhalb von WS_PROGRESS_WINDOW zusammengefasst.
+func (wsh *WebSocketHandler) BroadcastTaskUpdate(task *Task) {
+	// Kopie verteilen, da der Task nach dem Aufruf weiter verändert werden kann
+	taskCopy := *task
+
+	wsh.taskTypeMutex.Lock()
+	wsh.taskTypes[task.ID] = task.Type
+	wsh.taskTypeMutex.Unlock()
+
+	wsh.progress.submit(&taskCopy)
+}
+
+// dispatchTaskUpdate verteilt ein Task-Update nach dem Zusammenfassen. Nach dem
+// Endstatus eines Tasks wird sein Typ vergessen, damit taskTypes nicht unbegrenzt wächst.
+func (wsh *WebSocketHandler) dispatchTaskUpdate(task *Task) {
+	wsh.dispatch(Event{Type: "task_update", TaskID: task.ID, WorkerID: task.WorkerID, TaskType: task.Type, Content: task})
+
+	if terminalTaskStates[task.Status] {
+		wsh.taskTypeMutex.Lock()
+		delete(wsh.taskTypes, task.ID)
+		wsh.taskTypeMutex.Unlock()
+	}
+}
+
+// BroadcastWorkerUpdate sendet ein Worker-Update an alle verbundenen Clients
+func (wsh *WebSocketHandler) BroadcastWorkerUpdate(worker *Worker) {
+	workerCopy := *worker
+	wsh.dispatch(Event{Type: "worker_update", WorkerID: worker.ID, Content: &workerCopy})
 }
 
 // BroadcastMessage sendet eine allgemeine Nachricht an alle verbundenen Clients
 func (wsh *WebSocketHandler) BroadcastMessage(messageType string, content interface{}) {
-	msgPayload := map[string]interface{}{
-		"type": messageType,
-		"content": content,
+	wsh.dispatch(Event{Type: messageType, TaskID: taskIDFromContent(content), Content: content})
+}
+
+// dispatch serialisiert ein Ereignis einmal und verteilt es an die WebSocket-Clients
+// und an alle internen Abonnenten. Die Nachrichten landen nur in den Warteschlangen
+// der Clients, daher blockiert dispatch nie auf langsamen Verbindungen.
+func (wsh *WebSocketHandler) dispatch(event Event) {
+	if event.TaskType == "" && event.TaskID != "" {
+		wsh.taskTypeMutex.Lock()
+		event.TaskType = wsh.taskTypes[event.TaskID]
+		wsh.taskTypeMutex.Unlock()
+	}
+	if wsh.namespaces != nil {
+		event.Namespaces = wsh.namespaces.eventNamespaces(event)
 	}
-	
-	msgJSON, err := json.Marshal(msgPayload)
+
+	wsh.mutex.Lock()
+	defer wsh.mutex.Unlock()
+
+	event.Seq = wsh.seq + 1
+	msgJSON, err := json.Marshal(event.envelope())
 	if err != nil {
-		log.Printf("Fehler beim Serialisieren der Nachricht: %v", err)
+		websocketLogger.Errorf("Fehler beim Serialisieren der Nachricht %s: %v", event.Type, err)
 		return
 	}
-	
-	wsh.broadcast <- msgJSON
+	event.Payload = msgJSON
+	wsh.seq = event.Seq
+
+	if len(wsh.replay) == wsh.config.ReplaySize {
+		copy(wsh.replay, wsh.replay[1:])
+		wsh.replay = wsh.replay[:len(wsh.replay)-1]
+	}
+	wsh.replay = append(wsh.replay, event)
+
+	wsh.publish(event)
+
+	msg := wsMessage{key: event.coalesceKey(), payload: msgJSON}
+	for client := range wsh.clients {
+		if !client.wants(event) {
+			continue
+		}
+		if !client.enqueue(msg) {
+			websocketLogger.Warnf("WebSocket-Client zu langsam (%d Nachrichten wartend), Verbindung wird getrennt", wsh.config.SendBuffer)
+			wsh.stats.add(&wsh.stats.ConnectionsDropped)
+			delete(wsh.clients, client)
+			go client.closeWith(websocket.CloseTryAgainLater, "Client zu langsam")
+		}
+	}
+}
+
+// coalesceKey bestimmt, welche wartenden Nachrichten dieses Ereignis ersetzen darf
+func (e Event) coalesceKey() string {
+	switch {
+	case e.Type == "worker_update":
+		return e.Type + ":" + e.WorkerID
+	case e.TaskID != "":
+		return e.Type + ":" + e.TaskID
+	}
+	return ""
+}
+
+// envelope baut die JSON-Hülle, die Clients empfangen
+func (e Event) envelope() protocol.StreamMessage {
+	msg := protocol.NewStreamMessage(e.Type, e.Content)
+	msg.Seq = e.Seq
+	msg.TaskID = e.TaskID
+	if e.Type == "worker_update" {
+		msg.WorkerID = e.WorkerID
+	}
+	return msg
+}
+
+// taskIDFromContent liest die Task-ID aus dem Inhalt allgemeiner Nachrichten, falls vorhanden
+func taskIDFromContent(content interface{}) string {
+	switch c := content.(type) {
+	case *Task:
+		return c.ID
+	case *protocol.Migration:
+		return c.TaskID
+	case map[string]interface{}:
+		for _, key := range []string{"taskId", "task_id"} {
+			if id, ok := c[key].(string); ok {
+				return id
+			}
+		}
+	case map[string]string:
+		if id, ok := c["taskId"]; ok {
+			return id
+		}
+		return c["task_id"]
+	}
+	return ""
+}
+
+// eventFilter schränkt die Ereignisse eines Empfängers ein. Innerhalb einer Dimension
+// genügt ein Treffer, über Dimensionen hinweg müssen alle gesetzten passen; leere
+// Mengen bedeuten "alle". Der Namespace ist kein Abonnement, sondern die Sichtbarkeit
+// des Empfängers; leer bedeutet alle Namespaces.
+type eventFilter struct {
+	namespace string
+	taskIDs   map[string]bool
+	workerIDs map[string]bool
+	types     map[string]bool
+	taskTypes map[string]bool
+}
+
+// matches prüft, ob ein Ereignis den Filter erfüllt
+func (f eventFilter) matches(event Event) bool {
+	if f.namespace != "" && event.Namespaces != nil && !containsString(event.Namespaces, f.namespace) {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return false
+	}
+	if len(f.taskIDs) > 0 && !f.taskIDs[event.TaskID] {
+		return false
+	}
+	if len(f.workerIDs) > 0 && !f.workerIDs[event.WorkerID] {
+		return false
+	}
+	if len(f.taskTypes) > 0 && !f.taskTypes[event.TaskType] {
+		return false
+	}
+	return true
+}
+
+// add ergänzt die Abonnements um die Werte einer subscribe-Nachricht
+func (f *eventFilter) add(msg clientMessage) {
+	f.taskIDs = addValues(f.taskIDs, msg.TaskIDs)
+	f.workerIDs = addValues(f.workerIDs, msg.WorkerIDs)
+	f.types = addValues(f.types, msg.EventTypes)
+	f.taskTypes = addValues(f.taskTypes, msg.TaskTypes)
+}
+
+// remove entfernt die Werte einer unsubscribe-Nachricht aus den Abonnements
+func (f *eventFilter) remove(msg clientMessage) {
+	removeValues(f.taskIDs, msg.TaskIDs)
+	removeValues(f.workerIDs, msg.WorkerIDs)
+	removeValues(f.types, msg.EventTypes)
+	removeValues(f.taskTypes, msg.TaskTypes)
+}
+
+// state liefert die Abonnements in sortierter Form für die Antwort an den Client
+func (f eventFilter) state() subscriptionState {
+	return subscriptionState{
+		TaskIDs:    sortedKeys(f.taskIDs),
+		WorkerIDs:  sortedKeys(f.workerIDs),
+		EventTypes: sortedKeys(f.types),
+		TaskTypes:  sortedKeys(f.taskTypes),
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+func addValues(set map[string]bool, values []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	for _, value := range values {
+		if value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
+
+func removeValues(set map[string]bool, values []string) {
+	for _, value := range values {
+		delete(set, value)
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// wsStats zählt Verbindungen und Nachrichten des WebSocketHandlers
+type wsStats struct {
+	ConnectionsOpened uint64 `json:"connectionsOpened"`
+	// ConnectionsRejected sind Upgrades mit nicht erlaubtem Origin oder ohne gültige Zugangsdaten
+	ConnectionsRejected uint64 `json:"connectionsRejected"`
+	// ConnectionsTimedOut sind Verbindungen ohne Pong oder mit abgelaufener Schreibfrist
+	ConnectionsTimedOut uint64 `json:"connectionsTimedOut"`
+	// ConnectionsDropped sind Verbindungen, die wegen voller Warteschlange oder Schreibfehlern getrennt wurden
+	ConnectionsDropped uint64 `json:"connectionsDropped"`
+	// MessagesTooLarge sind Verbindungen, die wegen zu großer Nachrichten getrennt wurden
+	MessagesTooLarge uint64 `json:"messagesTooLarge"`
+	// MessagesDropped sind wegen voller Warteschlangen verworfene oder ersetzte Nachrichten
+	MessagesDropped uint64 `json:"messagesDropped"`
+}
+
+func (s *wsStats) add(counter *uint64) {
+	atomic.AddUint64(counter, 1)
+}
+
+// snapshot liefert eine konsistent gelesene Kopie der Zähler
+func (s *wsStats) snapshot() wsStats {
+	return wsStats{
+		ConnectionsOpened:   atomic.LoadUint64(&s.ConnectionsOpened),
+		ConnectionsRejected: atomic.LoadUint64(&s.ConnectionsRejected),
+		ConnectionsTimedOut: atomic.LoadUint64(&s.ConnectionsTimedOut),
+		ConnectionsDropped:  atomic.LoadUint64(&s.ConnectionsDropped),
+		MessagesTooLarge:    atomic.LoadUint64(&s.MessagesTooLarge),
+		MessagesDropped:     atomic.LoadUint64(&s.MessagesDropped),
+	}
+}
+
+// activeConnections liefert die Zahl der verbundenen Clients
+func (wsh *WebSocketHandler) activeConnections() int {
+	wsh.mutex.Lock()
+	defer wsh.mutex.Unlock()
+	return len(wsh.clients)
+}
+
+// HandleStats ist der HTTP-Handler für GET /api/system/websocket
+func (wsh *WebSocketHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"activeConnections": wsh.activeConnections(),
+		"counters":          wsh.stats.snapshot(),
+		"progressUpdates":   wsh.progress.stats(),
+	})
+}
+
+// HandleEvents ist der HTTP-Handler für GET /api/system/events. Er liefert die Ereignisse
+// aus dem Replay-Puffer, die im Namespace der Anfrage sichtbar sind, in der Form, in der
+// WebSocket-Clients sie erhalten.
+func (wsh *WebSocketHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	filter := eventFilter{namespace: namespaceFromContext(r.Context())}
+	events := []json.RawMessage{}
+	wsh.mutex.Lock()
+	for _, event := range wsh.replay {
+		if filter.matches(event) {
+			events = append(events, event.Payload)
+		}
+	}
+	wsh.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
 }
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestWSHandler erstellt einen WebSocketHandler mit kleinem Replay-Puffer
+func newTestWSHandler(replaySize, sendBuffer int) *WebSocketHandler {
+	wsh := NewWebSocketHandler()
+	wsh.config.ReplaySize = replaySize
+	wsh.config.SendBuffer = sendBuffer
+	return wsh
+}
+
+// newTestWSClient erstellt einen Client ohne Verbindung; nur die Warteschlange wird verwendet
+func newTestWSClient(wsh *WebSocketHandler, filter eventFilter) *wsClient {
+	return &wsClient{
+		config: wsh.config,
+		stats:  &wsh.stats,
+		filter: filter,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// queuedMessages beschreibt die wartenden Nachrichten als "<type>:<seq>"
+func queuedMessages(t *testing.T, c *wsClient) []string {
+	t.Helper()
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	var messages []string
+	for _, msg := range c.queue {
+		var envelope struct {
+			Type string `json:"type"`
+			Seq  uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal(msg.payload, &envelope); err != nil {
+			t.Fatalf("ungültige Nachricht %s: %v", msg.payload, err)
+		}
+		messages = append(messages, fmt.Sprintf("%s:%d", envelope.Type, envelope.Seq))
+	}
+	return messages
+}
+
+func TestWebSocketResume(t *testing.T) {
+	tests := []struct {
+		name       string
+		replaySize int
+		sendBuffer int
+		events     []string
+		filter     eventFilter
+		epoch      string
+		since      string
+		// want sind die nachgelieferten Nachrichten; nil bedeutet, dass ein Snapshot nötig ist
+		want []string
+	}{
+		{
+			name:       "lückenlos",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a", "b", "c", "d"},
+			since:  "2",
+			want:   []string{"resumed:4", "c:3", "d:4"},
+		},
+		{
+			name:       "nichts verpasst",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a", "b"},
+			since:  "2",
+			want:   []string{"resumed:2"},
+		},
+		{
+			name:       "noch keine Ereignisse",
+			replaySize: 10, sendBuffer: 10,
+			since: "0",
+			want:  []string{"resumed:0"},
+		},
+		{
+			name:       "ältester Eintrag noch im Puffer",
+			replaySize: 3, sendBuffer: 10,
+			events: []string{"a", "b", "c", "d", "e"},
+			since:  "2",
+			want:   []string{"resumed:5", "c:3", "d:4", "e:5"},
+		},
+		{
+			name:       "aus dem Puffer verdrängt",
+			replaySize: 3, sendBuffer: 10,
+			events: []string{"a", "b", "c", "d", "e"},
+			since:  "1",
+		},
+		{
+			name:       "Filter",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a", "b", "a", "b"},
+			filter: eventFilter{types: map[string]bool{"b": true}},
+			since:  "0",
+			want:   []string{"resumed:4", "b:2", "b:4"},
+		},
+		{
+			name:       "mehr als die Warteschlange fasst",
+			replaySize: 10, sendBuffer: 2,
+			events: []string{"a", "b", "c"},
+			since:  "0",
+		},
+		{
+			name:       "Sequenznummer aus der Zukunft",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a"},
+			since:  "2",
+		},
+		{
+			name:       "keine Zahl",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a"},
+			since:  "x",
+		},
+		{
+			name:       "andere Epoche",
+			replaySize: 10, sendBuffer: 10,
+			events: []string{"a"},
+			epoch:  "alt",
+			since:  "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wsh := newTestWSHandler(tt.replaySize, tt.sendBuffer)
+			for _, eventType := range tt.events {
+				wsh.BroadcastMessage(eventType, map[string]string{})
+			}
+			epoch := tt.epoch
+			if epoch == "" {
+				epoch = wsh.epoch
+			}
+
+			client := newTestWSClient(wsh, tt.filter)
+			wsh.mutex.Lock()
+			resumed := wsh.resume(client, epoch, tt.since)
+			wsh.mutex.Unlock()
+
+			if tt.want == nil {
+				if resumed {
+					t.Fatalf("Wiederaufnahme trotz Lücke: %v", queuedMessages(t, client))
+				}
+				return
+			}
+			if !resumed {
+				t.Fatal("Wiederaufnahme abgelehnt")
+			}
+			if got := queuedMessages(t, client); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("Nachrichten = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketResumeBeforeQueuedEvents(t *testing.T) {
+	wsh := newTestWSHandler(10, 10)
+	wsh.BroadcastMessage("a", map[string]string{})
+	wsh.BroadcastMessage("b", map[string]string{})
+
+	// Ein Client, der bereits live Ereignisse erhält, bekommt die verpassten davor
+	client := newTestWSClient(wsh, eventFilter{})
+	wsh.mutex.Lock()
+	wsh.clients[client] = true
+	resumed := wsh.resume(client, wsh.epoch, "1")
+	wsh.mutex.Unlock()
+	if !resumed {
+		t.Fatal("Wiederaufnahme abgelehnt")
+	}
+	wsh.BroadcastMessage("c", map[string]string{})
+
+	want := []string{"resumed:2", "b:2", "c:3"}
+	if got := queuedMessages(t, client); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Nachrichten = %v, erwartet %v", got, want)
+	}
+}
+
+func TestEventFilterMatches(t *testing.T) {
+	event := Event{Type: "task_update", TaskID: "task-1", WorkerID: "worker-1", TaskType: "process_data"}
+	tests := []struct {
+		name  string
+		query map[string][]string
+		want  bool
+	}{
+		{"ohne Filter", nil, true},
+		{"Typ", map[string][]string{"type": {"task_update"}}, true},
+		{"anderer Typ", map[string][]string{"type": {"worker_update"}}, false},
+		{"kommagetrennt", map[string][]string{"task_id": {"task-2, task-1"}}, true},
+		{"mehrfach", map[string][]string{"worker_id": {"worker-2", "worker-1"}}, true},
+		{"anderer Worker", map[string][]string{"worker_id": {"worker-2"}}, false},
+		{"Task-Typ", map[string][]string{"task_type": {"process_data"}}, true},
+		{"anderer Task-Typ", map[string][]string{"task_type": {"send_email"}}, false},
+		{"alle Filter", map[string][]string{"type": {"task_update"}, "task_id": {"task-1"}, "worker_id": {"worker-1"}, "task_type": {"process_data"}}, true},
+		{"ein Filter passt nicht", map[string][]string{"type": {"task_update"}, "task_id": {"task-2"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := eventFilter{
+				taskIDs:   splitQueryValues(tt.query["task_id"]),
+				workerIDs: splitQueryValues(tt.query["worker_id"]),
+				types:     splitQueryValues(tt.query["type"]),
+				taskTypes: splitQueryValues(tt.query["task_type"]),
+			}
+			if got := filter.matches(event); got != tt.want {
+				t.Fatalf("matches = %v, erwartet %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventFilterSubscriptions(t *testing.T) {
+	var filter eventFilter
+	steps := []struct {
+		name    string
+		action  string
+		msg     clientMessage
+		want    subscriptionState
+		matches map[string]bool
+	}{
+		{
+			name:    "Task abonnieren",
+			action:  "subscribe",
+			msg:     clientMessage{TaskIDs: []string{"task-1", ""}},
+			want:    subscriptionState{TaskIDs: []string{"task-1"}, WorkerIDs: []string{}, EventTypes: []string{}, TaskTypes: []string{}},
+			matches: map[string]bool{"task-1": true, "task-2": false},
+		},
+		{
+			name:    "weiteren Task abonnieren",
+			action:  "subscribe",
+			msg:     clientMessage{TaskIDs: []string{"task-2"}, EventTypes: []string{"task_update"}},
+			want:    subscriptionState{TaskIDs: []string{"task-1", "task-2"}, WorkerIDs: []string{}, EventTypes: []string{"task_update"}, TaskTypes: []string{}},
+			matches: map[string]bool{"task-1": true, "task-2": true, "task-3": false},
+		},
+		{
+			name:    "Task abbestellen",
+			action:  "unsubscribe",
+			msg:     clientMessage{TaskIDs: []string{"task-1", "task-9"}},
+			want:    subscriptionState{TaskIDs: []string{"task-2"}, WorkerIDs: []string{}, EventTypes: []string{"task_update"}, TaskTypes: []string{}},
+			matches: map[string]bool{"task-1": false, "task-2": true},
+		},
+		{
+			name:    "letzten Task abbestellen",
+			action:  "unsubscribe",
+			msg:     clientMessage{TaskIDs: []string{"task-2"}},
+			want:    subscriptionState{TaskIDs: []string{}, WorkerIDs: []string{}, EventTypes: []string{"task_update"}, TaskTypes: []string{}},
+			matches: map[string]bool{"task-1": true, "task-3": true},
+		},
+	}
+	for _, step := range steps {
+		if step.action == "subscribe" {
+			filter.add(step.msg)
+		} else {
+			filter.remove(step.msg)
+		}
+		if got := filter.state(); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: Abonnements %+v, erwartet %+v", step.name, got, step.want)
+		}
+		for taskID, want := range step.matches {
+			if got := filter.matches(Event{Type: "task_update", TaskID: taskID}); got != want {
+				t.Fatalf("%s: matches(%s) = %v, erwartet %v", step.name, taskID, got, want)
+			}
+		}
+	}
+}
+
+func TestWebSocketUpgradeAuth(t *testing.T) {
+	auth := &Authenticator{verifiers: []Verifier{parseAPIKeys("ops:geheim")}}
+
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		auth           *Authenticator
+		origin         string
+		query          string
+		header         http.Header
+		subprotocols   []string
+		wantStatus     int
+		wantProtocol   string
+	}{
+		{name: "ohne Allowlist", origin: "http://evil.example", wantStatus: http.StatusSwitchingProtocols},
+		{name: "erlaubter Origin", allowedOrigins: []string{"http://ui.example"}, origin: "HTTP://UI.example", wantStatus: http.StatusSwitchingProtocols},
+		{name: "nicht erlaubter Origin", allowedOrigins: []string{"http://ui.example"}, origin: "http://evil.example", wantStatus: http.StatusForbidden},
+		{name: "Wildcard", allowedOrigins: []string{"*"}, origin: "http://evil.example", wantStatus: http.StatusSwitchingProtocols},
+		{name: "ohne Origin (kein Browser)", allowedOrigins: []string{"http://ui.example"}, wantStatus: http.StatusSwitchingProtocols},
+		{name: "ohne Token", auth: auth, wantStatus: http.StatusUnauthorized},
+		{name: "falsches Token", auth: auth, query: "?token=falsch", wantStatus: http.StatusUnauthorized},
+		{name: "Token als Parameter", auth: auth, query: "?token=geheim", wantStatus: http.StatusSwitchingProtocols},
+		{name: "Token im Authorization-Header", auth: auth, header: http.Header{"Authorization": {"Bearer geheim"}}, wantStatus: http.StatusSwitchingProtocols},
+		{name: "Token als Subprotokoll", auth: auth, subprotocols: []string{"bearer", "geheim"}, wantStatus: http.StatusSwitchingProtocols, wantProtocol: "bearer"},
+		{name: "Origin wird vor dem Token geprüft", auth: auth, allowedOrigins: []string{"http://ui.example"}, origin: "http://evil.example", query: "?token=geheim", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wsh := NewWebSocketHandler()
+			wsh.config.AllowedOrigins = tt.allowedOrigins
+			wsh.SetAuthenticator(tt.auth)
+			wsh.Start()
+			server := httptest.NewServer(http.HandlerFunc(wsh.HandleWebSocket))
+			defer server.Close()
+
+			header := http.Header{}
+			for key, values := range tt.header {
+				header[key] = values
+			}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: tt.subprotocols}
+			conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+tt.query, header)
+			if resp == nil {
+				t.Fatalf("keine Antwort: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Status %d, erwartet %d (%v)", resp.StatusCode, tt.wantStatus, err)
+			}
+			if conn == nil {
+				if got := wsh.stats.snapshot().ConnectionsRejected; got != 1 {
+					t.Errorf("ConnectionsRejected = %d, erwartet 1", got)
+				}
+				return
+			}
+			defer conn.Close()
+			if conn.Subprotocol() != tt.wantProtocol {
+				t.Errorf("Subprotokoll %q, erwartet %q", conn.Subprotocol(), tt.wantProtocol)
+			}
+		})
+	}
+}
+
+func TestWebSocketEventsBetween(t *testing.T) {
+	wsh := newTestWSHandler(3, 10)
+	for _, eventType := range []string{"a", "b", "c", "d", "e"} {
+		wsh.BroadcastMessage(eventType, map[string]string{})
+	}
+
+	tests := []struct {
+		name     string
+		from, to uint64
+		want     string
+		ok       bool
+	}{
+		{name: "im Puffer", from: 2, to: 5, want: "c,d", ok: true},
+		{name: "leer", from: 4, to: 5, want: "", ok: true},
+		{name: "bis zum Ende", from: 3, to: 99, want: "d,e", ok: true},
+		{name: "verdrängt", from: 1, to: 5, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok := wsh.eventsBetween(tt.from, tt.to)
+			var types []string
+			for _, e := range events {
+				types = append(types, e.Type)
+			}
+			if ok != tt.ok || strings.Join(types, ",") != tt.want {
+				t.Fatalf("eventsBetween = %v, %v, erwartet %q, %v", types, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWebSocketForgetsTaskTypeAfterTerminalStatus(t *testing.T) {
+	wsh := newTestWSHandler(10, 10)
+	typeOf := func(taskID string) (string, bool) {
+		wsh.taskTypeMutex.Lock()
+		defer wsh.taskTypeMutex.Unlock()
+		taskType, ok := wsh.taskTypes[taskID]
+		return taskType, ok
+	}
+
+	wsh.BroadcastTaskUpdate(&Task{ID: "a", Type: "sum", Status: "RUNNING"})
+	wsh.BroadcastTaskUpdate(&Task{ID: "b", Type: "sum", Status: "PAUSED"})
+	if taskType, _ := typeOf("a"); taskType != "sum" {
+		t.Fatalf("Typ von a = %q, erwartet sum", taskType)
+	}
+
+	wsh.BroadcastTaskUpdate(&Task{ID: "a", Type: "sum", Status: "COMPLETED"})
+	if _, ok := typeOf("a"); ok {
+		t.Fatal("Typ von a nach Endstatus noch vorhanden")
+	}
+	if _, ok := typeOf("b"); !ok {
+		t.Fatal("Typ von b trotz PAUSED entfernt")
+	}
+
+	// Das Update mit dem Endstatus selbst trägt den Typ noch
+	last := wsh.replay[len(wsh.replay)-1]
+	if last.TaskID != "a" || last.TaskType != "sum" {
+		t.Fatalf("letztes Ereignis = %s/%s, erwartet a/sum", last.TaskID, last.TaskType)
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// Verhalten bei voller Sendewarteschlange eines WebSocket-Clients
+const (
+	// overflowDropOldest verwirft die älteste wartende Nachricht
+	overflowDropOldest = "drop_oldest"
+	// overflowCoalesce ersetzt eine wartende Nachricht zum selben Task oder Worker
+	// durch die neuere und verwirft nur ohne passende Nachricht die älteste
+	overflowCoalesce = "coalesce"
+	// overflowDisconnect trennt den Client, damit er neu lädt statt Lücken zu haben
+	overflowDisconnect = "disconnect"
+)
+
+// wsConfig enthält die Einstellungen für WebSocket-Verbindungen
+type wsConfig struct {
+	// SendBuffer ist die maximale Anzahl wartender Nachrichten pro Client
+	SendBuffer int
+	// OverflowPolicy legt fest, was bei voller Warteschlange passiert
+	OverflowPolicy string
+	// WriteTimeout begrenzt die Dauer eines einzelnen Schreibvorgangs
+	WriteTimeout time.Duration
+	// PingInterval ist der Abstand der Pings an den Client
+	PingInterval time.Duration
+	// PongTimeout ist die Zeit ohne Pong oder Nachricht, nach der die Verbindung als tot gilt
+	PongTimeout time.Duration
+	// MaxMessageSize begrenzt die Größe eingehender Nachrichten in Bytes
+	MaxMessageSize int64
+	// ReplaySize ist die Anzahl der Ereignisse, die für Wiederaufnahmen vorgehalten werden
+	ReplaySize int
+	// ProgressWindow ist das Zeitfenster, in dem Fortschritts-Updates eines Tasks
+	// zusammengefasst werden (0 schaltet das Zusammenfassen ab)
+	ProgressWindow time.Duration
+	// AllowedOrigins sind die erlaubten Origins von Browsern ("*": alle, leer: alle)
+	AllowedOrigins []string
+	// MaxMessageRate begrenzt die Nachrichten pro Sekunde und Verbindung (0: unbegrenzt).
+	// Was darüber hinausgeht, wartet in der Warteschlange und unterliegt dem Überlaufverhalten.
+	MaxMessageRate int
+}
+
+// loadWSConfig liest die WebSocket-Einstellungen aus der Umgebung
+func loadWSConfig() wsConfig {
+	config := wsConfig{
+		SendBuffer:     envInt("WS_SEND_BUFFER", 256),
+		OverflowPolicy: envString("WS_OVERFLOW_POLICY", overflowCoalesce),
+		WriteTimeout:   envDuration("WS_WRITE_TIMEOUT", 10*time.Second),
+		PingInterval:   envDuration("WS_PING_INTERVAL", 30*time.Second),
+		PongTimeout:    envDuration("WS_PONG_TIMEOUT", 60*time.Second),
+		MaxMessageSize: int64(envInt("WS_MAX_MESSAGE_SIZE", 32*1024)),
+		ReplaySize:     envInt("WS_REPLAY_SIZE", 1000),
+		ProgressWindow: envDuration("WS_PROGRESS_WINDOW", 500*time.Millisecond),
+		MaxMessageRate: envInt("WS_MAX_MESSAGE_RATE", 100),
+	}
+	for _, origin := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.AllowedOrigins = append(config.AllowedOrigins, origin)
+		}
+	}
+	if len(config.AllowedOrigins) == 0 {
+		websocketLogger.Warnf("WS_ALLOWED_ORIGINS ist nicht gesetzt, WebSocket-Verbindungen von allen Origins sind erlaubt")
+	}
+
+	switch config.OverflowPolicy {
+	case overflowDropOldest, overflowCoalesce, overflowDisconnect:
+	default:
+		websocketLogger.Warnf("Unbekannte WS_OVERFLOW_POLICY %q, verwende %s", config.OverflowPolicy, overflowCoalesce)
+		config.OverflowPolicy = overflowCoalesce
+	}
+	if config.SendBuffer < 1 {
+		config.SendBuffer = 1
+	}
+	if config.ReplaySize < 1 {
+		config.ReplaySize = 1
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.PongTimeout <= config.PingInterval {
+		// Sonst gilt jede Verbindung kurz vor dem nächsten Ping als tot
+		websocketLogger.Warnf("WS_PONG_TIMEOUT muss größer als WS_PING_INTERVAL sein, verwende %s", 2*config.PingInterval)
+		config.PongTimeout = 2 * config.PingInterval
+	}
+	return config
+}
+
+// wsClient ist eine WebSocket-Verbindung mit den Abonnements des Clients.
+// Nachrichten werden in eine begrenzte Warteschlange gestellt und von einer
+// eigenen Goroutine geschrieben, damit ein langsamer Client niemanden blockiert.
+type wsClient struct {
+	conn   *websocket.Conn
+	config wsConfig
+	stats  *wsStats
+	// commands führt Befehle des Clients als interne REST-Aufrufe aus
+	commands http.Handler
+	// token wird an Befehle weitergereicht, damit sie wie REST-Aufrufe geprüft werden
+	token string
+	// remoteAddr ist die Adresse des Clients; Kontingente ohne Authentifizierung gelten je Adresse
+	remoteAddr string
+
+	// filter enthält die Abonnements; ein leerer Filter empfängt alle Ereignisse
+	filter      eventFilter
+	filterMutex sync.Mutex
+
+	queue      []wsMessage
+	queueMutex sync.Mutex
+	// dropped zählt verworfene oder ersetzte Nachrichten
+	dropped int
+	// notify weckt die Schreib-Goroutine; Kapazität 1 genügt, da sie die ganze Warteschlange leert
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// wsMessage ist eine serialisierte Nachricht in der Sendewarteschlange
+type wsMessage struct {
+	// key identifiziert Nachrichten, die sich gegenseitig ersetzen dürfen (leer: nie)
+	key     string
+	payload []byte
+}
+
+// clientMessage ist eine Nachricht vom Client an den Server, z.B.
+// {"action": "subscribe", "taskIds": ["..."], "eventTypes": ["task_update"]} oder
+// {"action": "cancel_task", "id": "42", "params": {"taskId": "..."}}
+type clientMessage struct {
+	Action string `json:"action"`
+	// ID ist die vom Client gewählte Korrelations-ID, die in der Antwort zurückkommt
+	ID         string          `json:"id,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	TaskIDs    []string        `json:"taskIds,omitempty"`
+	WorkerIDs  []string        `json:"workerIds,omitempty"`
+	EventTypes []string        `json:"eventTypes,omitempty"`
+	TaskTypes  []string        `json:"taskTypes,omitempty"`
+}
+
+// subscriptionState ist die Antwort auf subscribe/unsubscribe/reset mit den aktuellen Abonnements
+type subscriptionState struct {
+	TaskIDs    []string `json:"taskIds"`
+	WorkerIDs  []string `json:"workerIds"`
+	EventTypes []string `json:"eventTypes"`
+	TaskTypes  []string `json:"taskTypes"`
+}
+
+// newWSClient erstellt einen Client und setzt seine Lese-Limits. Die Schreib-Goroutine
+// startet erst mit start(), damit vorher Begrüßung und Snapshot eingereiht werden können.
+func newWSClient(conn *websocket.Conn, config wsConfig, stats *wsStats, commands http.Handler) *wsClient {
+	c := &wsClient{
+		conn:     conn,
+		config:   config,
+		stats:    stats,
+		commands: commands,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	conn.SetReadLimit(config.MaxMessageSize)
+	c.extendReadDeadline()
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+	return c
+}
+
+// start startet die Schreib-Goroutine
+func (c *wsClient) start() {
+	go c.writePump()
+}
+
+// extendReadDeadline verlängert die Lesefrist; ohne Pong oder Nachricht bis dahin
+// schlägt das Lesen fehl und die halb offene Verbindung wird entfernt
+func (c *wsClient) extendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.config.PongTimeout))
+}
+
+// enqueue stellt eine Nachricht in die Warteschlange, ohne zu blockieren. Der
+// Rückgabewert ist false, wenn der Client laut Überlaufverhalten getrennt werden muss.
+func (c *wsClient) enqueue(msg wsMessage) bool {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+
+	if len(c.queue) >= c.config.SendBuffer {
+		switch c.config.OverflowPolicy {
+		case overflowDisconnect:
+			return false
+		case overflowCoalesce:
+			if c.replaceQueued(msg) {
+				c.dropped++
+				c.stats.add(&c.stats.MessagesDropped)
+				return true
+			}
+		}
+		c.queue = c.queue[1:]
+		c.dropped++
+		c.stats.add(&c.stats.MessagesDropped)
+	}
+	c.queue = append(c.queue, msg)
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// prepend stellt Nachrichten unabhängig vom Limit vor alle wartenden Nachrichten
+func (c *wsClient) prepend(msgs ...wsMessage) {
+	c.queueMutex.Lock()
+	c.queue = append(append([]wsMessage(nil), msgs...), c.queue...)
+	c.queueMutex.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// replaceQueued ersetzt eine wartende Nachricht mit demselben Schlüssel.
+// Muss unter queueMutex aufgerufen werden.
+func (c *wsClient) replaceQueued(msg wsMessage) bool {
+	if msg.key == "" {
+		return false
+	}
+	for i := len(c.queue) - 1; i >= 0; i-- {
+		if c.queue[i].key == msg.key {
+			c.queue[i] = msg
+			return true
+		}
+	}
+	return false
+}
+
+// writePump schreibt die Nachrichten der Warteschlange und sendet regelmäßig Pings,
+// bis der Client geschlossen wird
+func (c *wsClient) writePump() {
+	ping := time.NewTicker(c.config.PingInterval)
+	defer ping.Stop()
+	limiter := newRateLimiter(c.config.MaxMessageRate)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.writeFailed(err)
+				return
+			}
+			continue
+		case <-c.notify:
+		}
+
+		// Einzeln entnehmen, damit bei gedrosseltem Senden wartende Nachrichten
+		// weiterhin zusammengefasst werden können
+		for {
+			msg, ok := c.pop()
+			if !ok {
+				break
+			}
+			if !limiter.wait(c.done) {
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg.payload); err != nil {
+				c.writeFailed(err)
+				return
+			}
+		}
+	}
+}
+
+// pop entnimmt die älteste wartende Nachricht
+func (c *wsClient) pop() (wsMessage, bool) {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	if len(c.queue) == 0 {
+		return wsMessage{}, false
+	}
+	msg := c.queue[0]
+	c.queue = c.queue[1:]
+	return msg, true
+}
+
+// writeFailed zählt einen Schreibfehler und schließt die Verbindung
+func (c *wsClient) writeFailed(err error) {
+	select {
+	case <-c.done:
+		// Verbindung wurde bereits absichtlich geschlossen
+		return
+	default:
+	}
+
+	if isTimeout(err) {
+		websocketLogger.Warnf("WebSocket-Client nimmt keine Daten an (Schreib-Timeout), Verbindung wird getrennt")
+		c.stats.add(&c.stats.ConnectionsTimedOut)
+	} else {
+		websocketLogger.Errorf("Fehler beim Senden der WebSocket-Nachricht: %v", err)
+		c.stats.add(&c.stats.ConnectionsDropped)
+	}
+	c.close()
+}
+
+// readFailed ordnet den Grund für das Ende der Leseschleife ein
+func (c *wsClient) readFailed(err error) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	switch {
+	case isTimeout(err):
+		websocketLogger.Warnf("WebSocket-Client antwortet nicht mehr (kein Pong innerhalb von %s)", c.config.PongTimeout)
+		c.stats.add(&c.stats.ConnectionsTimedOut)
+	case errors.Is(err, websocket.ErrReadLimit):
+		websocketLogger.Warnf("WebSocket-Nachricht größer als %d Bytes, Verbindung wird getrennt", c.config.MaxMessageSize)
+		c.stats.add(&c.stats.MessagesTooLarge)
+	case websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure):
+		websocketLogger.Warnf("WebSocket-Lesefehler: %v", err)
+	}
+}
+
+// isTimeout prüft, ob ein Fehler durch eine abgelaufene Frist entstanden ist
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// close beendet die Schreib-Goroutine und schließt die Verbindung; mehrfacher Aufruf ist erlaubt
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// closeWith sendet vor dem Schließen einen Close-Frame mit Grund an den Client
+func (c *wsClient) closeWith(code int, reason string) {
+	deadline := time.Now().Add(c.config.WriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.close()
+}
+
+// droppedCount liefert die Anzahl verworfener Nachrichten
+func (c *wsClient) droppedCount() int {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+	return c.dropped
+}
+
+// handleMessage verarbeitet eine Nachricht des Clients und beantwortet sie
+func (c *wsClient) handleMessage(data []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.reply("error", "", map[string]string{"message": "Ungültige Nachricht: " + err.Error()})
+		return
+	}
+
+	if command, ok := wsCommands[msg.Action]; ok {
+		c.executeCommand(msg, command)
+		return
+	}
+
+	c.filterMutex.Lock()
+	switch msg.Action {
+	case "subscribe":
+		c.filter.add(msg)
+	case "unsubscribe":
+		c.filter.remove(msg)
+	case "reset":
+		// Der Namespace gilt für die ganze Verbindung und bleibt erhalten
+		c.filter = eventFilter{namespace: c.filter.namespace}
+	default:
+		c.filterMutex.Unlock()
+		c.reply("error", msg.ID, map[string]string{"message": "Unbekannte Aktion: " + msg.Action})
+		return
+	}
+	state := c.filter.state()
+	c.filterMutex.Unlock()
+
+	c.reply("subscription", msg.ID, state)
+}
+
+// wants prüft, ob ein Ereignis zu den Abonnements des Clients passt
+func (c *wsClient) wants(event Event) bool {
+	c.filterMutex.Lock()
+	defer c.filterMutex.Unlock()
+	return c.filter.matches(event)
+}
+
+// send stellt eine bereits serialisierte Nachricht nur für diesen Client in die Warteschlange
+func (c *wsClient) send(payload []byte) {
+	if !c.enqueue(wsMessage{payload: payload}) {
+		c.stats.add(&c.stats.ConnectionsDropped)
+		c.closeWith(websocket.CloseTryAgainLater, "Client zu langsam")
+	}
+}
+
+// reply sendet eine Antwort nur an diesen Client; id ist die Korrelations-ID der Anfrage
+func (c *wsClient) reply(messageType, id string, content interface{}) {
+	msgPayload := protocol.NewStreamMessage(messageType, content)
+	msgPayload.ID = id
+	msgJSON, err := json.Marshal(msgPayload)
+	if err != nil {
+		websocketLogger.Errorf("Fehler beim Serialisieren der Nachricht %s: %v", messageType, err)
+		return
+	}
+	c.send(msgJSON)
+}
+
+// rateLimiter ist ein Token-Bucket, der höchstens rate Nachrichten pro Sekunde
+// zulässt und kurze Spitzen bis zu einer Sekunde ausgleicht
+type rateLimiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// wait blockiert, bis eine Nachricht gesendet werden darf. Liefert false, wenn
+// done vorher geschlossen wird.
+func (l *rateLimiter) wait(done <-chan struct{}) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return false
+		case <-timer.C:
+		}
+		l.tokens = 1
+		l.last = time.Now()
+	}
+	l.tokens--
+	return true
+}
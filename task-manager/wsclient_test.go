@@ -0,0 +1,282 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newQueueOnlyWSClient erstellt einen Client ohne Verbindung; nur die Warteschlange wird verwendet
+func newQueueOnlyWSClient(sendBuffer int, policy string) *wsClient {
+	return &wsClient{
+		config: wsConfig{SendBuffer: sendBuffer, OverflowPolicy: policy},
+		stats:  &wsStats{},
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// queuedPayloads liefert die wartenden Nutzdaten in Reihenfolge
+func queuedPayloads(c *wsClient) string {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	payloads := make([]string, 0, len(c.queue))
+	for _, msg := range c.queue {
+		payloads = append(payloads, string(msg.payload))
+	}
+	return strings.Join(payloads, ",")
+}
+
+func TestWSClientOverflow(t *testing.T) {
+	// Nachrichten als "<schlüssel>=<nutzdaten>"; ohne = haben sie keinen Schlüssel
+	message := func(spec string) wsMessage {
+		if parts := strings.SplitN(spec, "=", 2); len(parts) == 2 {
+			return wsMessage{key: parts[0], payload: []byte(parts[1])}
+		}
+		return wsMessage{payload: []byte(spec)}
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		messages []string
+		want     string
+		dropped  int
+		// disconnect ist true, wenn die letzte Nachricht zur Trennung führen muss
+		disconnect bool
+	}{
+		{
+			name:     "unter dem Limit",
+			policy:   overflowDisconnect,
+			messages: []string{"a", "b"},
+			want:     "a,b",
+		},
+		{
+			name:     "drop_oldest",
+			policy:   overflowDropOldest,
+			messages: []string{"t1=a", "b", "c", "t1=d"},
+			want:     "b,c,d",
+			dropped:  1,
+		},
+		{
+			name:     "coalesce ersetzt die jüngste Nachricht mit demselben Schlüssel",
+			policy:   overflowCoalesce,
+			messages: []string{"t1=a", "t2=b", "t1=c", "t1=d"},
+			want:     "a,b,d",
+			dropped:  1,
+		},
+		{
+			name:     "coalesce ohne passenden Schlüssel verwirft die älteste",
+			policy:   overflowCoalesce,
+			messages: []string{"t1=a", "t2=b", "c", "t3=d"},
+			want:     "b,c,d",
+			dropped:  1,
+		},
+		{
+			// d ersetzt a an dessen Platz und ist damit beim nächsten Überlauf die älteste
+			name:     "coalesce ohne Schlüssel verwirft die älteste",
+			policy:   overflowCoalesce,
+			messages: []string{"t1=a", "t2=b", "c", "t1=d", "e"},
+			want:     "b,c,e",
+			dropped:  2,
+		},
+		{
+			name:       "disconnect",
+			policy:     overflowDisconnect,
+			messages:   []string{"a", "b", "c", "d"},
+			want:       "a,b,c",
+			disconnect: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newQueueOnlyWSClient(3, tt.policy)
+
+			for i, spec := range tt.messages {
+				ok := client.enqueue(message(spec))
+				last := i == len(tt.messages)-1
+				if ok == (last && tt.disconnect) {
+					t.Fatalf("enqueue(%s) = %v", spec, ok)
+				}
+			}
+			if got := queuedPayloads(client); got != tt.want {
+				t.Fatalf("Warteschlange = %s, erwartet %s", got, tt.want)
+			}
+			if got := client.droppedCount(); got != tt.dropped {
+				t.Fatalf("verworfen = %d, erwartet %d", got, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestWSClientPrependIgnoresLimit(t *testing.T) {
+	wsh := newTestWSHandler(10, 2)
+	client := newTestWSClient(wsh, eventFilter{})
+	client.enqueue(wsMessage{payload: []byte("c")})
+	client.enqueue(wsMessage{payload: []byte("d")})
+	client.prepend(wsMessage{payload: []byte("a")}, wsMessage{payload: []byte("b")})
+
+	if got := queuedPayloads(client); got != "a,b,c,d" {
+		t.Fatalf("Warteschlange = %s, erwartet a,b,c,d", got)
+	}
+	if got := client.droppedCount(); got != 0 {
+		t.Fatalf("verworfen = %d, erwartet 0", got)
+	}
+}
+
+func TestWSClientEnqueueAfterClose(t *testing.T) {
+	client := newQueueOnlyWSClient(1, overflowDisconnect)
+	client.enqueue(wsMessage{payload: []byte("a")})
+	close(client.done)
+
+	// Ein bereits geschlossener Client wird nicht ein zweites Mal getrennt
+	if !client.enqueue(wsMessage{payload: []byte("b")}) {
+		t.Fatal("enqueue nach close = false")
+	}
+	if got := queuedPayloads(client); got != "a" {
+		t.Fatalf("Warteschlange = %s, erwartet a", got)
+	}
+}
+
+func TestLoadWSConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantPing     time.Duration
+		wantPong     time.Duration
+		wantMaxSize  int64
+		wantOverflow string
+	}{
+		{"Standard", nil, 30 * time.Second, 60 * time.Second, 32 * 1024, overflowCoalesce},
+		{"gesetzt", map[string]string{"WS_PING_INTERVAL": "5s", "WS_PONG_TIMEOUT": "12s", "WS_MAX_MESSAGE_SIZE": "1024"}, 5 * time.Second, 12 * time.Second, 1024, overflowCoalesce},
+		{"Pong-Timeout nicht größer als Ping-Intervall", map[string]string{"WS_PING_INTERVAL": "10s", "WS_PONG_TIMEOUT": "10s"}, 10 * time.Second, 20 * time.Second, 32 * 1024, overflowCoalesce},
+		{"ungültiges Ping-Intervall", map[string]string{"WS_PING_INTERVAL": "0s"}, 30 * time.Second, 60 * time.Second, 32 * 1024, overflowCoalesce},
+		{"unbekanntes Überlaufverhalten", map[string]string{"WS_OVERFLOW_POLICY": "block"}, 30 * time.Second, 60 * time.Second, 32 * 1024, overflowCoalesce},
+		{"disconnect", map[string]string{"WS_OVERFLOW_POLICY": overflowDisconnect}, 30 * time.Second, 60 * time.Second, 32 * 1024, overflowDisconnect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			config := loadWSConfig()
+			if config.PingInterval != tt.wantPing || config.PongTimeout != tt.wantPong ||
+				config.MaxMessageSize != tt.wantMaxSize || config.OverflowPolicy != tt.wantOverflow {
+				t.Fatalf("Konfiguration %+v", config)
+			}
+		})
+	}
+}
+
+// startTestWSServer startet einen WebSocket-Server mit kurzen Fristen
+func startTestWSServer(t *testing.T) (*WebSocketHandler, string) {
+	t.Helper()
+	wsh := NewWebSocketHandler()
+	wsh.config.PingInterval = 50 * time.Millisecond
+	wsh.config.PongTimeout = 500 * time.Millisecond
+	wsh.config.MaxMessageSize = 256
+	wsh.Start()
+	server := httptest.NewServer(http.HandlerFunc(wsh.HandleWebSocket))
+	t.Cleanup(server.Close)
+	return wsh, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// waitForCounter wartet, bis ein Zähler den erwarteten Wert erreicht
+func waitForCounter(t *testing.T, name string, counter *uint64, want uint64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadUint64(counter) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s = %d, erwartet %d", name, atomic.LoadUint64(counter), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWSClientKeepalive(t *testing.T) {
+	tests := []struct {
+		name string
+		// client bedient die Verbindung des Clients
+		client        func(conn *websocket.Conn)
+		wantTimedOut  uint64
+		wantTooLarge  uint64
+		wantConnected bool
+	}{
+		{
+			// Lesen beantwortet Pings automatisch mit Pongs
+			name: "Client antwortet auf Pings",
+			client: func(conn *websocket.Conn) {
+				go func() {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+			},
+			wantConnected: true,
+		},
+		{
+			name:         "Client liest nicht und sendet keine Pongs",
+			client:       func(conn *websocket.Conn) {},
+			wantTimedOut: 1,
+		},
+		{
+			name: "Nachricht über dem Limit",
+			client: func(conn *websocket.Conn) {
+				go func() {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+				conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 1024)))
+			},
+			wantTooLarge: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wsh, url := startTestWSServer(t)
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			tt.client(conn)
+
+			if tt.wantConnected {
+				// Mehrere Pong-Timeouts lang verbunden bleiben
+				time.Sleep(3 * wsh.config.PongTimeout)
+			}
+			waitForCounter(t, "ConnectionsTimedOut", &wsh.stats.ConnectionsTimedOut, tt.wantTimedOut)
+			waitForCounter(t, "MessagesTooLarge", &wsh.stats.MessagesTooLarge, tt.wantTooLarge)
+
+			want := 0
+			if tt.wantConnected {
+				want = 1
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				wsh.mutex.Lock()
+				active := len(wsh.clients)
+				wsh.mutex.Unlock()
+				if active == want {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("%d aktive Verbindungen, erwartet %d", active, want)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
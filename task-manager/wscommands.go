@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// wsCommandTimeout begrenzt die Ausführungsdauer eines Befehls
+const wsCommandTimeout = 10 * time.Second
+
+// commandParams sind die Parameter eines Befehls über die WebSocket-Verbindung
+type commandParams struct {
+	TaskID   string                 `json:"taskId"`
+	WorkerID string                 `json:"workerId"`
+	Type     string                 `json:"type"`
+	Priority int                    `json:"priority"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// wsCommand übersetzt einen Befehl in den entsprechenden REST-Aufruf (immer POST).
+// Befehle laufen so durch dieselben Handler und Middlewares wie die REST-API.
+type wsCommand func(p commandParams) (path string, body interface{}, err error)
+
+// wsCommands sind die über die WebSocket-Verbindung verfügbaren Befehle
+var wsCommands = map[string]wsCommand{
+	"create_task": func(p commandParams) (string, interface{}, error) {
+		return "/api/tasks", CreateTaskRequest{Type: p.Type, Priority: p.Priority, Data: p.Data}, nil
+	},
+	"migrate_task": func(p commandParams) (string, interface{}, error) {
+		if p.TaskID == "" || p.WorkerID == "" {
+			return "", nil, errors.New("taskId und workerId sind erforderlich")
+		}
+		return "/api/tasks/" + url.PathEscape(p.TaskID) + "/migrate", map[string]string{"worker_id": p.WorkerID}, nil
+	},
+	"cancel_task":    taskCommand("cancel"),
+	"pause_task":     taskCommand("pause"),
+	"resume_task":    taskCommand("resume"),
+	"fail_worker":    workerCommand("fail"),
+	"recover_worker": workerCommand("recover"),
+}
+
+func taskCommand(operation string) wsCommand {
+	return func(p commandParams) (string, interface{}, error) {
+		if p.TaskID == "" {
+			return "", nil, errors.New("taskId ist erforderlich")
+		}
+		return "/api/tasks/" + url.PathEscape(p.TaskID) + "/" + operation, nil, nil
+	}
+}
+
+func workerCommand(operation string) wsCommand {
+	return func(p commandParams) (string, interface{}, error) {
+		if p.WorkerID == "" {
+			return "", nil, errors.New("workerId ist erforderlich")
+		}
+		return "/api/workers/" + url.PathEscape(p.WorkerID) + "/" + operation, nil, nil
+	}
+}
+
+// commandError ist der Inhalt eines Fehler-Frames zu einem Befehl
+type commandError struct {
+	Status  int             `json:"status"`
+	Message string          `json:"message,omitempty"`
+	Details json.RawMessage `json:"details,omitempty"`
+}
+
+// executeCommand führt einen Befehl aus und antwortet mit einem ack- oder error-Frame,
+// das die Korrelations-ID des Clients trägt
+func (c *wsClient) executeCommand(msg clientMessage, command wsCommand) {
+	if msg.ID == "" {
+		c.reply("error", "", commandError{Status: http.StatusBadRequest, Message: "Befehle benötigen eine id"})
+		return
+	}
+	if c.commands == nil {
+		c.reply("error", msg.ID, commandError{Status: http.StatusServiceUnavailable, Message: "Befehle sind nicht verfügbar"})
+		return
+	}
+
+	var params commandParams
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.reply("error", msg.ID, commandError{Status: http.StatusBadRequest, Message: "Ungültige Parameter: " + err.Error()})
+			return
+		}
+	}
+	path, body, err := command(params)
+	if err != nil {
+		c.reply("error", msg.ID, commandError{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	var bodyJSON []byte
+	if body != nil {
+		if bodyJSON, err = json.Marshal(body); err != nil {
+			c.reply("error", msg.ID, commandError{Status: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+	}
+
+	// Nicht den Kontext der Upgrade-Anfrage verwenden: er endet mit dem Handler
+	ctx, cancel := context.WithTimeout(context.Background(), wsCommandTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(bodyJSON))
+	if err != nil {
+		c.reply("error", msg.ID, commandError{Status: http.StatusInternalServerError, Message: err.Error()})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = c.remoteAddr
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	c.filterMutex.Lock()
+	req.Header.Set("X-Namespace", c.filter.namespace)
+	c.filterMutex.Unlock()
+
+	resp := newCommandResponse()
+	c.commands.ServeHTTP(resp, req)
+
+	if resp.status >= 200 && resp.status < 300 {
+		var result json.RawMessage
+		if json.Valid(resp.body.Bytes()) && resp.body.Len() > 0 {
+			result = resp.body.Bytes()
+		}
+		c.reply("ack", msg.ID, result)
+		return
+	}
+
+	cmdErr := commandError{Status: resp.status}
+	if strings.HasPrefix(resp.Header().Get("Content-Type"), "application/json") && json.Valid(resp.body.Bytes()) {
+		cmdErr.Details = resp.body.Bytes()
+	} else {
+		cmdErr.Message = strings.TrimSpace(resp.body.String())
+	}
+	c.reply("error", msg.ID, cmdErr)
+}
+
+// commandResponse nimmt die Antwort eines intern aufgerufenen HTTP-Handlers auf
+type commandResponse struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func newCommandResponse() *commandResponse {
+	return &commandResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+func (r *commandResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *commandResponse) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(data)
+}
+
+func (r *commandResponse) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// commandFrame ist ein ack- oder error-Frame, wie ihn der Client empfängt
+type commandFrame struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Content json.RawMessage `json:"content"`
+}
+
+// newTestCommandRouter bildet die REST-Routen der Befehle nach und merkt sich den letzten Aufruf
+func newTestCommandRouter(lastCall *string) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/tasks", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*lastCall = r.Method + " " + r.URL.Path + " " + string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"t1"}`))
+	}).Methods("POST")
+	r.HandleFunc("/api/tasks/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		*lastCall = r.Method + " " + r.URL.Path
+		if mux.Vars(r)["id"] == "done" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"Task ist bereits abgeschlossen"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}).Methods("POST")
+	r.HandleFunc("/api/workers/{id}/fail", func(w http.ResponseWriter, r *http.Request) {
+		*lastCall = r.Method + " " + r.URL.Path
+		http.Error(w, "Worker nicht gefunden", http.StatusNotFound)
+	}).Methods("POST")
+	return r
+}
+
+func TestWSClientCommands(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		noHandler bool
+		wantFrame commandFrame
+		wantCall  string
+	}{
+		{
+			name:      "create_task wird bestätigt",
+			message:   `{"action":"create_task","id":"1","params":{"type":"sleep","priority":2}}`,
+			wantFrame: commandFrame{Type: "ack", ID: "1", Content: json.RawMessage(`{"id":"t1"}`)},
+			wantCall:  `POST /api/tasks {"type":"sleep","priority":2,"data":null}`,
+		},
+		{
+			name:      "Antwort ohne Inhalt",
+			message:   `{"action":"cancel_task","id":"2","params":{"taskId":"a b"}}`,
+			wantFrame: commandFrame{Type: "ack", ID: "2", Content: json.RawMessage(`null`)},
+			wantCall:  "POST /api/tasks/a b/cancel",
+		},
+		{
+			name:      "JSON-Fehler landet in details",
+			message:   `{"action":"cancel_task","id":"3","params":{"taskId":"done"}}`,
+			wantFrame: commandFrame{Type: "error", ID: "3", Content: json.RawMessage(`{"status":409,"details":{"error":"Task ist bereits abgeschlossen"}}`)},
+			wantCall:  "POST /api/tasks/done/cancel",
+		},
+		{
+			name:      "Text-Fehler landet in message",
+			message:   `{"action":"fail_worker","id":"4","params":{"workerId":"w1"}}`,
+			wantFrame: commandFrame{Type: "error", ID: "4", Content: json.RawMessage(`{"status":404,"message":"Worker nicht gefunden"}`)},
+			wantCall:  "POST /api/workers/w1/fail",
+		},
+		{
+			name:      "fehlender Parameter",
+			message:   `{"action":"cancel_task","id":"5","params":{}}`,
+			wantFrame: commandFrame{Type: "error", ID: "5", Content: json.RawMessage(`{"status":400,"message":"taskId ist erforderlich"}`)},
+		},
+		{
+			name:      "ungültige Parameter",
+			message:   `{"action":"migrate_task","id":"6","params":[]}`,
+			wantFrame: commandFrame{Type: "error", ID: "6"},
+		},
+		{
+			name:      "ohne id",
+			message:   `{"action":"cancel_task","params":{"taskId":"t1"}}`,
+			wantFrame: commandFrame{Type: "error", Content: json.RawMessage(`{"status":400,"message":"Befehle benötigen eine id"}`)},
+		},
+		{
+			name:      "ohne Befehls-Handler",
+			message:   `{"action":"cancel_task","id":"7","params":{"taskId":"t1"}}`,
+			noHandler: true,
+			wantFrame: commandFrame{Type: "error", ID: "7", Content: json.RawMessage(`{"status":503,"message":"Befehle sind nicht verfügbar"}`)},
+		},
+		{
+			name:      "unbekannte Aktion",
+			message:   `{"action":"explode","id":"8"}`,
+			wantFrame: commandFrame{Type: "error", ID: "8", Content: json.RawMessage(`{"message":"Unbekannte Aktion: explode"}`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lastCall string
+			c := newTestWSClient(newTestWSHandler(10, 10), eventFilter{})
+			if !tt.noHandler {
+				c.commands = newTestCommandRouter(&lastCall)
+			}
+
+			c.handleMessage([]byte(tt.message))
+
+			c.queueMutex.Lock()
+			defer c.queueMutex.Unlock()
+			if len(c.queue) != 1 {
+				t.Fatalf("%d Antworten, erwartet 1", len(c.queue))
+			}
+			var frame commandFrame
+			if err := json.Unmarshal(c.queue[0].payload, &frame); err != nil {
+				t.Fatalf("ungültige Antwort %s: %v", c.queue[0].payload, err)
+			}
+			if frame.Type != tt.wantFrame.Type || frame.ID != tt.wantFrame.ID {
+				t.Errorf("Antwort %s/%q, erwartet %s/%q", frame.Type, frame.ID, tt.wantFrame.Type, tt.wantFrame.ID)
+			}
+			if tt.wantFrame.Content != nil && string(frame.Content) != string(tt.wantFrame.Content) {
+				t.Errorf("Inhalt %s, erwartet %s", frame.Content, tt.wantFrame.Content)
+			}
+			if lastCall != tt.wantCall {
+				t.Errorf("Aufruf %q, erwartet %q", lastCall, tt.wantCall)
+			}
+		})
+	}
+}
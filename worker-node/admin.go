@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+	"github.com/streadway/amqp"
+)
+
+// readinessTimeout begrenzt die Prüfung von Redis in GET /readyz
+const readinessTimeout = 2 * time.Second
+
+// NewAdminServer erstellt den HTTP-Server für Health-Checks, Metriken und Log-Stufen des Workers
+// (ADMIN_ADDR, Standard :8081). Log-Stufen lassen sich nur mit ADMIN_API_KEY ändern.
+func NewAdminServer(w *Worker) *http.Server {
+	addr := os.Getenv("ADMIN_ADDR")
+	if addr == "" {
+		addr = ":8081"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", w.HandleHealthz)
+	mux.HandleFunc("/readyz", w.HandleReadyz)
+	mux.Handle("/metrics", w.metrics.Handler())
+	mux.Handle("/logging", requireAdminKey(os.Getenv("ADMIN_API_KEY"), protocol.LogLevelsHandler(adminLogger)))
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// requireAdminKey lässt lesende Anfragen durch, verändernde nur mit dem Schlüssel key im
+// Header "Authorization: Bearer <schlüssel>". Ohne Schlüssel sind verändernde Anfragen
+// abgeschaltet, da der Admin-Server ohne TLS von jedem Container im Netz erreichbar ist.
+func requireAdminKey(key string, next http.Handler) http.Handler {
+	if key == "" {
+		adminLogger.Warnf("ADMIN_API_KEY ist nicht gesetzt, Log-Stufen lassen sich nicht ändern")
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if key == "" {
+			http.Error(rw, "Änderungen sind ohne ADMIN_API_KEY abgeschaltet", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(bearerToken(r)), []byte(key)) != 1 {
+			adminLogger.Event("access_denied").Warnf("Zugriff verweigert: %s %s von %s", r.Method, r.URL.Path, r.RemoteAddr)
+			rw.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(rw, "Nicht authentifiziert", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// bearerToken liest das Token aus dem Header "Authorization: Bearer <token>"
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return header[len(prefix):]
+}
+
+// ServeAdmin startet den Admin-Server; er läuft ohne TLS und ist nur für das
+// interne Netz (Docker, Kubernetes, Prometheus) gedacht
+func ServeAdmin(srv *http.Server) {
+	adminLogger.Infof("Admin-Server (Health-Checks, Metriken) gestartet auf %s", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		adminLogger.Fatalf("Fehler beim Starten des Admin-Servers: %v", err)
+	}
+}
+
+// watchChannel merkt sich für GET /readyz, dass der AMQP-Kanal geschlossen wurde
+func (w *Worker) watchChannel(closed <-chan *amqp.Error) {
+	err := fmt.Errorf("AMQP-Kanal geschlossen")
+	if amqpErr, ok := <-closed; ok && amqpErr != nil {
+		err = fmt.Errorf("AMQP-Kanal geschlossen: %v", amqpErr)
+		brokerLogger.Event("channel_closed").Errorf("%v", err)
+	}
+	w.mutex.Lock()
+	w.channelErr = err
+	w.mutex.Unlock()
+}
+
+// watchConsumers merkt sich für GET /readyz, dass der Broker einen Consumer abgebrochen hat
+func (w *Worker) watchConsumers(cancelled <-chan string) {
+	if tag, ok := <-cancelled; ok {
+		brokerLogger.Event("consumer_cancelled").Errorf("Consumer %s wurde vom Broker abgebrochen", tag)
+		w.mutex.Lock()
+		w.consuming = false
+		w.mutex.Unlock()
+	}
+}
+
+// HandleHealthz ist der HTTP-Handler für GET /healthz: der Prozess läuft
+func (w *Worker) HandleHealthz(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(map[string]string{"status": "ok"})
+}
+
+// HandleReadyz ist der HTTP-Handler für GET /readyz: der AMQP-Kanal ist offen, Redis
+// antwortet und die Consumer aller Namespaces sind registriert. Andernfalls 503.
+func (w *Worker) HandleReadyz(rw http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"amqp": "ok", "redis": "ok", "consumer": "ok"}
+	ready := true
+
+	w.mutex.RLock()
+	channelErr := w.channelErr
+	consuming := w.consuming
+	w.mutex.RUnlock()
+
+	if channelErr != nil {
+		checks["amqp"] = channelErr.Error()
+		ready = false
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := w.redisClient.Ping(ctx).Err(); err != nil {
+		checks["redis"] = err.Error()
+		ready = false
+	}
+	if !consuming {
+		checks["consumer"] = "nicht registriert"
+		ready = false
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if !ready {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(rw).Encode(map[string]interface{}{
+		"ready":  ready,
+		"checks": checks,
+	})
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/streadway/amqp"
+)
+
+func TestHandleReadyz(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup bringt den Worker in den zu prüfenden Zustand
+		setup      func(w *Worker, mr *miniredis.Miniredis)
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "bereit",
+			setup:      func(w *Worker, mr *miniredis.Miniredis) {},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"amqp": "ok", "redis": "ok", "consumer": "ok"},
+		},
+		{
+			name: "AMQP-Kanal vom Broker geschlossen",
+			setup: func(w *Worker, mr *miniredis.Miniredis) {
+				closed := make(chan *amqp.Error, 1)
+				closed <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"}
+				w.watchChannel(closed)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"amqp": "AMQP-Kanal geschlossen: Exception (320) Reason: \"CONNECTION_FORCED\"", "redis": "ok", "consumer": "ok"},
+		},
+		{
+			name: "AMQP-Kanal ordentlich geschlossen",
+			setup: func(w *Worker, mr *miniredis.Miniredis) {
+				closed := make(chan *amqp.Error)
+				close(closed)
+				w.watchChannel(closed)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"amqp": "AMQP-Kanal geschlossen", "redis": "ok", "consumer": "ok"},
+		},
+		{
+			name: "Consumer abgebrochen",
+			setup: func(w *Worker, mr *miniredis.Miniredis) {
+				cancelled := make(chan string, 1)
+				cancelled <- "worker-1"
+				w.watchConsumers(cancelled)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"amqp": "ok", "redis": "ok", "consumer": "nicht registriert"},
+		},
+		{
+			name:       "Redis nicht erreichbar",
+			setup:      func(w *Worker, mr *miniredis.Miniredis) { mr.Close() },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Redis meldet Fehler",
+			setup:      func(w *Worker, mr *miniredis.Miniredis) { mr.SetError("LOADING Redis lädt den Datenbestand") },
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"amqp": "ok", "redis": "LOADING Redis lädt den Datenbestand", "consumer": "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1, DialTimeout: 200 * time.Millisecond})
+			defer client.Close()
+			w := &Worker{ID: "worker-1", redisClient: client, consuming: true}
+			tt.setup(w, mr)
+
+			rec := httptest.NewRecorder()
+			w.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Status %d, erwartet %d", rec.Code, tt.wantStatus)
+			}
+			var body struct {
+				Ready  bool              `json:"ready"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("ungültige Antwort: %v", err)
+			}
+			if body.Ready != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("ready = %v bei Status %d", body.Ready, rec.Code)
+			}
+			if tt.wantChecks == nil {
+				// Die Meldung des Redis-Clients hängt vom Betriebssystem ab
+				if body.Checks["redis"] == "ok" || body.Checks["amqp"] != "ok" || body.Checks["consumer"] != "ok" {
+					t.Errorf("checks = %v, erwartet nur einen Redis-Fehler", body.Checks)
+				}
+				return
+			}
+			for key, want := range tt.wantChecks {
+				if body.Checks[key] != want {
+					t.Errorf("checks[%s] = %q, erwartet %q", key, body.Checks[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAdminKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		key           string
+		method        string
+		authorization string
+		wantStatus    int
+	}{
+		{"Lesen ohne Schlüssel", "", http.MethodGet, "", http.StatusOK},
+		{"Ändern ohne ADMIN_API_KEY", "", http.MethodPut, "Bearer geheim", http.StatusForbidden},
+		{"Lesen ohne Token", "geheim", http.MethodGet, "", http.StatusOK},
+		{"Ändern ohne Token", "geheim", http.MethodPut, "", http.StatusUnauthorized},
+		{"Ändern mit falschem Token", "geheim", http.MethodPut, "Bearer falsch", http.StatusUnauthorized},
+		{"Ändern mit Token", "geheim", http.MethodPut, "Bearer geheim", http.StatusOK},
+		{"Schema ohne Großschreibung", "geheim", http.MethodPut, "bearer geheim", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := requireAdminKey(tt.key, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+			req := httptest.NewRequest(tt.method, "/logging", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status %d, erwartet %d", rec.Code, tt.wantStatus)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Fatalf("Handler aufgerufen: %v", called)
+			}
+		})
+	}
+}
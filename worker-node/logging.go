@@ -0,0 +1,20 @@
+package main
+
+import "github.com/scimbe/distributed-task-demo-system/protocol"
+
+// Die Logger der Komponenten; ihre Namen sind die Schlüssel in LOG_LEVELS und
+// in /logging. mainLogger nimmt auch alle Ausgaben über das Paket log auf.
+var (
+	mainLogger       = protocol.NewLogger("main")
+	adminLogger      = protocol.NewLogger("admin")
+	brokerLogger     = protocol.NewLogger("broker")
+	checkpointLogger = protocol.NewLogger("checkpoint")
+	encryptionLogger = protocol.NewLogger("encryption")
+	taskLogger       = protocol.NewLogger("task")
+	tracingLogger    = protocol.NewLogger("tracing")
+)
+
+// init stellt die Ausgabe schon vor dem Start von main auf JSON um
+func init() {
+	protocol.InitLogging(mainLogger)
+}
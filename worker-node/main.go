@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"os"
 	"os/signal"
@@ -16,7 +15,11 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WorkerStatus definiert die möglichen Status eines Workers
@@ -67,6 +70,13 @@ type Task struct {
 	CreatedAt     TimeFormat             `json:"created_at"`
 	UpdatedAt     TimeFormat             `json:"updated_at"`
 	CheckpointData map[string]interface{} `json:"checkpoint_data,omitempty"`
+	Namespace      string                 `json:"namespace,omitempty"`
+
+	// input ist Data mit aufgelösten Secret-Referenzen für die laufende Ausführung;
+	// nicht exportiert, damit es weder serialisiert noch versendet wird
+	input map[string]interface{}
+	// traceCtx trägt den mit der Nachricht empfangenen Trace bis zur Verarbeitung
+	traceCtx context.Context
 }
 
 // Worker repräsentiert einen Arbeitsknoten im System
@@ -74,33 +84,77 @@ type Worker struct {
 	ID             string
 	Status         WorkerStatus
 	CurrentTaskID  string
+	// Namespaces sind die Namespaces, aus deren Warteschlangen der Worker Tasks annimmt
+	Namespaces     []string
 	amqpChannel    *amqp.Channel
+	// publisher sendet Nachrichten an den Task-Manager; im Betrieb ist das amqpChannel
+	publisher      publisher
 	redisClient    *redis.Client
+	// keyring verschlüsselt Data und CheckpointData in Redis; nil bedeutet Klartext
+	keyring        *protocol.Keyring
+	secrets        SecretProvider
+	metrics        *WorkerMetrics
 	taskQueue      chan *Task
 	mutex          sync.RWMutex
 	shutdownSignal chan struct{}
 	checkpointFreq time.Duration
+	// channelErr ist gesetzt, sobald der AMQP-Kanal geschlossen wurde; consuming,
+	// solange die Consumer aller Namespaces registriert sind (beides für GET /readyz)
+	channelErr     error
+	consuming      bool
+}
+
+// publisher ist der Teil eines AMQP-Kanals, über den der Worker Nachrichten sendet
+type publisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// toProtocolTask überträgt einen Task in das Nachrichtenformat des Brokers
+func toProtocolTask(task *Task) protocol.Task {
+	return protocol.Task{
+		ID:             task.ID,
+		Type:           task.Type,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		Data:           task.Data,
+		Progress:       task.Progress,
+		WorkerID:       task.WorkerID,
+		CreatedAt:      time.Time(task.CreatedAt),
+		UpdatedAt:      time.Time(task.UpdatedAt),
+		CheckpointData: task.CheckpointData,
+		Namespace:      task.Namespace,
+	}
 }
 
-// MessagePayload repräsentiert die Struktur der ausgetauschten Nachrichten
-type MessagePayload struct {
-	Type    string      `json:"type"`
-	TaskID  string      `json:"task_id,omitempty"`
-	WorkerID string     `json:"worker_id,omitempty"`
-	Content interface{} `json:"content,omitempty"`
+// fromProtocolTask übernimmt einen Task aus einer Broker-Nachricht
+func fromProtocolTask(task *protocol.Task) *Task {
+	return &Task{
+		ID:             task.ID,
+		Type:           task.Type,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		Data:           task.Data,
+		Progress:       task.Progress,
+		WorkerID:       task.WorkerID,
+		CreatedAt:      TimeFormat(task.CreatedAt),
+		UpdatedAt:      TimeFormat(task.UpdatedAt),
+		CheckpointData: task.CheckpointData,
+		Namespace:      task.Namespace,
+	}
 }
 
 // NewWorker erstellt eine neue Worker-Instanz
-func NewWorker(amqpConn *amqp.Connection, redisAddr string, workerID string) (*Worker, error) {
+func NewWorker(amqpConn *amqp.Connection, redisAddr string, workerID string, namespaces []string, keyring *protocol.Keyring) (*Worker, error) {
 	channel, err := amqpConn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("Fehler beim Erstellen des AMQP-Kanals: %w", err)
 	}
 
-	// Redis-Client erstellen
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
+	// Redis-Client erstellen (bei rediss:// oder REDIS_TLS=true über TLS)
+	redisClient, err := newRedisClient(redisAddr)
+	if err != nil {
+		return nil, err
+	}
 
 	// Redis-Verbindung testen
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -114,106 +168,145 @@ func NewWorker(amqpConn *amqp.Connection, redisAddr string, workerID string) (*W
 	worker := &Worker{
 		ID:             workerID,
 		Status:         WorkerIdle,
+		Namespaces:     namespaces,
 		amqpChannel:    channel,
+		publisher:      channel,
 		redisClient:    redisClient,
+		keyring:        keyring,
+		secrets:        NewSecretProviderFromEnv(),
 		taskQueue:      make(chan *Task, 10),
 		mutex:          sync.RWMutex{},
 		shutdownSignal: make(chan struct{}),
 		checkpointFreq: 5 * time.Second,
 	}
+	worker.metrics = newWorkerMetrics(worker)
+
+	// Ohne offenen Kanal kann der Worker weder Tasks empfangen noch Status melden
+	go worker.watchChannel(channel.NotifyClose(make(chan *amqp.Error, 1)))
+
+	// Warteschlangen deklarieren (eine pro Namespace)
+	for _, namespace := range namespaces {
+		_, err = channel.QueueDeclare(
+			protocol.DispatchQueue(namespace), // Name
+			true,                              // Dauerhaft
+			false,                             // Nicht löschen wenn unbenutzt
+			false,                             // Nicht exklusiv
+			false,                             // Kein Nolock
+			nil,                               // Keine Argumente
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Deklarieren der Warteschlange: %w", err)
+		}
+	}
 
-	// Warteschlangen deklarieren
-	_, err = channel.QueueDeclare(
-		"task_created", // Name
-		true,           // Dauerhaft
-		false,          // Nicht löschen wenn unbenutzt
-		false,          // Nicht exklusiv
-		false,          // Kein Nolock
-		nil,            // Keine Argumente
-	)
+	// Namespaces für den Task-Manager hinterlegen, damit er Worker-Ereignisse zuordnen kann
+	err = redisClient.Set(ctx, workerNamespacesKey(workerID), strings.Join(namespaces, ","), 0).Err()
 	if err != nil {
-		return nil, fmt.Errorf("Fehler beim Deklarieren der Warteschlange: %w", err)
+		return nil, fmt.Errorf("Fehler beim Speichern der Namespaces: %w", err)
 	}
 
 	return worker, nil
 }
 
 // Erweiterte Verarbeitung von Task-Nachrichten
-func (w *Worker) processTaskMessage(msg []byte) {
-	var payload MessagePayload
-	if err := json.Unmarshal(msg, &payload); err != nil {
-		log.Printf("Fehler beim Deserialisieren der Nachricht: %v", err)
-		return
-	}
+func (w *Worker) processTaskMessage(msg amqp.Delivery) {
+	// Den Trace des Task-Managers aus den Headern fortsetzen
+	ctx, span := startReceiveSpan(msg)
+	defer span.End()
 
-	// Task-Daten extrahieren
-	taskBytes, err := json.Marshal(payload.Content)
+	env, err := protocol.Decode(msg.Body)
 	if err != nil {
-		log.Printf("Fehler beim Re-Serialisieren der Task-Daten: %v", err)
-		return
-	}
-
-	var task Task
-	if err := json.Unmarshal(taskBytes, &task); err != nil {
-		log.Printf("Fehler beim Deserialisieren der Task-Daten: %v", err)
+		// Laut melden: eine inkompatible Nachricht bedeutet eine fehlerhafte Mischung von Versionen
+		brokerLogger.Ctx(ctx).Event("message_rejected").Errorf("Nachricht abgelehnt: %v", err)
+		span.RecordError(err)
 		return
 	}
+	span.SetAttributes(attribute.String("messaging.message.type", string(env.Type)))
 
 	// Verarbeitung je nach Nachrichtentyp
-	switch payload.Type {
-	case "task_created":
+	switch env.Type {
+	case protocol.TypeTaskCreated:
+		content, err := env.Task()
+		if err != nil {
+			brokerLogger.Ctx(ctx).Errorf("%v", err)
+			return
+		}
+		task := fromProtocolTask(content)
+		if err := w.openTask(task); err != nil {
+			encryptionLogger.Ctx(ctx).Task(task.ID).Event("task_decryption_failed").Errorf("%v", err)
+			return
+		}
+		task.traceCtx = ctx
+		span.SetAttributes(taskAttributes(task)...)
+
 		// Normaler neuer Task
-		log.Printf("Neuer Task empfangen: %s (Typ: %s, Priorität: %d, Status: %s)",
+		taskLogger.Ctx(ctx).Task(task.ID).Event("task_received").Infof("Neuer Task empfangen: %s (Typ: %s, Priorität: %d, Status: %s)",
 			task.ID, task.Type, task.Priority, task.Status)
 
 		// Prüfe, ob es sich um einen wiederherzustellenden Task handelt
 		if task.Status == "RECOVERING" {
 			// Behandle Recovery-Task direkt
-			w.handleRecoveryTask(&task)
+			w.handleRecoveryTask(task)
 		} else {
 			// Normaler Task zur Verarbeitung
-			w.taskQueue <- &task
+			w.taskQueue <- task
+		}
+
+	case protocol.TypeTaskRecovery:
+		content, err := env.Task()
+		if err != nil {
+			brokerLogger.Ctx(ctx).Errorf("%v", err)
+			return
+		}
+		task := fromProtocolTask(content)
+		if err := w.openTask(task); err != nil {
+			encryptionLogger.Ctx(ctx).Task(task.ID).Event("task_decryption_failed").Errorf("%v", err)
+			return
 		}
+		task.traceCtx = ctx
+		span.SetAttributes(taskAttributes(task)...)
 
-	case "task_recovery":
 		// Recovery-Nachricht für einen ausgefallenen Task
-		log.Printf("Recovery-Task empfangen: %s (Typ: %s, Priorität: %d)",
+		taskLogger.Ctx(ctx).Task(task.ID).Event("task_received").Infof("Recovery-Task empfangen: %s (Typ: %s, Priorität: %d)",
 			task.ID, task.Type, task.Priority)
 
 		// Recovery-Task direkt verarbeiten
-		w.handleRecoveryTask(&task)
+		w.handleRecoveryTask(task)
 
-	case "task_migration":
-		// Migration-Nachricht
-		// Prüfen, ob dieser Worker das Ziel ist
-		migrationData, ok := payload.Content.(map[string]interface{})
-		if !ok {
-			log.Printf("Ungültiges Format für Migrations-Daten")
+	case protocol.TypeTaskMigration:
+		migration, err := env.Migration()
+		if err != nil {
+			brokerLogger.Ctx(ctx).Errorf("%v", err)
 			return
 		}
 
-		targetWorkerId, _ := migrationData["targetWorkerId"].(string)
-		if targetWorkerId == w.ID {
+		// Prüfen, ob dieser Worker das Ziel ist
+		if migration.TargetWorkerID == w.ID {
 			// Dieser Worker ist das Ziel der Migration
-			log.Printf("Migration-Ziel für Task %s", payload.TaskID)
+			taskLogger.Ctx(ctx).Task(migration.TaskID).Event("task_migration_received").Infof("Migration-Ziel für Task %s", migration.TaskID)
 
 			// Task aus Redis laden
-			task, err := w.loadTaskFromRedis(payload.TaskID)
+			task, err := w.loadTaskFromRedis(migration.Namespace, migration.TaskID)
 			if err != nil {
-				log.Printf("Fehler beim Laden des Task %s für Migration: %v", payload.TaskID, err)
+				taskLogger.Ctx(ctx).Task(migration.TaskID).Errorf("Fehler beim Laden des Task %s für Migration: %v", migration.TaskID, err)
 				return
 			}
+			task.traceCtx = ctx
+			span.SetAttributes(taskAttributes(task)...)
 
 			// Task zur Verarbeitung weitergeben
 			w.handleRecoveryTask(task)
 		}
+
+	default:
+		brokerLogger.Ctx(ctx).Infof("Nachricht vom Typ %s wird von Workern nicht verarbeitet", env.Type)
 	}
 }
 
 // Hilfsfunktion zum Laden eines Tasks aus Redis
-func (w *Worker) loadTaskFromRedis(taskId string) (*Task, error) {
+func (w *Worker) loadTaskFromRedis(namespace, taskId string) (*Task, error) {
 	ctx := context.Background()
-	taskJSON, err := w.redisClient.Get(ctx, "task:"+taskId).Result()
+	taskJSON, err := w.redisClient.Get(ctx, protocol.NamespaceKey(namespace, "task:"+taskId)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -222,26 +315,61 @@ func (w *Worker) loadTaskFromRedis(taskId string) (*Task, error) {
 	if err := json.Unmarshal([]byte(taskJSON), &task); err != nil {
 		return nil, err
 	}
+	// Der Task-Manager speichert Tasks ohne Namespace; ohne ihn liefen Steuerbefehle,
+	// Checkpoints und Status-Updates über die Schlüssel des Default-Namespace
+	if task.Namespace == "" {
+		task.Namespace = namespace
+	}
+	if err := w.openTask(&task); err != nil {
+		return nil, err
+	}
 
 	return &task, nil
 }
 
+// openTask entschlüsselt Data und CheckpointData eines Tasks, z.B. wenn der Task-Manager
+// einen Recovery-Task aus Redis geladen hat; unverschlüsselte Felder bleiben unverändert
+func (w *Worker) openTask(task *Task) error {
+	var err error
+	if task.Data, err = w.keyring.Open(task.Data, protocol.DataAAD(task.ID)); err != nil {
+		return fmt.Errorf("Task %s: Daten nicht entschlüsselbar: %w", task.ID, err)
+	}
+	if task.CheckpointData, err = w.keyring.Open(task.CheckpointData, protocol.CheckpointAAD(task.ID)); err != nil {
+		return fmt.Errorf("Task %s: Checkpoint-Daten nicht entschlüsselbar: %w", task.ID, err)
+	}
+	return nil
+}
+
 // StartTaskProcessing startet die Verarbeitung von Tasks
 func (w *Worker) StartTaskProcessing() error {
-	// Task-Erstellungsnachrichten empfangen
-	msgs, err := w.amqpChannel.Consume(
-		"task_created", // Queue
-		"",             // Consumer
-		true,           // Auto-Ack
-		false,          // Exclusive
-		false,          // No-local
-		false,          // No-wait
-		nil,            // Args
-	)
-	if err != nil {
-		return fmt.Errorf("Fehler beim Registrieren des Consumers: %w", err)
+	// Task-Erstellungsnachrichten aller Namespaces des Workers in einem Kanal zusammenführen
+	msgs := make(chan amqp.Delivery)
+	for _, namespace := range w.Namespaces {
+		deliveries, err := w.amqpChannel.Consume(
+			protocol.DispatchQueue(namespace), // Queue
+			"",                                // Consumer
+			true,                              // Auto-Ack
+			false,                             // Exclusive
+			false,                             // No-local
+			false,                             // No-wait
+			nil,                               // Args
+		)
+		if err != nil {
+			return fmt.Errorf("Fehler beim Registrieren des Consumers für Namespace %s: %w", namespace, err)
+		}
+		go func() {
+			for msg := range deliveries {
+				msgs <- msg
+			}
+		}()
 	}
 
+	// Der Broker bricht Consumer z.B. ab, wenn ihre Warteschlange gelöscht wird
+	w.mutex.Lock()
+	w.consuming = true
+	w.mutex.Unlock()
+	go w.watchConsumers(w.amqpChannel.NotifyCancel(make(chan string, 1)))
+
 	// Worker-Status senden
 	go w.reportStatus()
 
@@ -251,7 +379,7 @@ func (w *Worker) StartTaskProcessing() error {
 			select {
 			case msg := <-msgs:
 				// Task-Nachricht mit erweiterter Funktionalität verarbeiten
-				w.processTaskMessage(msg.Body)
+				w.processTaskMessage(msg)
 				
 			case task := <-w.taskQueue:
 				// Task verarbeiten
@@ -268,18 +396,20 @@ func (w *Worker) StartTaskProcessing() error {
 
 // Behandlung von Task-Recovery-Nachrichten
 func (w *Worker) handleRecoveryTask(task *Task) {
-	log.Printf("Wiederherstellung von Task %s nach Worker-Ausfall", task.ID)
+	taskLogger.Ctx(task.traceContext()).Task(task.ID).Event("task_recovery_started").Infof("Wiederherstellung von Task %s nach Worker-Ausfall", task.ID)
+
+	ctx, span := tracer.Start(task.traceContext(), "task.recover", trace.WithAttributes(taskAttributes(task)...))
+	defer func() { endTaskSpan(span, task) }()
 
 	// Prüfen, ob Checkpoint-Daten vorhanden sind
 	if task.CheckpointData == nil {
 		// Lade den letzten Checkpoint aus Redis
-		ctx := context.Background()
 		
 		// Suche nach allen Checkpoints für diesen Task
-		checkpointKeys, err := w.redisClient.Keys(ctx, fmt.Sprintf("checkpoint:%s:*", task.ID)).Result()
+		checkpointKeys, err := w.checkpointKeys(ctx, task)
 		
 		if err != nil {
-			log.Printf("Fehler beim Suchen nach Checkpoints für Task %s: %v", task.ID, err)
+			checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Suchen nach Checkpoints für Task %s: %v", task.ID, err)
 		} else if len(checkpointKeys) > 0 {
 			// Sortiere Checkpoints nach Fortschritt (absteigend)
 			sort.Slice(checkpointKeys, func(i, j int) bool {
@@ -293,7 +423,13 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 			latestCheckpoint, err := w.redisClient.Get(ctx, checkpointKeys[0]).Result()
 			if err == nil {
 				var checkpointData map[string]interface{}
-				if err := json.Unmarshal([]byte(latestCheckpoint), &checkpointData); err == nil {
+				err := json.Unmarshal([]byte(latestCheckpoint), &checkpointData)
+				if err == nil {
+					checkpointData, err = w.keyring.Open(checkpointData, protocol.CheckpointAAD(task.ID))
+				}
+				if err != nil {
+					checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Task %s: Checkpoint %s nicht lesbar: %v", task.ID, checkpointKeys[0], err)
+				} else {
 					task.CheckpointData = checkpointData
 					
 					// Aktualisiere Fortschritt basierend auf Checkpoint
@@ -301,13 +437,23 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 						task.Progress = int(progress)
 					}
 					
-					log.Printf("Task %s: Letzter Checkpoint mit Fortschritt %d%% geladen", 
+					checkpointLogger.Ctx(ctx).Task(task.ID).Event("checkpoint_loaded").Infof("Task %s: Letzter Checkpoint mit Fortschritt %d%% geladen", 
 						task.ID, task.Progress)
 				}
 			}
 		}
 	}
 
+	// Secret-Referenzen erst unmittelbar vor der Ausführung auflösen
+	if !w.prepareInput(ctx, task) {
+		return
+	}
+	defer task.clearInput()
+
+	// Dauer mit dem Status messen, mit dem der Task den Worker verlässt
+	started := time.Now()
+	defer func() { w.metrics.observeTask(task, started) }()
+
 	// Worker-ID aktualisieren
 	task.WorkerID = w.ID
 	
@@ -316,7 +462,7 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 	task.UpdatedAt = TimeFormat(time.Now())
 	
 	// Status aktualisieren
-	w.updateTaskStatus(task)
+	w.updateTaskStatus(ctx, task)
 	
 	// Task normal verarbeiten, aber starte ab dem letzten Checkpoint
 	// Berechne die verbleibenden Schritte basierend auf dem Fortschritt
@@ -328,7 +474,7 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 	w.CurrentTaskID = task.ID
 	w.mutex.Unlock()
 	
-	log.Printf("Task %s: Wiederherstellung ab Fortschritt %d%%, noch %d Schritte", 
+	taskLogger.Ctx(ctx).Task(task.ID).Infof("Task %s: Wiederherstellung ab Fortschritt %d%%, noch %d Schritte", 
 		task.ID, startProgress, remainingSteps)
 	
 	// Checkpoint-Timer starten
@@ -337,6 +483,11 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 	
 	// Task simulieren (je nach Typ unterschiedliche Verarbeitung)
 	for step := 1; step <= remainingSteps; step++ {
+		if w.handleControl(ctx, task) {
+			return
+		}
+		stepCtx, stepSpan := tracer.Start(ctx, "task.step", trace.WithAttributes(attribute.Int("task.step", step)))
+		
 		// Simulation der Arbeit
 		sleepTime := time.Duration(500+rand.Intn(1000)) * time.Millisecond
 		time.Sleep(sleepTime)
@@ -348,24 +499,25 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 		}
 		
 		task.UpdatedAt = TimeFormat(time.Now())
-		w.updateTaskStatus(task)
+		w.updateTaskStatus(stepCtx, task)
 		
-		log.Printf("Task %s: Fortschritt nach Wiederherstellung %d%%", task.ID, task.Progress)
+		taskLogger.Ctx(stepCtx).Task(task.ID).Event("task_progress").Debugf("Task %s: Fortschritt nach Wiederherstellung %d%%", task.ID, task.Progress)
 		
 		// Checkpoint speichern (bei jedem Timer-Tick)
 		select {
 		case <-checkpointTicker.C:
-			w.saveCheckpoint(task)
+			w.saveCheckpoint(stepCtx, task)
 		default:
 			// Nicht blockieren
 		}
+		stepSpan.End()
 		
 		// Zufälligen Fehler simulieren (reduzierte Wahrscheinlichkeit nach Recovery)
 		if rand.Intn(100) < 3 {
-			log.Printf("Task %s: Simulierter Fehler bei Schritt %d nach Wiederherstellung", task.ID, step)
+			taskLogger.Ctx(ctx).Task(task.ID).Event("task_failed").Warnf("Task %s: Simulierter Fehler bei Schritt %d nach Wiederherstellung", task.ID, step)
 			task.Status = "FAILED"
 			task.UpdatedAt = TimeFormat(time.Now())
-			w.updateTaskStatus(task)
+			w.updateTaskStatus(ctx, task)
 			
 			w.mutex.Lock()
 			w.Status = WorkerIdle
@@ -380,14 +532,14 @@ func (w *Worker) handleRecoveryTask(task *Task) {
 	task.Status = "COMPLETED"
 	task.Progress = 100
 	task.UpdatedAt = TimeFormat(time.Now())
-	w.updateTaskStatus(task)
+	w.updateTaskStatus(ctx, task)
 	
 	w.mutex.Lock()
 	w.Status = WorkerIdle
 	w.CurrentTaskID = ""
 	w.mutex.Unlock()
 	
-	log.Printf("Task %s nach Wiederherstellung abgeschlossen", task.ID)
+	taskLogger.Ctx(ctx).Task(task.ID).Event("task_completed").Infof("Task %s nach Wiederherstellung abgeschlossen", task.ID)
 }
 
 // Hilfsfunktion zum Extrahieren des Fortschritts aus einem Checkpoint-Key
@@ -397,7 +549,8 @@ func extractProgressFromKey(key string) int {
 		return 0
 	}
 	
-	progress, err := strconv.Atoi(parts[2])
+	// Der Fortschritt ist immer der letzte Teil, auch bei Schlüsseln mit Namespace-Präfix
+	progress, err := strconv.Atoi(parts[len(parts)-1])
 	if err != nil {
 		return 0
 	}
@@ -405,14 +558,127 @@ func extractProgressFromKey(key string) int {
 	return progress
 }
 
+// controlKey ist der Redis-Schlüssel, über den der Task-Manager einen Task steuert
+func controlKey(task *Task) string {
+	return protocol.NamespaceKey(task.Namespace, "control:"+task.ID)
+}
+
+// checkpointKey ist der Redis-Schlüssel eines Checkpoints; progress "*" liefert das Suchmuster
+func checkpointKey(task *Task, progress string) string {
+	return protocol.NamespaceKey(task.Namespace, fmt.Sprintf("checkpoint:%s:%s", task.ID, progress))
+}
+
+// checkpointKeys liefert die Redis-Schlüssel aller Checkpoints eines Tasks. SCAN statt
+// KEYS, damit Redis bei vielen Schlüsseln aller Namespaces nicht blockiert; doppelt
+// gelieferte Schlüssel stören nicht, da nur der mit dem höchsten Fortschritt zählt.
+func (w *Worker) checkpointKeys(ctx context.Context, task *Task) ([]string, error) {
+	var keys []string
+	iter := w.redisClient.Scan(ctx, 0, checkpointKey(task, "*"), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// workerNamespacesKey ist der Redis-Schlüssel mit den Namespaces eines Workers
+func workerNamespacesKey(workerID string) string {
+	return "worker_namespaces:" + workerID
+}
+
+// pendingControl liefert den Steuerbefehl des Task-Managers für einen Task (z.B. "cancel") oder ""
+func (w *Worker) pendingControl(task *Task) string {
+	ctx := context.Background()
+	command, err := w.redisClient.Get(ctx, controlKey(task)).Result()
+	if err == redis.Nil {
+		return ""
+	}
+	if err != nil {
+		taskLogger.Task(task.ID).Errorf("Fehler beim Lesen des Steuerbefehls für Task %s: %v", task.ID, err)
+		return ""
+	}
+	return command
+}
+
+// handleControl führt einen anstehenden Steuerbefehl aus und liefert true,
+// wenn die Verarbeitung des Tasks beendet werden muss
+func (w *Worker) handleControl(ctx context.Context, task *Task) bool {
+	switch w.pendingControl(task) {
+	case "cancel":
+		w.cancelTask(ctx, task)
+		return true
+	case "pause":
+		w.pauseTask(ctx, task)
+		return true
+	}
+	return false
+}
+
+// pauseTask speichert einen Checkpoint, gibt den Task frei und meldet ihn als pausiert.
+// Beim Fortsetzen übernimmt ein beliebiger Worker den Task ab diesem Checkpoint.
+func (w *Worker) pauseTask(ctx context.Context, task *Task) {
+	w.saveCheckpoint(ctx, task)
+
+	task.Status = "PAUSED"
+	task.WorkerID = ""
+	task.UpdatedAt = TimeFormat(time.Now())
+	w.updateTaskStatus(ctx, task)
+
+	if err := w.redisClient.Del(ctx, controlKey(task)).Err(); err != nil {
+		taskLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Löschen des Steuerbefehls für Task %s: %v", task.ID, err)
+	}
+
+	w.mutex.Lock()
+	w.Status = WorkerIdle
+	w.CurrentTaskID = ""
+	w.mutex.Unlock()
+
+	taskLogger.Ctx(ctx).Task(task.ID).Event("task_paused").Infof("Task %s bei %d%% pausiert", task.ID, task.Progress)
+}
+
+// cancelTask beendet die Verarbeitung eines abgebrochenen Tasks
+func (w *Worker) cancelTask(ctx context.Context, task *Task) {
+	taskLogger.Ctx(ctx).Task(task.ID).Event("task_cancelled").Infof("Task %s wurde abgebrochen", task.ID)
+
+	task.Status = "CANCELLED"
+	task.UpdatedAt = TimeFormat(time.Now())
+	w.updateTaskStatus(ctx, task)
+
+	if err := w.redisClient.Del(ctx, controlKey(task)).Err(); err != nil {
+		taskLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Löschen des Steuerbefehls für Task %s: %v", task.ID, err)
+	}
+
+	w.mutex.Lock()
+	w.Status = WorkerIdle
+	w.CurrentTaskID = ""
+	w.mutex.Unlock()
+}
+
 // processTask führt einen Task aus
 func (w *Worker) processTask(task *Task) {
+	ctx, span := tracer.Start(task.traceContext(), "task.process", trace.WithAttributes(taskAttributes(task)...))
+	defer func() { endTaskSpan(span, task) }()
+
+	// Bereits vor der Ausführung abgebrochene oder pausierte Tasks überspringen
+	if w.handleControl(ctx, task) {
+		return
+	}
+
+	// Secret-Referenzen erst unmittelbar vor der Ausführung auflösen
+	if !w.prepareInput(ctx, task) {
+		return
+	}
+	defer task.clearInput()
+
+	// Dauer mit dem Status messen, mit dem der Task den Worker verlässt
+	started := time.Now()
+	defer func() { w.metrics.observeTask(task, started) }()
+
 	w.mutex.Lock()
 	w.Status = WorkerBusy
 	w.CurrentTaskID = task.ID
 	w.mutex.Unlock()
 
-	log.Printf("Starte Verarbeitung von Task %s", task.ID)
+	taskLogger.Ctx(ctx).Task(task.ID).Event("task_started").Infof("Starte Verarbeitung von Task %s", task.ID)
 
 	// Task zuweisen
 	task.Status = "RUNNING"
@@ -420,7 +686,7 @@ func (w *Worker) processTask(task *Task) {
 	task.UpdatedAt = TimeFormat(time.Now())
 
 	// Status aktualisieren
-	w.updateTaskStatus(task)
+	w.updateTaskStatus(ctx, task)
 
 	// Checkpoint-Timer starten
 	checkpointTicker := time.NewTicker(w.checkpointFreq)
@@ -429,6 +695,11 @@ func (w *Worker) processTask(task *Task) {
 	// Task simulieren (je nach Typ unterschiedliche Verarbeitung)
 	totalSteps := 10
 	for step := 1; step <= totalSteps; step++ {
+		if w.handleControl(ctx, task) {
+			return
+		}
+		stepCtx, stepSpan := tracer.Start(ctx, "task.step", trace.WithAttributes(attribute.Int("task.step", step)))
+
 		// Simulation der Arbeit
 		sleepTime := time.Duration(500+rand.Intn(1000)) * time.Millisecond
 		time.Sleep(sleepTime)
@@ -436,24 +707,25 @@ func (w *Worker) processTask(task *Task) {
 		// Fortschritt aktualisieren
 		task.Progress = (step * 100) / totalSteps
 		task.UpdatedAt = TimeFormat(time.Now())
-		w.updateTaskStatus(task)
+		w.updateTaskStatus(stepCtx, task)
 
-		log.Printf("Task %s: Fortschritt %d%%", task.ID, task.Progress)
+		taskLogger.Ctx(stepCtx).Task(task.ID).Event("task_progress").Debugf("Task %s: Fortschritt %d%%", task.ID, task.Progress)
 
 		// Checkpoint speichern (bei jedem Timer-Tick)
 		select {
 		case <-checkpointTicker.C:
-			w.saveCheckpoint(task)
+			w.saveCheckpoint(stepCtx, task)
 		default:
 			// Nicht blockieren
 		}
+		stepSpan.End()
 
 		// Zufälligen Fehler simulieren (5% Wahrscheinlichkeit - reduziert von 10%)
 		if rand.Intn(100) < 5 {
-			log.Printf("Task %s: Simulierter Fehler bei Schritt %d", task.ID, step)
+			taskLogger.Ctx(ctx).Task(task.ID).Event("task_failed").Warnf("Task %s: Simulierter Fehler bei Schritt %d", task.ID, step)
 			task.Status = "FAILED"
 			task.UpdatedAt = TimeFormat(time.Now())
-			w.updateTaskStatus(task)
+			w.updateTaskStatus(ctx, task)
 			
 			w.mutex.Lock()
 			w.Status = WorkerIdle
@@ -468,113 +740,152 @@ func (w *Worker) processTask(task *Task) {
 	task.Status = "COMPLETED"
 	task.Progress = 100
 	task.UpdatedAt = TimeFormat(time.Now())
-	w.updateTaskStatus(task)
+	w.updateTaskStatus(ctx, task)
 
 	w.mutex.Lock()
 	w.Status = WorkerIdle
 	w.CurrentTaskID = ""
 	w.mutex.Unlock()
 
-	log.Printf("Task %s abgeschlossen", task.ID)
+	taskLogger.Ctx(ctx).Task(task.ID).Event("task_completed").Infof("Task %s abgeschlossen", task.ID)
 }
 
-// updateTaskStatus aktualisiert den Status eines Tasks
-func (w *Worker) updateTaskStatus(task *Task) {
-	// Status-Update an Redis senden
-	ctx := context.Background()
-	taskJSON, err := json.Marshal(task)
+// prepareInput löst die Secret-Referenzen in Data in die Eingabe der Ausführung auf.
+// Fehlt ein Secret, schlägt der Task fehl; gemeldet wird nur der Name des Secrets.
+func (w *Worker) prepareInput(ctx context.Context, task *Task) bool {
+	input, err := resolveSecrets(w.secrets, task.Data)
 	if err != nil {
-		log.Printf("Fehler beim Serialisieren des Tasks: %v", err)
+		taskLogger.Ctx(ctx).Task(task.ID).Event("task_failed").Errorf("Task %s: Secrets konnten nicht aufgelöst werden: %v", task.ID, err)
+		task.Status = "FAILED"
+		task.WorkerID = w.ID
+		task.UpdatedAt = TimeFormat(time.Now())
+		w.updateTaskStatus(ctx, task)
+		return false
+	}
+	task.input = input
+	return true
+}
+
+// clearInput verwirft die aufgelösten Secrets nach der Ausführung
+func (t *Task) clearInput() {
+	t.input = nil
+}
+
+// updateTaskStatus aktualisiert den Status eines Tasks; gespeichert und versendet wird
+// Data mit den Secret-Referenzen, nie die aufgelösten Werte aus input
+func (w *Worker) updateTaskStatus(ctx context.Context, task *Task) {
+	// Status-Update an Redis senden; Data und CheckpointData nur verschlüsselt
+	stored := *task
+	var err error
+	if stored.Data, err = w.keyring.Seal(task.Data, protocol.DataAAD(task.ID)); err != nil {
+		encryptionLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Verschlüsseln der Task-Daten: %v", err)
+		return
+	}
+	if stored.CheckpointData, err = w.keyring.Seal(task.CheckpointData, protocol.CheckpointAAD(task.ID)); err != nil {
+		encryptionLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Verschlüsseln der Checkpoint-Daten: %v", err)
+		return
+	}
+	taskJSON, err := json.Marshal(&stored)
+	if err != nil {
+		taskLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Serialisieren des Tasks: %v", err)
 		return
 	}
 	
-	err = w.redisClient.Set(ctx, "task:"+task.ID, taskJSON, 0).Err()
+	err = w.redisSet(ctx, protocol.NamespaceKey(task.Namespace, "task:"+task.ID), taskJSON)
 	if err != nil {
-		log.Printf("Fehler beim Speichern des Tasks in Redis: %v", err)
+		taskLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Speichern des Tasks in Redis: %v", err)
 	}
 
 	// Status-Update über Message Queue senden
-	msgPayload := MessagePayload{
-		Type:    "task_status",
-		TaskID:  task.ID,
-		WorkerID: w.ID,
-		Content: task,
+	if err := w.publish(ctx, protocol.TypeTaskStatus, task.ID, toProtocolTask(task)); err != nil {
+		taskLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Senden des Status-Updates: %v", err)
 	}
-	
-	msgJSON, err := json.Marshal(msgPayload)
+}
+
+// publish sendet eine Nachricht an die gleichnamige Warteschlange des Task-Managers
+func (w *Worker) publish(ctx context.Context, msgType protocol.Type, taskID string, content interface{}) (err error) {
+	ctx, span := startSpan(ctx, string(msgType)+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationNameKey.String(string(msgType)),
+		))
+	defer func() { endSpan(span, err) }()
+
+	msgJSON, err := protocol.Encode(msgType, taskID, w.ID, content)
 	if err != nil {
-		log.Printf("Fehler beim Serialisieren der Nachricht: %v", err)
-		return
+		return err
 	}
-	
-	err = w.amqpChannel.Publish(
-		"",            // Exchange
-		"task_status", // Routing-Schlüssel
-		false,         // Mandatory
-		false,         // Immediate
+
+	err = w.publisher.Publish(
+		"",              // Exchange
+		string(msgType), // Routing-Schlüssel
+		false,           // Mandatory
+		false,           // Immediate
 		amqp.Publishing{
+			Headers:     traceHeaders(ctx),
 			ContentType: "application/json",
 			Body:        msgJSON,
 		},
 	)
-	
 	if err != nil {
-		log.Printf("Fehler beim Senden des Status-Updates: %v", err)
+		w.metrics.publishErrors.WithLabelValues(string(msgType)).Inc()
 	}
+	return err
+}
+
+// redisSet schreibt einen Wert ohne Ablaufzeit nach Redis, als Kind-Span des laufenden Traces
+func (w *Worker) redisSet(ctx context.Context, key string, value []byte) (err error) {
+	ctx, span := startSpan(ctx, "redis.set", trace.WithAttributes(
+		semconv.DBSystemRedis,
+		semconv.DBOperationKey.String("SET"),
+	))
+	defer func() { endSpan(span, err) }()
+	return w.redisClient.Set(ctx, key, value, 0).Err()
 }
 
 // saveCheckpoint speichert einen Checkpoint des Task-Zustands
-func (w *Worker) saveCheckpoint(task *Task) {
+func (w *Worker) saveCheckpoint(ctx context.Context, task *Task) {
+	ctx, span := startSpan(ctx, "task.checkpoint", trace.WithAttributes(attribute.Int("task.progress", task.Progress)))
+	defer span.End()
+
 	// Simuliere Checkpoint-Daten
-	timestamp := time.Now()
+	checkpoint := protocol.Checkpoint{
+		Timestamp: time.Now(),
+		Progress:  task.Progress,
+		Step:      fmt.Sprintf("step_%d", task.Progress/10),
+	}
 	task.CheckpointData = map[string]interface{}{
-		"timestamp": timestamp.Format(time.RFC3339),
-		"progress":  task.Progress,
-		"step":      fmt.Sprintf("step_%d", task.Progress/10),
+		"timestamp": checkpoint.Timestamp.Format(time.RFC3339),
+		"progress":  checkpoint.Progress,
+		"step":      checkpoint.Step,
 	}
 
-	// Checkpoint-Daten in Redis speichern
-	ctx := context.Background()
-	checkpointJSON, err := json.Marshal(task.CheckpointData)
+	// Checkpoint-Daten in Redis speichern (verschlüsselt, sofern ein Schlüsselbund geladen ist)
+	sealed, err := w.keyring.Seal(task.CheckpointData, protocol.CheckpointAAD(task.ID))
+	if err != nil {
+		checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Verschlüsseln des Checkpoints: %v", err)
+		return
+	}
+	checkpointJSON, err := json.Marshal(sealed)
 	if err != nil {
-		log.Printf("Fehler beim Serialisieren des Checkpoints: %v", err)
+		checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Serialisieren des Checkpoints: %v", err)
 		return
 	}
 	
-	err = w.redisClient.Set(ctx, fmt.Sprintf("checkpoint:%s:%d", task.ID, task.Progress), checkpointJSON, 0).Err()
+	start := time.Now()
+	err = w.redisSet(ctx, checkpointKey(task, strconv.Itoa(task.Progress)), checkpointJSON)
+	w.metrics.checkpointWrite.Observe(time.Since(start).Seconds())
 	if err != nil {
-		log.Printf("Fehler beim Speichern des Checkpoints in Redis: %v", err)
+		checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Speichern des Checkpoints in Redis: %v", err)
+		w.metrics.checkpointErrors.Inc()
 	}
 
-	log.Printf("Checkpoint für Task %s bei %d%% gespeichert", task.ID, task.Progress)
+	checkpointLogger.Ctx(ctx).Task(task.ID).Event("checkpoint_saved").Infof("Checkpoint für Task %s bei %d%% gespeichert", task.ID, task.Progress)
 
 	// Checkpoint-Update über Message Queue senden
-	msgPayload := MessagePayload{
-		Type:    "task_checkpoint",
-		TaskID:  task.ID,
-		WorkerID: w.ID,
-		Content: task.CheckpointData,
-	}
-	
-	msgJSON, err := json.Marshal(msgPayload)
-	if err != nil {
-		log.Printf("Fehler beim Serialisieren der Checkpoint-Nachricht: %v", err)
-		return
-	}
-	
-	err = w.amqpChannel.Publish(
-		"",               // Exchange
-		"task_checkpoint", // Routing-Schlüssel
-		false,            // Mandatory
-		false,            // Immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        msgJSON,
-		},
-	)
-	
-	if err != nil {
-		log.Printf("Fehler beim Senden des Checkpoint-Updates: %v", err)
+	if err := w.publish(ctx, protocol.TypeTaskCheckpoint, task.ID, checkpoint); err != nil {
+		checkpointLogger.Ctx(ctx).Task(task.ID).Errorf("Fehler beim Senden des Checkpoint-Updates: %v", err)
 	}
 }
 
@@ -592,40 +903,18 @@ func (w *Worker) reportStatus() {
 			taskID := w.CurrentTaskID
 			w.mutex.RUnlock()
 
-			statusPayload := map[string]interface{}{
-				"id":     w.ID,
-				"status": status,
-				"task":   taskID,
-				"time":   time.Now().Format(time.RFC3339),
+			statusPayload := protocol.WorkerStatus{
+				ID:         w.ID,
+				Status:     string(status),
+				Task:       taskID,
+				Time:       time.Now(),
+				Namespaces: w.Namespaces,
 			}
 
-			msgPayload := MessagePayload{
-				Type:    "worker_status",
-				WorkerID: w.ID,
-				Content: statusPayload,
-			}
-			
-			msgJSON, err := json.Marshal(msgPayload)
-			if err != nil {
-				log.Printf("Fehler beim Serialisieren des Status: %v", err)
-				continue
-			}
-			
-			err = w.amqpChannel.Publish(
-				"",              // Exchange
-				"worker_status", // Routing-Schlüssel
-				false,           // Mandatory
-				false,           // Immediate
-				amqp.Publishing{
-					ContentType: "application/json",
-					Body:        msgJSON,
-				},
-			)
-			
-			if err != nil {
-				log.Printf("Fehler beim Senden des Worker-Status: %v", err)
+			if err := w.publish(context.Background(), protocol.TypeWorkerStatus, "", statusPayload); err != nil {
+				brokerLogger.Errorf("Fehler beim Senden des Worker-Status: %v", err)
 			} else {
-				log.Printf("Worker %s Status gesendet: %s", w.ID, status)
+				brokerLogger.Event("worker_status_sent").Debugf("Worker %s Status gesendet: %s", w.ID, status)
 			}
 		case <-w.shutdownSignal:
 			return
@@ -633,12 +922,39 @@ func (w *Worker) reportStatus() {
 	}
 }
 
+// reloadKeyring übernimmt Änderungen an der Schlüsselbund-Datei, z.B. einen neuen
+// Primärschlüssel, im Abstand von ENCRYPTION_KEYRING_RELOAD (Standard 30s)
+func reloadKeyring(keyring *protocol.Keyring) {
+	interval := 30 * time.Second
+	if value := os.Getenv("ENCRYPTION_KEYRING_RELOAD"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			interval = d
+		} else {
+			encryptionLogger.Warnf("Ungültiger Wert für ENCRYPTION_KEYRING_RELOAD (%q), verwende %s", value, interval)
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		changed, err := keyring.Reload()
+		if err != nil {
+			encryptionLogger.Errorf("Fehler beim Neuladen des Schlüsselbunds, bisherige Schlüssel bleiben aktiv: %v", err)
+		} else if changed {
+			encryptionLogger.Event("keyring_reloaded").Infof("Schlüsselbund neu geladen (Primärschlüssel %s)", keyring.Primary())
+		}
+	}
+}
+
 func main() {
 	// Zufallsgenerator initialisieren
 	rand.Seed(time.Now().UnixNano())
 
 	// Worker-ID aus Umgebungsvariable lesen
 	workerID := os.Getenv("WORKER_ID")
+	if workerID == "" {
+		// Bei mTLS weist sich der Worker über den Common Name seines Zertifikats aus
+		workerID = certificateIdentity()
+	}
 	if workerID == "" {
 		// Fallback auf zufällige ID, falls keine Umgebungsvariable gesetzt ist
 		workerID = fmt.Sprintf("worker-%d", rand.Intn(1000))
@@ -656,36 +972,83 @@ func main() {
 		redisURL = "redis:6379"
 	}
 
-	log.Printf("Starte Worker mit ID: %s", workerID)
-	log.Printf("Verbinde mit RabbitMQ: %s", rabbitmqURL)
-	log.Printf("Verbinde mit Redis: %s", redisURL)
+	// Namespaces, deren Tasks dieser Worker annimmt
+	var namespaces []string
+	for _, namespace := range strings.Split(os.Getenv("WORKER_NAMESPACES"), ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" {
+			continue
+		}
+		if err := protocol.ValidateNamespace(namespace); err != nil {
+			mainLogger.Fatalf("WORKER_NAMESPACES: %v", err)
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{protocol.DefaultNamespace}
+	}
+
+	// Ab hier trägt jede Log-Zeile die worker_id
+	protocol.SetLogWorkerID(workerID)
+	mainLogger.Infof("Starte Worker mit ID: %s", workerID)
+	mainLogger.Infof("Namespaces: %s", strings.Join(namespaces, ", "))
+	mainLogger.Infof("Verbinde mit RabbitMQ: %s", rabbitmqURL)
+	mainLogger.Infof("Verbinde mit Redis: %s", redisURL)
 
-	// Verbindung zu RabbitMQ herstellen
-	amqpConn, err := amqp.Dial(rabbitmqURL)
+	// Tracing der Task-Verarbeitung, fortgesetzt aus den Headern des Task-Managers
+	shutdownTracing, err := setupTracing(workerID)
 	if err != nil {
-		log.Fatalf("Fehler beim Verbinden mit RabbitMQ: %v", err)
+		mainLogger.Fatalf("Fehler bei der Tracing-Konfiguration: %v", err)
+	}
+
+	// Verbindung zu RabbitMQ herstellen (bei amqps:// über TLS)
+	amqpConn, err := dialAMQP(rabbitmqURL)
+	if err != nil {
+		mainLogger.Fatalf("Fehler beim Verbinden mit RabbitMQ: %v", err)
 	}
 	defer amqpConn.Close()
 
 	// Worker erstellen
-	worker, err := NewWorker(amqpConn, redisURL, workerID)
+	// Schlüsselbund für die Verschlüsselung von Task-Daten in Redis
+	keyring, err := protocol.LoadKeyringFromEnv()
 	if err != nil {
-		log.Fatalf("Fehler beim Erstellen des Workers: %v", err)
+		mainLogger.Fatalf("Fehler beim Laden des Schlüsselbunds: %v", err)
+	}
+	if keyring != nil {
+		encryptionLogger.Infof("Verschlüsselung von Task-Daten aktiv (Primärschlüssel %s)", keyring.Primary())
+		go reloadKeyring(keyring)
 	}
 
+	worker, err := NewWorker(amqpConn, redisURL, workerID, namespaces, keyring)
+	if err != nil {
+		mainLogger.Fatalf("Fehler beim Erstellen des Workers: %v", err)
+	}
+
+	// Health-Checks und Metriken; vor der Task-Verarbeitung, damit /healthz sofort antwortet
+	adminServer := NewAdminServer(worker)
+	go ServeAdmin(adminServer)
+
 	// Task-Verarbeitung starten
 	if err := worker.StartTaskProcessing(); err != nil {
-		log.Fatalf("Fehler beim Starten der Task-Verarbeitung: %v", err)
+		mainLogger.Fatalf("Fehler beim Starten der Task-Verarbeitung: %v", err)
 	}
 
-	log.Printf("Worker gestartet mit ID: %s", worker.ID)
+	mainLogger.Event("worker_started").Infof("Worker gestartet mit ID: %s", worker.ID)
 
 	// Warten auf Beendigungssignal
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
-	log.Println("Worker wird heruntergefahren...")
+	mainLogger.Infof("Worker wird heruntergefahren...")
 	close(worker.shutdownSignal)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := adminServer.Shutdown(ctx); err != nil {
+		mainLogger.Errorf("Fehler beim Beenden des Admin-Servers: %v", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		mainLogger.Errorf("Fehler beim Export der letzten Spans: %v", err)
+	}
 	time.Sleep(1 * time.Second) // Zeit zum Aufräumen geben
 }
\ No newline at end of file
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+	"github.com/streadway/amqp"
+)
+
+// recordingPublisher hält gesendete Nachrichten fest, statt sie an RabbitMQ zu senden
+type recordingPublisher struct {
+	mutex    sync.Mutex
+	messages []*protocol.Envelope
+}
+
+func (p *recordingPublisher) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	env, err := protocol.Decode(msg.Body)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.messages = append(p.messages, env)
+	return nil
+}
+
+// take liefert die bisher gesendeten Nachrichten und leert die Liste
+func (p *recordingPublisher) take() []*protocol.Envelope {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	messages := p.messages
+	p.messages = nil
+	return messages
+}
+
+// newTestWorker erstellt einen Worker mit einem Redis im Speicher und ohne Schlüsselbund
+func newTestWorker(t *testing.T) (*Worker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Worker{ID: "worker-2", Namespaces: []string{"team-a"}, redisClient: client}, mr
+}
+
+func TestLoadTaskFromRedisMigrationNamespace(t *testing.T) {
+	w, mr := newTestWorker(t)
+
+	// Wie von TaskService.MigrateTask gespeichert: ohne Namespace-Feld
+	stored, err := json.Marshal(map[string]interface{}{
+		"id":       "task-1",
+		"type":     "process_data",
+		"status":   "MIGRATING",
+		"progress": 40,
+		"data":     map[string]interface{}{"items": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("ns:team-a:task:task-1", string(stored))
+	mr.Set("ns:team-a:control:task-1", "cancel")
+
+	task, err := w.loadTaskFromRedis("team-a", "task-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Namespace != "team-a" {
+		t.Fatalf("Namespace = %q, erwartet team-a", task.Namespace)
+	}
+	if got := controlKey(task); got != "ns:team-a:control:task-1" {
+		t.Fatalf("controlKey = %s", got)
+	}
+	if got := checkpointKey(task, "40"); got != "ns:team-a:checkpoint:task-1:40" {
+		t.Fatalf("checkpointKey = %s", got)
+	}
+	if got := w.pendingControl(task); got != "cancel" {
+		t.Fatalf("Steuerbefehl = %q, erwartet cancel", got)
+	}
+
+	// Ein gespeicherter Namespace bleibt unverändert
+	stored, _ = json.Marshal(map[string]interface{}{"id": "task-2", "status": "MIGRATING", "namespace": "team-a"})
+	mr.Set("ns:team-a:task:task-2", string(stored))
+	if task, err = w.loadTaskFromRedis("team-a", "task-2"); err != nil || task.Namespace != "team-a" {
+		t.Fatalf("task-2: Namespace = %q, Fehler %v", task.Namespace, err)
+	}
+
+	// Tasks des Default-Namespace behalten Schlüssel ohne Präfix
+	stored, _ = json.Marshal(map[string]interface{}{"id": "task-3", "status": "MIGRATING"})
+	mr.Set("task:task-3", string(stored))
+	if task, err = w.loadTaskFromRedis("default", "task-3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := controlKey(task); got != "control:task-3" {
+		t.Fatalf("controlKey im Default-Namespace = %s", got)
+	}
+}
+
+func TestCheckpointKeys(t *testing.T) {
+	w, mr := newTestWorker(t)
+
+	// Mehr Checkpoints als ein SCAN-Aufruf liefert, dazu fremde Tasks und Namespaces
+	const checkpoints = 250
+	for i := 0; i < checkpoints; i++ {
+		mr.Set(fmt.Sprintf("ns:team-a:checkpoint:task-1:%d", i), "{}")
+	}
+	mr.Set("ns:team-a:checkpoint:task-10:5", "{}")
+	mr.Set("ns:team-b:checkpoint:task-1:99", "{}")
+	mr.Set("checkpoint:task-1:98", "{}")
+
+	keys, err := w.checkpointKeys(context.Background(), &Task{ID: "task-1", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unique := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "ns:team-a:checkpoint:task-1:") {
+			t.Fatalf("fremder Schlüssel %s", key)
+		}
+		unique[key] = true
+	}
+	if len(unique) != checkpoints {
+		t.Fatalf("%d Checkpoints gefunden, erwartet %d", len(unique), checkpoints)
+	}
+
+	keys, err = w.checkpointKeys(context.Background(), &Task{ID: "task-1"})
+	if err != nil || len(keys) != 1 || keys[0] != "checkpoint:task-1:98" {
+		t.Fatalf("Default-Namespace: %v, Fehler %v", keys, err)
+	}
+}
+
+func TestPauseAndResumeFromCheckpoint(t *testing.T) {
+	w, mr := newTestWorker(t)
+	pub := &recordingPublisher{}
+	w.publisher = pub
+	w.metrics = newWorkerMetrics(w)
+	w.checkpointFreq = time.Hour
+	w.Status = WorkerBusy
+	w.CurrentTaskID = "task-1"
+
+	// Der Task-Manager fordert die Pause an, während der Worker bei 40% ist
+	task := &Task{ID: "task-1", Type: "process_data", Status: "RUNNING", WorkerID: w.ID, Progress: 40, Namespace: "team-a"}
+	mr.Set("ns:team-a:control:task-1", "pause")
+	if !w.handleControl(context.Background(), task) {
+		t.Fatal("Pause nicht ausgeführt")
+	}
+
+	if !mr.Exists("ns:team-a:checkpoint:task-1:40") {
+		t.Fatal("kein Checkpoint bei 40% gespeichert")
+	}
+	if mr.Exists("ns:team-a:control:task-1") {
+		t.Fatal("Steuerbefehl nicht gelöscht")
+	}
+	if w.Status != WorkerIdle || w.CurrentTaskID != "" {
+		t.Fatalf("Worker %s mit Task %q, erwartet IDLE ohne Task", w.Status, w.CurrentTaskID)
+	}
+	messages := pub.take()
+	if len(messages) != 2 || messages[0].Type != protocol.TypeTaskCheckpoint || messages[1].Type != protocol.TypeTaskStatus {
+		t.Fatalf("gesendet: %v, erwartet task_checkpoint und task_status", messages)
+	}
+	paused, err := messages[1].Task()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paused.Status != "PAUSED" || paused.WorkerID != "" || paused.Progress != 40 || paused.CheckpointData["progress"] != float64(40) {
+		t.Fatalf("Status-Update %+v, erwartet PAUSED ohne Worker mit Checkpoint bei 40%%", paused)
+	}
+
+	// ResumeTask stellt den gemeldeten Task als task_recovery neu ein. Damit der Test nicht
+	// die simulierte Arbeit abwartet, hält der Worker vor dem ersten Schritt erneut an.
+	recovery := *paused
+	recovery.Status = "RECOVERING"
+	recovery.Namespace = "team-a"
+	body, err := protocol.Encode(protocol.TypeTaskRecovery, "task-1", "", recovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("ns:team-a:control:task-1", "pause")
+	w.processTaskMessage(amqp.Delivery{RoutingKey: protocol.DispatchQueue("team-a"), Body: body})
+
+	messages = pub.take()
+	if len(messages) == 0 || messages[0].Type != protocol.TypeTaskStatus {
+		t.Fatalf("gesendet: %v, erwartet zuerst task_status", messages)
+	}
+	resumed, err := messages[0].Task()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Status != "RUNNING" || resumed.WorkerID != w.ID || resumed.Progress != 40 {
+		t.Fatalf("Status-Update %+v, erwartet RUNNING auf %s ab 40%%", resumed, w.ID)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// workerSlots ist die Zahl gleichzeitig ausführbarer Tasks; der Worker verarbeitet Tasks nacheinander
+const workerSlots = 1
+
+// WorkerMetrics sind die Kennzahlen eines Workers für GET /metrics. Alle Metriken
+// tragen das Label worker_id, damit sie sich im Prometheus-Server zuordnen lassen.
+type WorkerMetrics struct {
+	registry *prometheus.Registry
+
+	taskDuration     *prometheus.HistogramVec
+	checkpointWrite  prometheus.Histogram
+	checkpointErrors prometheus.Counter
+	publishErrors    *prometheus.CounterVec
+}
+
+// newWorkerMetrics erstellt die Metriken eines Workers
+func newWorkerMetrics(w *Worker) *WorkerMetrics {
+	m := &WorkerMetrics{
+		registry: prometheus.NewRegistry(),
+
+		taskDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "worker_task_duration_seconds",
+			Help:    "Ausführungsdauer von Tasks auf diesem Worker nach Typ und Ergebnis",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600},
+		}, []string{"type", "status"}),
+		checkpointWrite: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "worker_checkpoint_write_seconds",
+			Help:    "Dauer des Schreibens eines Checkpoints nach Redis",
+			Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+		}),
+		checkpointErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "worker_checkpoint_write_errors_total",
+			Help: "Fehlgeschlagene Checkpoint-Schreibvorgänge",
+		}),
+		publishErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "worker_publish_errors_total",
+			Help: "Fehlgeschlagene Veröffentlichungen an den Broker nach Nachrichtentyp",
+		}, []string{"type"}),
+	}
+
+	busy := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "worker_slots_busy",
+		Help: "Belegte Ausführungsplätze",
+	}, func() float64 {
+		w.mutex.RLock()
+		defer w.mutex.RUnlock()
+		if w.CurrentTaskID != "" {
+			return 1
+		}
+		return 0
+	})
+	slots := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "worker_slots",
+		Help: "Verfügbare Ausführungsplätze; Auslastung = worker_slots_busy / worker_slots",
+	}, func() float64 { return workerSlots })
+	queued := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "worker_tasks_queued",
+		Help: "Angenommene Tasks, die auf einen freien Ausführungsplatz warten",
+	}, func() float64 { return float64(len(w.taskQueue)) })
+
+	labeled := prometheus.WrapRegistererWith(prometheus.Labels{"worker_id": w.ID}, m.registry)
+	labeled.MustRegister(m.taskDuration, m.checkpointWrite, m.checkpointErrors, m.publishErrors, busy, slots, queued)
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// observeTask erfasst die Dauer eines Tasks mit dem Status, mit dem er den Worker verlässt
+func (m *WorkerMetrics) observeTask(task *Task, started time.Time) {
+	m.taskDuration.WithLabelValues(task.Type, task.Status).Observe(time.Since(started).Seconds())
+}
+
+// Handler liefert den HTTP-Handler für GET /metrics
+func (m *WorkerMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
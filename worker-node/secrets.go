@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/scimbe/distributed-task-demo-system/protocol"
+)
+
+// ErrSecretNotFound wird zurückgegeben, wenn ein Provider ein Secret nicht kennt
+var ErrSecretNotFound = errors.New("Secret nicht gefunden")
+
+// SecretProvider liefert den Wert eines Secrets zu seinem Namen (z.B. "db/password").
+// Fehlermeldungen dürfen den Namen, aber nie den Wert enthalten.
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// FileSecretProvider liest Secrets aus Dateien unterhalb eines Verzeichnisses, wie sie
+// Docker und Kubernetes unter /run/secrets bereitstellen; "db/password" entspricht
+// der Datei <dir>/db/password
+type FileSecretProvider struct {
+	dir string
+}
+
+// NewSecretProviderFromEnv erstellt den Provider für SECRETS_DIR (Standard /run/secrets)
+func NewSecretProviderFromEnv() SecretProvider {
+	dir := os.Getenv("SECRETS_DIR")
+	if dir == "" {
+		dir = "/run/secrets"
+	}
+	return &FileSecretProvider{dir: dir}
+}
+
+// Secret liest die Datei des Secrets; ein abschließender Zeilenumbruch gehört nicht zum Wert
+func (p *FileSecretProvider) Secret(name string) (string, error) {
+	if err := protocol.ValidateSecretName(name); err != nil {
+		return "", err
+	}
+	value, err := ioutil.ReadFile(filepath.Join(p.dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		// Der Pfad ist unkritisch, der Fehler enthält keinen Inhalt der Datei
+		return "", fmt.Errorf("Secret %s nicht lesbar: %w", name, err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(value), "\n"), "\r"), nil
+}
+
+// resolveSecrets liefert eine Kopie von data, in der alle Secret-Referenzen
+// ({"$secret": "<name>"}) durch ihre Werte ersetzt sind. data selbst bleibt unverändert,
+// damit die Werte nicht mit dem Task gespeichert oder versendet werden.
+func resolveSecrets(provider SecretProvider, data map[string]interface{}) (map[string]interface{}, error) {
+	resolved, err := resolveValue(provider, data)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := resolved.(map[string]interface{})
+	return m, nil
+}
+
+func resolveValue(provider SecretProvider, value interface{}) (interface{}, error) {
+	if name, ok := protocol.SecretRef(value); ok {
+		return provider.Secret(name)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			r, err := resolveValue(provider, item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := resolveValue(provider, item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mapSecretProvider liefert Secrets aus einer Map
+type mapSecretProvider map[string]string
+
+func (p mapSecretProvider) Secret(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "secrets")
+	files := map[string]string{
+		"secrets/password":    "geheim\n",
+		"secrets/db/password": "db-geheim\r\n",
+		"secrets/multiline":   "zeile1\nzeile2\n\n",
+		"outside":             "fremd",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := &FileSecretProvider{dir: dir}
+
+	tests := []struct {
+		name     string
+		secret   string
+		want     string
+		wantErr  string
+		notFound bool
+	}{
+		{name: "einfach", secret: "password", want: "geheim"},
+		{name: "Unterverzeichnis mit CRLF", secret: "db/password", want: "db-geheim"},
+		{name: "nur ein Zeilenumbruch entfernt", secret: "multiline", want: "zeile1\nzeile2\n"},
+		{name: "fehlt", secret: "missing", notFound: true},
+		{name: "Verzeichnis verlassen", secret: "../outside", wantErr: "ungültiger Secret-Name"},
+		{name: "absoluter Pfad", secret: "/etc/passwd", wantErr: "ungültiger Secret-Name"},
+		{name: "Verzeichnis", secret: "db", wantErr: "nicht lesbar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := provider.Secret(tt.secret)
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, ErrSecretNotFound) {
+					t.Fatalf("Fehler = %v, erwartet ErrSecretNotFound", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fehler = %v, erwartet %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unerwarteter Fehler: %v", err)
+			case value != tt.want:
+				t.Fatalf("Secret = %q, erwartet %q", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	provider := mapSecretProvider{"db/password": "geheim", "token": "t0k3n"}
+	ref := func(name string) map[string]interface{} {
+		return map[string]interface{}{"$secret": name}
+	}
+
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "ohne Referenzen",
+			data: map[string]interface{}{"operation": "sum", "input": []interface{}{1.0, 2.0}},
+			want: map[string]interface{}{"operation": "sum", "input": []interface{}{1.0, 2.0}},
+		},
+		{
+			name: "verschachtelt",
+			data: map[string]interface{}{
+				"db":      map[string]interface{}{"user": "app", "password": ref("db/password")},
+				"headers": []interface{}{ref("token"), "plain"},
+			},
+			want: map[string]interface{}{
+				"db":      map[string]interface{}{"user": "app", "password": "geheim"},
+				"headers": []interface{}{"t0k3n", "plain"},
+			},
+		},
+		{
+			name: "Objekt mit $secret und weiteren Feldern bleibt unverändert",
+			data: map[string]interface{}{"x": map[string]interface{}{"$secret": "token", "y": 1.0}},
+			want: map[string]interface{}{"x": map[string]interface{}{"$secret": "token", "y": 1.0}},
+		},
+		{
+			name:    "unbekanntes Secret",
+			data:    map[string]interface{}{"a": []interface{}{ref("missing")}},
+			wantErr: true,
+		},
+		{
+			name: "nil",
+			data: nil,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := deepCopyJSON(tt.data)
+			resolved, err := resolveSecrets(provider, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Fehler erwartet")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unerwarteter Fehler: %v", err)
+				}
+				if !reflect.DeepEqual(resolved, tt.want) {
+					t.Fatalf("resolveSecrets = %v, erwartet %v", resolved, tt.want)
+				}
+			}
+			// Die aufgelösten Werte dürfen nie in den Task-Daten selbst landen
+			if !reflect.DeepEqual(tt.data, before) {
+				t.Fatalf("Task-Daten verändert: %v", tt.data)
+			}
+		})
+	}
+}
+
+func deepCopyJSON(value map[string]interface{}) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(value))
+	for key, item := range value {
+		copied[key] = deepCopyValue(item)
+	}
+	return copied
+}
+
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyJSON(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	}
+	return value
+}
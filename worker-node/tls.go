@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/streadway/amqp"
+)
+
+// clientTLSConfig liest CA, Client-Zertifikat und Servernamen einer ausgehenden
+// Verbindung aus <prefix>_TLS_CA_FILE, <prefix>_TLS_CERT_FILE, <prefix>_TLS_KEY_FILE
+// und <prefix>_TLS_SERVER_NAME. Ohne CA wird den System-Zertifikaten vertraut;
+// ein Client-Zertifikat wird nur bei mTLS benötigt.
+func clientTLSConfig(prefix string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: os.Getenv(prefix + "_TLS_SERVER_NAME"),
+	}
+	if caFile := os.Getenv(prefix + "_TLS_CA_FILE"); caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s_TLS_CA_FILE: %w", prefix, err)
+		}
+		config.RootCAs = pool
+	}
+	certFile := os.Getenv(prefix + "_TLS_CERT_FILE")
+	keyFile := os.Getenv(prefix + "_TLS_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Fehler beim Laden von %s_TLS_CERT_FILE/%s_TLS_KEY_FILE: %w", prefix, prefix, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// loadCertPool liest eine oder mehrere PEM-kodierte CA-Zertifikate
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("keine PEM-Zertifikate in %s", file)
+	}
+	return pool, nil
+}
+
+// certificateIdentity liefert den Common Name des Client-Zertifikats für RabbitMQ
+// bzw. Redis. Mit mTLS weist sich der Worker darüber aus; ohne WORKER_ID wird er
+// als Worker-ID verwendet.
+func certificateIdentity() string {
+	for _, prefix := range []string{"AMQP", "REDIS"} {
+		certFile := os.Getenv(prefix + "_TLS_CERT_FILE")
+		keyFile := os.Getenv(prefix + "_TLS_KEY_FILE")
+		if certFile == "" || keyFile == "" {
+			continue
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil || len(cert.Certificate) == 0 {
+			continue
+		}
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && parsed.Subject.CommonName != "" {
+			return parsed.Subject.CommonName
+		}
+	}
+	return ""
+}
+
+// externalAuth meldet sich über SASL EXTERNAL an: RabbitMQ übernimmt den Benutzer
+// aus dem Client-Zertifikat (Plugin rabbitmq_auth_mechanism_ssl)
+type externalAuth struct{}
+
+func (externalAuth) Mechanism() string { return "EXTERNAL" }
+func (externalAuth) Response() string  { return "" }
+
+// dialAMQP verbindet sich mit RabbitMQ. Bei amqps:// wird die Verbindung mit den
+// Einstellungen aus AMQP_TLS_* verschlüsselt; mit AMQP_AUTH_EXTERNAL=true ersetzt
+// das Client-Zertifikat Benutzername und Passwort.
+func dialAMQP(url string) (*amqp.Connection, error) {
+	if !strings.HasPrefix(url, "amqps://") {
+		return amqp.Dial(url)
+	}
+	tlsConfig, err := clientTLSConfig("AMQP")
+	if err != nil {
+		return nil, err
+	}
+	config := amqp.Config{TLSClientConfig: tlsConfig}
+	if os.Getenv("AMQP_AUTH_EXTERNAL") == "true" {
+		if len(tlsConfig.Certificates) == 0 {
+			return nil, errors.New("AMQP_AUTH_EXTERNAL erfordert AMQP_TLS_CERT_FILE und AMQP_TLS_KEY_FILE")
+		}
+		config.SASL = []amqp.Authentication{externalAuth{}}
+	}
+	return amqp.DialConfig(url, config)
+}
+
+// newRedisClient erstellt einen Redis-Client für REDIS_URL. Akzeptiert werden
+// host:port sowie redis:// und rediss:// URLs; TLS wird bei rediss:// oder
+// REDIS_TLS=true mit den Einstellungen aus REDIS_TLS_* verwendet.
+func newRedisClient(redisURL string) (*redis.Client, error) {
+	options := &redis.Options{Addr: redisURL}
+	if strings.Contains(redisURL, "://") {
+		parsed, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("Ungültige REDIS_URL: %w", err)
+		}
+		options = parsed
+	}
+	if options.TLSConfig != nil || os.Getenv("REDIS_TLS") == "true" {
+		tlsConfig, err := clientTLSConfig("REDIS")
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.ServerName == "" {
+			if host, _, err := net.SplitHostPort(options.Addr); err == nil {
+				tlsConfig.ServerName = host
+			}
+		}
+		options.TLSConfig = tlsConfig
+	}
+	return redis.NewClient(options), nil
+}
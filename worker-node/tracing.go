@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer erzeugt die Spans des Workers; ohne konfigurierten Exporter sind sie wirkungslos
+var tracer = otel.Tracer("github.com/scimbe/distributed-task-demo-system/worker-node")
+
+// setupTracing richtet OpenTelemetry nach TRACING_EXPORTER ein (otlp, file oder none,
+// wie im Task-Manager). Die zurückgegebene Funktion exportiert die gepufferten Spans.
+func setupTracing(workerID string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	mode := os.Getenv("TRACING_EXPORTER")
+	switch mode {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var err error
+		if exporter, err = otlptracehttp.New(context.Background()); err != nil {
+			return nil, fmt.Errorf("OTLP-Exporter: %w", err)
+		}
+	case "file":
+		path := os.Getenv("TRACING_FILE")
+		if path == "" {
+			path = "traces.json"
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("Trace-Datei %s: %w", path, err)
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(file)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unbekannter TRACING_EXPORTER %q (erlaubt: otlp, file, none)", mode)
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("worker-node"),
+			semconv.ServiceInstanceIDKey.String(workerID),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	tracingLogger.Infof("Tracing aktiv (Exporter: %s)", mode)
+	return provider.Shutdown, nil
+}
+
+// amqpHeaderCarrier überträgt den Trace-Kontext in den Headern einer AMQP-Nachricht
+type amqpHeaderCarrier amqp.Table
+
+func (c amqpHeaderCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c amqpHeaderCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// traceHeaders liefert die AMQP-Header mit dem Trace-Kontext von ctx
+func traceHeaders(ctx context.Context) amqp.Table {
+	headers := amqp.Table{}
+	otel.GetTextMapPropagator().Inject(ctx, amqpHeaderCarrier(headers))
+	return headers
+}
+
+// traceContext liefert den Kontext, in dem ein Task verarbeitet wird; Tasks ohne
+// empfangenen Trace (z.B. Recovery durch den Task-Manager) beginnen einen neuen
+func (t *Task) traceContext() context.Context {
+	if t.traceCtx != nil {
+		return t.traceCtx
+	}
+	return context.Background()
+}
+
+// startSpan startet einen Kind-Span, aber nur innerhalb eines bestehenden Traces,
+// damit periodische Vorgänge wie Status-Meldungen keine eigenen Traces erzeugen
+func startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// startReceiveSpan setzt den in den Headern einer Nachricht übertragenen Trace fort
+func startReceiveSpan(msg amqp.Delivery) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), amqpHeaderCarrier(msg.Headers))
+	return tracer.Start(ctx, msg.RoutingKey+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationNameKey.String(msg.RoutingKey),
+			semconv.MessagingOperationReceive,
+		))
+}
+
+// endSpan schließt einen Span ab und markiert ihn bei einem Fehler als fehlgeschlagen
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endTaskSpan schließt den Span einer Task-Ausführung mit dem erreichten Status ab
+func endTaskSpan(span trace.Span, task *Task) {
+	span.SetAttributes(
+		attribute.String("task.status", task.Status),
+		attribute.Int("task.progress", task.Progress),
+	)
+	if task.Status == "FAILED" {
+		span.SetStatus(codes.Error, "Task fehlgeschlagen")
+	}
+	span.End()
+}
+
+// taskAttributes sind die Span-Attribute eines Tasks
+func taskAttributes(task *Task) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("task.id", task.ID),
+		attribute.String("task.type", task.Type),
+		attribute.String("task.namespace", task.Namespace),
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testSpansOnce sync.Once
+	testSpans     *tracetest.SpanRecorder
+)
+
+// recordSpans richtet einmalig einen TracerProvider ein, der alle Spans aufzeichnet.
+// Der globale tracer wird nur beim ersten SetTracerProvider umgelenkt.
+func recordSpans() *tracetest.SpanRecorder {
+	testSpansOnce.Do(func() {
+		testSpans = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(testSpans)))
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	})
+	return testSpans
+}
+
+func TestStartReceiveSpan(t *testing.T) {
+	recordSpans()
+	// Der Task-Manager veröffentlicht mit den Headern aus traceHeaders
+	publishCtx, publishSpan := tracer.Start(context.Background(), "task_queue publish")
+	publishSpan.End()
+	published := publishSpan.SpanContext()
+
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		// wantParent: der Empfangs-Span setzt den Trace des Task-Managers fort
+		wantParent bool
+	}{
+		{name: "Header vom Task-Manager", headers: traceHeaders(publishCtx), wantParent: true},
+		{name: "ohne Header", headers: nil},
+		{name: "ungültiger traceparent", headers: amqp.Table{"traceparent": "00-kaputt"}},
+		{name: "traceparent als Bytes", headers: amqp.Table{"traceparent": []byte(traceHeaders(publishCtx)["traceparent"].(string))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, span := startReceiveSpan(amqp.Delivery{RoutingKey: "task_queue", Headers: tt.headers})
+			span.End()
+
+			sc := trace.SpanContextFromContext(ctx)
+			if !sc.IsValid() || sc.SpanID() != span.SpanContext().SpanID() {
+				t.Fatalf("Kontext enthält den Empfangs-Span nicht")
+			}
+			readOnly := span.(sdktrace.ReadOnlySpan)
+			if readOnly.Name() != "task_queue receive" || readOnly.SpanKind() != trace.SpanKindConsumer {
+				t.Errorf("Span %q (%v), erwartet \"task_queue receive\" (consumer)", readOnly.Name(), readOnly.SpanKind())
+			}
+			if got := sc.TraceID() == published.TraceID(); got != tt.wantParent {
+				t.Errorf("gleicher Trace wie der Task-Manager: %v, erwartet %v", got, tt.wantParent)
+			}
+			if tt.wantParent && readOnly.Parent().SpanID() != published.SpanID() {
+				t.Errorf("Eltern-Span %s, erwartet %s", readOnly.Parent().SpanID(), published.SpanID())
+			}
+		})
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	recordSpans()
+	traced, parent := tracer.Start(context.Background(), "task")
+	defer parent.End()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantSpan bool
+	}{
+		{name: "innerhalb eines Traces", ctx: traced, wantSpan: true},
+		{name: "ohne Trace (z.B. Status-Meldung)", ctx: context.Background(), wantSpan: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, span := startSpan(tt.ctx, "publish")
+			span.End()
+
+			_, recording := span.(sdktrace.ReadOnlySpan)
+			if recording != tt.wantSpan {
+				t.Fatalf("neuer Span: %v, erwartet %v", recording, tt.wantSpan)
+			}
+			if tt.wantSpan && trace.SpanContextFromContext(ctx).TraceID() != parent.SpanContext().TraceID() {
+				t.Errorf("Span gehört nicht zum Trace des Tasks")
+			}
+			// Ohne Span dürfen auch keine Trace-Header entstehen
+			if headers := traceHeaders(ctx); !tt.wantSpan && len(headers) != 0 {
+				t.Errorf("Header %v, erwartet keine", headers)
+			}
+		})
+	}
+}